@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/job"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/pglib"
+	"github.com/KennyChenFight/golib/redislib"
+	"github.com/jessevdk/go-flags"
+)
+
+type PostgresConfig struct {
+	URL       string `long:"url" description:"database url" env:"URL" required:"true"`
+	DebugMode bool   `long:"debug-mode" description:"database debug mode" env:"DEBUG_MODE"`
+}
+
+type FilterConfig struct {
+	Backend string `long:"backend" description:"where url ids are filtered, should match the server, local only publishes changes to servers" env:"BACKEND" choice:"auto" choice:"redis" choice:"local" default:"auto"`
+}
+
+type RedisConfig struct {
+	URL string `long:"url" description:"redis url" env:"URL" required:"true"`
+}
+
+type IDArgs struct {
+	ID string `positional-arg-name:"id" required:"true"`
+}
+
+type IDsArgs struct {
+	IDs []string `positional-arg-name:"id" required:"1"`
+}
+
+type InspectCommand struct {
+	Args IDArgs `positional-args:"yes" required:"true"`
+}
+
+type ExpireCommand struct {
+	Args IDArgs `positional-args:"yes" required:"true"`
+}
+
+type DeleteCommand struct {
+	Args IDArgs `positional-args:"yes" required:"true"`
+}
+
+type KeyStatsCommand struct{}
+
+type GenerateKeysCommand struct {
+	Number int `long:"number" description:"random keys to generate, ids already in keys or urls are skipped" required:"true"`
+}
+
+type FilterCheckCommand struct {
+	Args IDArgs `positional-args:"yes" required:"true"`
+}
+
+type FilterInfoCommand struct{}
+
+type WarmCacheCommand struct {
+	Number    int           `long:"number" description:"most recently created active urls to put into cache and filter" required:"true"`
+	BatchSize int           `long:"batch-size" description:"urls read from database per batch" default:"500"`
+	Interval  time.Duration `long:"interval" description:"pause between batches" default:"200ms"`
+}
+
+type CacheInvalidateCommand struct {
+	Args IDsArgs `positional-args:"yes" required:"true"`
+}
+
+type IDConfig struct {
+	Length  int    `long:"length" description:"length of new ids, existing ids keep their length" env:"LENGTH" default:"6"`
+	Charset string `long:"charset" description:"charset of new ids" env:"CHARSET" choice:"alphabet" choice:"base62" choice:"lowercase" choice:"unambiguous" default:"alphabet"`
+}
+
+type RecycleConfig struct {
+	Cooldown time.Duration `long:"cooldown" description:"cooldown before ids of expired or deleted urls go back to the key pool, 0 disables recycling" env:"COOLDOWN" default:"0s"`
+}
+
+type Environment struct {
+	PostgresConfig  PostgresConfig         `group:"postgres" namespace:"postgres" env-namespace:"POSTGRES"`
+	RedisConfig     RedisConfig            `group:"redis" namespace:"redis" env-namespace:"REDIS"`
+	FilterConfig    FilterConfig           `group:"filter" namespace:"filter" env-namespace:"FILTER"`
+	IDConfig        IDConfig               `group:"id" namespace:"id" env-namespace:"ID"`
+	RecycleConfig   RecycleConfig          `group:"recycle" namespace:"recycle" env-namespace:"RECYCLE"`
+	Inspect         InspectCommand         `command:"inspect" description:"show a url with its cache, filter and health check state, expired urls included"`
+	Expire          ExpireCommand          `command:"expire" description:"force expire a url and remove it from cache and filter"`
+	Delete          DeleteCommand          `command:"delete" description:"delete a url and remove it from cache and filter"`
+	KeyStats        KeyStatsCommand        `command:"key-stats" description:"show key pool and quarantine stats"`
+	GenerateKeys    GenerateKeysCommand    `command:"generate-keys" description:"insert random keys into the key pool"`
+	FilterCheck     FilterCheckCommand     `command:"filter-check" description:"check whether an id is in the filter, always true with local backend"`
+	FilterInfo      FilterInfoCommand      `command:"filter-info" description:"show items, capacity and estimated false positive rate of the redis filter"`
+	CacheInvalidate CacheInvalidateCommand `command:"cache-invalidate" description:"delete cached original urls"`
+	WarmCache       WarmCacheCommand       `command:"warm-cache" description:"put most recently created active urls into cache and filter"`
+}
+
+type admin struct {
+	urlDAO         dao.UrlDAO
+	keyDAO         dao.KeyDAO
+	cacheDAO       dao.CacheDAO
+	healthCheckDAO dao.HealthCheckDAO
+	filter         dao.OriginalURLIDFilter
+}
+
+func main() {
+	var env Environment
+	parser := flags.NewParser(&env, flags.Default)
+	if _, err := parser.Parse(); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		} else {
+			os.Exit(1)
+		}
+	}
+
+	pgClient, err := pglib.NewDefaultGOPGClient(pglib.GOPGConfig{
+		URL:       env.PostgresConfig.URL,
+		DebugMode: env.PostgresConfig.DebugMode,
+		PoolSize:  1,
+	})
+	if err != nil {
+		log.Fatalf("fail to init postgres client:%v", err)
+	}
+
+	redisClient, err := redislib.NewGORedisClient(redislib.GORedisConfig{URL: env.RedisConfig.URL}, nil)
+	if err != nil {
+		log.Fatalf("fail to init redis client:%v", err)
+	}
+
+	logger, err := loglib.NewProductionLogger()
+	if err != nil {
+		log.Fatalf("fail to init logger:%v", err)
+	}
+
+	if err := shortid.ValidateLength(env.IDConfig.Length); err != nil {
+		log.Fatalf("invalid id length:%v", err)
+	}
+	randomStrGenerator, err := shortid.NewGenerator(env.IDConfig.Charset)
+	if err != nil {
+		log.Fatalf("invalid id charset:%v", err)
+	}
+
+	filter, err := dao.NewOriginalURLIDFilter(logger, redisClient, dao.FilterConfig{Backend: env.FilterConfig.Backend})
+	if err != nil {
+		log.Fatalf("fail to init filter:%v", err)
+	}
+
+	a := &admin{
+		// admin不會新增url 用不到key strategy
+		urlDAO:         dao.NewPGUrlDAO(logger, pgClient, env.RecycleConfig.Cooldown, dao.NewPoolKeyStrategy()),
+		keyDAO:         dao.NewPGKeyDAO(logger, pgClient, randomStrGenerator, env.IDConfig.Length),
+		cacheDAO:       dao.NewRedisCacheDAO(logger, redisClient, 0, filter),
+		healthCheckDAO: dao.NewPGHealthCheckDAO(logger, pgClient),
+		filter:         filter,
+	}
+
+	// Ctrl+C時取消還在跑的query
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var result interface{}
+	var businessErr *business.Error
+	switch parser.Active.Name {
+	case "inspect":
+		result, businessErr = a.inspect(ctx, env.Inspect.Args.ID)
+	case "expire":
+		result, businessErr = a.expire(ctx, env.Expire.Args.ID)
+	case "delete":
+		result, businessErr = a.delete(ctx, env.Delete.Args.ID)
+	case "key-stats":
+		result, businessErr = a.keyStats(ctx)
+	case "generate-keys":
+		result, businessErr = a.generateKeys(ctx, env.GenerateKeys.Number)
+	case "filter-check":
+		result, businessErr = a.filterCheck(ctx, env.FilterCheck.Args.ID)
+	case "filter-info":
+		result, businessErr = a.filterInfo(ctx)
+	case "cache-invalidate":
+		result, businessErr = a.cacheInvalidate(ctx, env.CacheInvalidate.Args.IDs)
+	case "warm-cache":
+		result, businessErr = job.NewWarmCacheJob(job.WarmCacheJobConfig{
+			Name:      "WarmCacheJob",
+			Number:    env.WarmCache.Number,
+			BatchSize: env.WarmCache.BatchSize,
+			Interval:  env.WarmCache.Interval,
+		}, a.urlDAO, a.cacheDAO).Work(ctx)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if businessErr != nil {
+		encoder.Encode(map[string]interface{}{"code": businessErr.BusinessCode, "message": businessErr.Message, "reason": businessErr.Error()})
+		os.Exit(1)
+	}
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalf("fail to print result:%v", err)
+	}
+}
+
+func (a *admin) inspect(ctx context.Context, id string) (interface{}, *business.Error) {
+	url, err := a.urlDAO.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// cache沒有、cache記著不存在(negative cache)或是health check還沒跑過都不算錯誤
+	negativeCached := false
+	cached, err := a.cacheDAO.GetOriginalURL(ctx, id)
+	if err != nil {
+		switch err.Reason {
+		case dao.RedisErrKeyNotExist:
+		case dao.RedisErrCachedNotFound:
+			negativeCached = true
+		default:
+			return nil, err
+		}
+	}
+	inFilter, err := a.cacheDAO.ExistOriginalURLIDInFilters(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	health, err := a.healthCheckDAO.Get(ctx, id)
+	if err != nil && err.BusinessCode != business.NotFound {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"url":            url,
+		"expired":        !url.ExpiredAt.After(time.Now()),
+		"cachedOriginal": cached,
+		"negativeCached": negativeCached,
+		"inFilter":       inFilter,
+		"health":         health,
+	}, nil
+}
+
+func (a *admin) expire(ctx context.Context, id string) (interface{}, *business.Error) {
+	if err := a.urlDAO.ForceExpire(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := a.cacheDAO.DeleteOriginalURL(ctx, id); err != nil {
+		return nil, err
+	}
+	removed, err := a.cacheDAO.DeleteOriginalURLIDInFilters(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"id": id, "expired": true, "removedFromFilter": removed}, nil
+}
+
+func (a *admin) delete(ctx context.Context, id string) (interface{}, *business.Error) {
+	if err := a.cacheDAO.DeleteOriginalURL(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := a.urlDAO.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	removed, err := a.cacheDAO.DeleteOriginalURLIDInFilters(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"id": id, "deleted": true, "removedFromFilter": removed}, nil
+}
+
+func (a *admin) keyStats(ctx context.Context) (interface{}, *business.Error) {
+	available, err := a.keyDAO.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quarantined, err := a.keyDAO.CountQuarantined(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"available": available, "quarantined": quarantined}, nil
+}
+
+func (a *admin) generateKeys(ctx context.Context, number int) (interface{}, *business.Error) {
+	inserted, err := a.keyDAO.BatchCreate(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"requested": number, "inserted": inserted, "skipped": number - inserted}, nil
+}
+
+func (a *admin) filterCheck(ctx context.Context, id string) (interface{}, *business.Error) {
+	exist, err := a.cacheDAO.ExistOriginalURLIDInFilters(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// filter 可能會有false positive 所以exist只代表可能存在 local backend在admin裡沒有載入 一律回傳true
+	return map[string]interface{}{"id": id, "mayExist": exist}, nil
+}
+
+func (a *admin) filterInfo(ctx context.Context) (interface{}, *business.Error) {
+	inspector, ok := a.filter.(dao.OriginalURLIDFilterInspector)
+	if !ok {
+		return nil, business.NewError(business.Validation, http.StatusBadRequest, "filter info is only available with redis backend", errors.New("filter backend is not redis"))
+	}
+	return inspector.Info(ctx)
+}
+
+func (a *admin) cacheInvalidate(ctx context.Context, ids []string) (interface{}, *business.Error) {
+	if err := a.cacheDAO.DeleteMultiOriginalURL(ctx, ids); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"invalidated": ids}, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/lock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/repository"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+	"github.com/KennyChenFight/Shortening-URL/pkg/transfer"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/pglib"
+	"github.com/KennyChenFight/golib/redislib"
+	"github.com/jessevdk/go-flags"
+)
+
+type PostgresConfig struct {
+	URL       string `long:"url" description:"database url" env:"URL" required:"true"`
+	DebugMode bool   `long:"debug-mode" description:"database debug mode" env:"DEBUG_MODE"`
+	PoolSize  int    `long:"pool-size" description:"database pool size" env:"POOL_SIZE" default:"10"`
+}
+
+type FilterConfig struct {
+	Backend string `long:"backend" description:"where url ids are filtered, should match the server, local only publishes changes to servers" env:"BACKEND" choice:"auto" choice:"redis" choice:"local" default:"auto"`
+}
+
+type TimeoutConfig struct {
+	Read  time.Duration `long:"read" description:"max duration of reading one batch, 0 disables the timeout" env:"READ" default:"30s"`
+	Write time.Duration `long:"write" description:"max duration of writing one batch, 0 disables the timeout" env:"WRITE" default:"30s"`
+}
+
+type LockConfig struct {
+	Backend string `long:"backend" description:"where locks are held, should match the server" env:"BACKEND" choice:"redis" choice:"postgres" default:"redis"`
+}
+
+type RedisConfig struct {
+	URL string `long:"url" description:"redis url" env:"URL" required:"true"`
+}
+
+type ExportCommand struct {
+	Format         string `long:"format" description:"file format" choice:"csv" choice:"jsonl" default:"csv"`
+	Output         string `long:"output" short:"o" description:"output file, stdout if empty"`
+	IncludeExpired bool   `long:"include-expired" description:"also export expired urls"`
+	BatchSize      int    `long:"batch-size" description:"urls per query" default:"1000"`
+}
+
+type ImportCommand struct {
+	Format    string `long:"format" description:"file format" choice:"csv" choice:"jsonl" default:"csv"`
+	Input     string `long:"input" short:"i" description:"input file, stdin if empty"`
+	BatchSize int    `long:"batch-size" description:"urls per transaction" default:"500"`
+}
+
+type IDConfig struct {
+	Length   int    `long:"length" description:"length of new ids, existing ids keep their length" env:"LENGTH" default:"6"`
+	Charset  string `long:"charset" description:"charset of new ids" env:"CHARSET" choice:"alphabet" choice:"base62" choice:"lowercase" choice:"unambiguous" default:"alphabet"`
+	Strategy string `long:"strategy" description:"how new ids are assigned" env:"STRATEGY" choice:"pool" choice:"sequence" choice:"hash" default:"pool"`
+	Secret   string `long:"secret" description:"secret to scramble sequence ids, required by sequence strategy" env:"SECRET"`
+}
+
+type Environment struct {
+	PostgresConfig PostgresConfig `group:"postgres" namespace:"postgres" env-namespace:"POSTGRES"`
+	RedisConfig    RedisConfig    `group:"redis" namespace:"redis" env-namespace:"REDIS"`
+	FilterConfig   FilterConfig   `group:"filter" namespace:"filter" env-namespace:"FILTER"`
+	LockConfig     LockConfig     `group:"lock" namespace:"lock" env-namespace:"LOCK"`
+	TimeoutConfig  TimeoutConfig  `group:"timeout" namespace:"timeout" env-namespace:"TIMEOUT"`
+	IDConfig       IDConfig       `group:"id" namespace:"id" env-namespace:"ID"`
+	Export         ExportCommand  `command:"export" description:"export urls to csv or jsonl"`
+	Import         ImportCommand  `command:"import" description:"import urls from csv or jsonl, keep the original id when it is free"`
+}
+
+func main() {
+	var env Environment
+	parser := flags.NewParser(&env, flags.Default)
+	if _, err := parser.Parse(); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		} else {
+			os.Exit(1)
+		}
+	}
+
+	// log.Fatalf不會跑defer 錯誤都回到這裡 檔案跟連線關掉之後才離開
+	if err := run(env, parser.Active.Name); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func run(env Environment, command string) error {
+	pgClient, err := pglib.NewDefaultGOPGClient(pglib.GOPGConfig{
+		URL:       env.PostgresConfig.URL,
+		DebugMode: env.PostgresConfig.DebugMode,
+		PoolSize:  env.PostgresConfig.PoolSize,
+	})
+	if err != nil {
+		return fmt.Errorf("fail to init postgres client:%w", err)
+	}
+	defer pgClient.Close()
+
+	redisClient, err := redislib.NewGORedisClient(redislib.GORedisConfig{URL: env.RedisConfig.URL}, nil)
+	if err != nil {
+		return fmt.Errorf("fail to init redis client:%w", err)
+	}
+	defer redisClient.Close()
+
+	logger, err := loglib.NewProductionLogger()
+	if err != nil {
+		return fmt.Errorf("fail to init logger:%w", err)
+	}
+
+	if err := shortid.ValidateLength(env.IDConfig.Length); err != nil {
+		return fmt.Errorf("invalid id length:%w", err)
+	}
+	randomStrGenerator, err := shortid.NewGenerator(env.IDConfig.Charset)
+	if err != nil {
+		return fmt.Errorf("invalid id charset:%w", err)
+	}
+
+	filter, err := dao.NewOriginalURLIDFilter(logger, redisClient, dao.FilterConfig{Backend: env.FilterConfig.Backend})
+	if err != nil {
+		return fmt.Errorf("fail to init filter:%w", err)
+	}
+
+	keyStrategy, err := dao.NewKeyStrategy(dao.KeyStrategyConfig{
+		Strategy: env.IDConfig.Strategy,
+		Charset:  env.IDConfig.Charset,
+		Length:   env.IDConfig.Length,
+		Secret:   env.IDConfig.Secret,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid id strategy:%w", err)
+	}
+
+	// 匯入不會刪除url 不需要回收設定
+	urlDAO := dao.NewPGUrlDAO(logger, pgClient, 0, keyStrategy)
+	keyDAO := dao.NewPGKeyDAO(logger, pgClient, randomStrGenerator, env.IDConfig.Length)
+	cacheDAO := dao.NewRedisCacheDAO(logger, redisClient, 0, filter)
+	healthCheckDAO := dao.NewPGHealthCheckDAO(logger, pgClient)
+	var locker lock.Locker = lock.NewRedisLocker(logger, redisClient, false)
+	if env.LockConfig.Backend == "postgres" {
+		locker = lock.NewPGLocker(logger, pgClient)
+	}
+
+	urlRepository := repository.NewURLRepository(logger, urlDAO, keyDAO, cacheDAO, healthCheckDAO, locker, env.IDConfig.Strategy, repository.Timeouts{
+		Read:  env.TimeoutConfig.Read,
+		Write: env.TimeoutConfig.Write,
+	})
+
+	// Ctrl+C時取消還在跑的batch 已經寫入的batch不會rollback
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command {
+	case "export":
+		return runExport(ctx, env.Export, urlRepository)
+	case "import":
+		return runImport(ctx, env.Import, urlRepository)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, cmd ExportCommand, urlRepository repository.Repository) error {
+	var w io.Writer = os.Stdout
+	if cmd.Output != "" {
+		file, err := os.Create(cmd.Output)
+		if err != nil {
+			return fmt.Errorf("fail to create output file:%w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	exported, err := transfer.Export(ctx, w, transfer.Format(cmd.Format), urlRepository, cmd.BatchSize, cmd.IncludeExpired)
+	if err != nil {
+		return fmt.Errorf("fail to export urls after %d exported:%w", exported, err)
+	}
+	log.Printf("exported %d urls", exported)
+	return nil
+}
+
+func runImport(ctx context.Context, cmd ImportCommand, urlRepository repository.Repository) error {
+	var r io.Reader = os.Stdin
+	if cmd.Input != "" {
+		file, err := os.Open(cmd.Input)
+		if err != nil {
+			return fmt.Errorf("fail to open input file:%w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	summary, err := transfer.Import(ctx, r, transfer.Format(cmd.Format), urlRepository, cmd.BatchSize)
+	// 中途失敗也把已經匯入的結果印出來 id對應表不能丟
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(summary); encodeErr != nil {
+		log.Printf("fail to print import summary:%v", encodeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("fail to import urls:%w", err)
+	}
+	return nil
+}
@@ -3,43 +3,106 @@ package main
 import (
 	"context"
 	"log"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/KennyChenFight/golib/redislib"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
 	"github.com/KennyChenFight/Shortening-URL/pkg/graceful"
 	"github.com/KennyChenFight/Shortening-URL/pkg/job"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/KennyChenFight/golib/pglib"
-	"github.com/KennyChenFight/randstr"
 	"github.com/jessevdk/go-flags"
+	"go.uber.org/zap"
 )
 
+const storageSQLite = "sqlite"
+
 type PostgresConfig struct {
-	URL              string `long:"url" description:"database url" env:"URL" required:"true"`
+	URL              string `long:"url" description:"database url, required by postgres storage" env:"URL"`
 	DebugMode        bool   `long:"debug-mode" description:"database debug mode" env:"DEBUG_MODE"`
 	PoolSize         int    `long:"pool-size" description:"database pool size" env:"POOL_SIZE" default:"100"`
 	MigrationFileDir string `long:"migration-file-dir" description:"migration file dir" env:"MIGRATION_FILE_DIR" default:"file://migrations"`
 }
 
+type SQLiteConfig struct {
+	Path string `long:"path" description:"database file, should be the same file as the server" env:"PATH" default:"shortening-url.db"`
+}
+
+type FilterConfig struct {
+	Backend            string  `long:"backend" description:"where url ids are filtered, should match the server, local only publishes changes to servers" env:"BACKEND" choice:"auto" choice:"redis" choice:"local" default:"auto"`
+	Capacity           int     `long:"capacity" description:"expected url ids, reserved for the redis filter when it does not exist yet or is rebuilt" env:"CAPACITY" default:"1000000"`
+	BucketSize         int     `long:"bucket-size" description:"fingerprints per bucket of the redis filter" env:"BUCKET_SIZE" default:"2"`
+	Expansion          int     `long:"expansion" description:"size of a new sub filter relative to the last one when the redis filter is full" env:"EXPANSION" default:"1"`
+	RebuildTimerFormat string  `long:"rebuild-timer-format" description:"rebuild the redis filter from urls cron format" env:"REBUILD_TIMER_FORMAT" default:"45 3 * * *"`
+	RebuildPageSize    int     `long:"rebuild-page-size" description:"url ids read per query while rebuilding" env:"REBUILD_PAGE_SIZE" default:"1000"`
+	StatsTimerFormat   string  `long:"stats-timer-format" description:"check how full the redis filter is cron format" env:"STATS_TIMER_FORMAT" default:"*/5 * * * *"`
+	WarnFillRatio      float64 `long:"warn-fill-ratio" description:"warn when items divided by capacity reaches this ratio" env:"WARN_FILL_RATIO" default:"0.8"`
+	WarnErrorRate      float64 `long:"warn-error-rate" description:"warn when the estimated false positive rate reaches this rate" env:"WARN_ERROR_RATE" default:"0.03"`
+}
+
 type RedisConfig struct {
 	URL string `long:"url" description:"redis url" env:"URL" required:"true"`
 }
 
 type GenerateKeyConfig struct {
-	EveryKeyNumber int `long:"every-key-number" description:"every key number" env:"EVERY_KEY_NUMBER" default:"100"`
+	TimerFormat   string `long:"timer-format" description:"key pool check cron format" env:"TIMER_FORMAT" default:"*/5 * * * *"`
+	LowWatermark  int    `long:"low-watermark" description:"refill when unused keys drop below this" env:"LOW_WATERMARK" default:"10000"`
+	HighWatermark int    `long:"high-watermark" description:"refill up to this many unused keys" env:"HIGH_WATERMARK" default:"50000"`
+	MaxBatchSize  int    `long:"max-batch-size" description:"max keys per insert" env:"MAX_BATCH_SIZE" default:"5000"`
+}
+
+type MetricsConfig struct {
+	Port string `long:"port" description:"prometheus metrics port" env:"PORT" default:":2112"`
 }
 
 type ExpireURLConfig struct {
 	Number int `long:"number" description:"expire url number" env:"NUMBER" default:"1000"`
 }
 
+type HealthCheckConfig struct {
+	TimerFormat     string        `long:"timer-format" description:"health check cron format" env:"TIMER_FORMAT" default:"0 */6 * * *"`
+	BatchSize       int           `long:"batch-size" description:"urls per page" env:"BATCH_SIZE" default:"500"`
+	Concurrency     int           `long:"concurrency" description:"max concurrent probes" env:"CONCURRENCY" default:"20"`
+	PerHostInterval time.Duration `long:"per-host-interval" description:"min interval between requests to the same host" env:"PER_HOST_INTERVAL" default:"1s"`
+	Timeout         time.Duration `long:"timeout" description:"probe timeout" env:"TIMEOUT" default:"10s"`
+	BrokenThreshold int           `long:"broken-threshold" description:"consecutive failures to mark a link as broken" env:"BROKEN_THRESHOLD" default:"3"`
+	UserAgent       string        `long:"user-agent" description:"probe user agent" env:"USER_AGENT" default:"Shortening-URL-HealthCheck/1.0"`
+}
+
+type IDConfig struct {
+	Length   int    `long:"length" description:"length of new ids, existing ids keep their length" env:"LENGTH" default:"6"`
+	Charset  string `long:"charset" description:"charset of new ids" env:"CHARSET" choice:"alphabet" choice:"base62" choice:"lowercase" choice:"unambiguous" default:"alphabet"`
+	Strategy string `long:"strategy" description:"how new ids are assigned, keys are only generated for pool" env:"STRATEGY" choice:"pool" choice:"sequence" choice:"hash" default:"pool"`
+}
+
+type RecycleConfig struct {
+	Cooldown      time.Duration `long:"cooldown" description:"cooldown before ids of expired or deleted urls go back to the key pool, 0 disables recycling" env:"COOLDOWN" default:"0s"`
+	TimerFormat   string        `long:"timer-format" description:"recycle cron format" env:"TIMER_FORMAT" default:"15 * * * *"`
+	ReleaseNumber int           `long:"release-number" description:"max ids put back per run" env:"RELEASE_NUMBER" default:"1000"`
+}
+
+type TimeoutConfig struct {
+	Job time.Duration `long:"job" description:"max duration of one round of a job, 0 disables the timeout" env:"JOB" default:"1h"`
+}
+
 type Environment struct {
 	PostgresConfig    PostgresConfig    `group:"postgres" namespace:"postgres" env-namespace:"POSTGRES"`
+	SQLiteConfig      SQLiteConfig      `group:"sqlite" namespace:"sqlite" env-namespace:"SQLITE"`
 	RedisConfig       RedisConfig       `group:"redis" namespace:"redis" env-namespace:"REDIS"`
+	FilterConfig      FilterConfig      `group:"filter" namespace:"filter" env-namespace:"FILTER"`
+	IDConfig          IDConfig          `group:"id" namespace:"id" env-namespace:"ID"`
+	RecycleConfig     RecycleConfig     `group:"recycle" namespace:"recycle" env-namespace:"RECYCLE"`
 	GenerateKeyConfig GenerateKeyConfig `group:"generate-key" namespace:"generate-key" env-namespace:"GENERATE_KEY"`
 	ExpireURLConfig   ExpireURLConfig   `group:"expire-url" namespace:"expire-url" env-namespace:"EXPIRE_URL"`
+	HealthCheckConfig HealthCheckConfig `group:"health-check" namespace:"health-check" env-namespace:"HEALTH_CHECK"`
+	MetricsConfig     MetricsConfig     `group:"metrics" namespace:"metrics" env-namespace:"METRICS"`
+	TimeoutConfig     TimeoutConfig     `group:"timeout" namespace:"timeout" env-namespace:"TIMEOUT"`
+	Storage           string            `long:"storage" description:"where urls and keys are kept, should match the server" env:"STORAGE" choice:"postgres" choice:"sqlite" default:"postgres"`
 }
 
 func main() {
@@ -52,14 +115,11 @@ func main() {
 			os.Exit(1)
 		}
 	}
-
-	pgClient, err := pglib.NewDefaultGOPGClient(pglib.GOPGConfig{
-		URL:       env.PostgresConfig.URL,
-		DebugMode: env.PostgresConfig.DebugMode,
-		PoolSize:  env.PostgresConfig.PoolSize,
-	})
-	if err != nil {
-		log.Fatalf("fail to init postgres client:%v", err)
+	if env.GenerateKeyConfig.LowWatermark >= env.GenerateKeyConfig.HighWatermark {
+		log.Fatalf("generate-key low watermark:%d must be less than high watermark:%d", env.GenerateKeyConfig.LowWatermark, env.GenerateKeyConfig.HighWatermark)
+	}
+	if env.HealthCheckConfig.Concurrency <= 0 || env.HealthCheckConfig.BatchSize <= 0 {
+		log.Fatalf("health-check concurrency:%d and batch size:%d must be greater than 0", env.HealthCheckConfig.Concurrency, env.HealthCheckConfig.BatchSize)
 	}
 
 	redisClient, err := redislib.NewGORedisClient(redislib.GORedisConfig{URL: env.RedisConfig.URL}, nil)
@@ -72,29 +132,137 @@ func main() {
 		log.Fatalf("fail to init logger:%v", err)
 	}
 
-	randomStrGenerator := randstr.NewFastGenerator(randstr.CharSetEnglishAlphabet)
+	if err := shortid.ValidateLength(env.IDConfig.Length); err != nil {
+		log.Fatalf("invalid id length:%v", err)
+	}
+	randomStrGenerator, err := shortid.NewGenerator(env.IDConfig.Charset)
+	if err != nil {
+		log.Fatalf("invalid id charset:%v", err)
+	}
+
+	filter, err := dao.NewOriginalURLIDFilter(logger, redisClient, dao.FilterConfig{
+		Backend:    env.FilterConfig.Backend,
+		Capacity:   env.FilterConfig.Capacity,
+		BucketSize: env.FilterConfig.BucketSize,
+		Expansion:  env.FilterConfig.Expansion,
+	})
+	if err != nil {
+		log.Fatalf("fail to init filter:%v", err)
+	}
 
-	keyDAO := dao.NewPGKeyDAO(logger, pgClient, randomStrGenerator)
-	urlDAO := dao.NewPGUrlDAO(logger, pgClient)
-	cacheDAO := dao.NewRedisCacheDAO(logger, redisClient)
+	var (
+		keyDAO         dao.KeyDAO
+		urlDAO         dao.UrlDAO
+		healthCheckDAO dao.HealthCheckDAO
+	)
+	// migration由server負責 cron只連同一個database
+	if env.Storage == storageSQLite {
+		if env.IDConfig.Strategy != dao.KeyStrategyPool {
+			log.Fatalf("sqlite storage only supports pool id strategy")
+		}
+		sqliteDB, err := dao.NewSQLiteClient(env.SQLiteConfig.Path)
+		if err != nil {
+			log.Fatalf("fail to init sqlite client:%v", err)
+		}
+		keyDAO = dao.NewSQLiteKeyDAO(logger, sqliteDB, randomStrGenerator, env.IDConfig.Length)
+		urlDAO = dao.NewSQLiteUrlDAO(logger, sqliteDB, env.RecycleConfig.Cooldown, nil)
+		healthCheckDAO = dao.NewSQLiteHealthCheckDAO(logger, sqliteDB)
+	} else {
+		if env.PostgresConfig.URL == "" {
+			log.Fatalf("postgres url is required by postgres storage")
+		}
+		pgClient, err := pglib.NewDefaultGOPGClient(pglib.GOPGConfig{
+			URL:       env.PostgresConfig.URL,
+			DebugMode: env.PostgresConfig.DebugMode,
+			PoolSize:  env.PostgresConfig.PoolSize,
+		})
+		if err != nil {
+			log.Fatalf("fail to init postgres client:%v", err)
+		}
+		keyDAO = dao.NewPGKeyDAO(logger, pgClient, randomStrGenerator, env.IDConfig.Length)
+		// cronjob不會新增url 用不到key strategy
+		urlDAO = dao.NewPGUrlDAO(logger, pgClient, env.RecycleConfig.Cooldown, dao.NewPoolKeyStrategy())
+		healthCheckDAO = dao.NewPGHealthCheckDAO(logger, pgClient)
+	}
+	cacheDAO := dao.NewRedisCacheDAO(logger, redisClient, 0, filter)
 
-	// gen key cronjob時間可以設在離峰時期 可以在上線前就gen足夠的key 之後可以每隔一段時期在gen key即可
-	generateKeyJob := job.NewGenerateKeyJob(job.GenerateKeyJobConfig{Name: "GenerateKeyJob", TimerFormat: "30 23 * * sun", EveryKeyNumber: env.GenerateKeyConfig.EveryKeyNumber}, keyDAO)
+	// gen key cronjob 頻繁檢查keys table剩多少key 低於low watermark才補到high watermark 平常只有一個count query
+	generateKeyJob := job.NewGenerateKeyJob(job.GenerateKeyJobConfig{
+		Name:          "GenerateKeyJob",
+		TimerFormat:   env.GenerateKeyConfig.TimerFormat,
+		LowWatermark:  env.GenerateKeyConfig.LowWatermark,
+		HighWatermark: env.GenerateKeyConfig.HighWatermark,
+		MaxBatchSize:  env.GenerateKeyConfig.MaxBatchSize,
+	}, keyDAO)
 	// expireURL cronjob時間可以設在每天半夜的時候來delete 一定數量的expired URL
 	expireURLJob := job.NewExpiredURLJob(job.ExpiredURLJobConfig{Name: "ExpiredURLJob", TimerFormat: "30 23 * * *", ExpireURLNumber: env.ExpireURLConfig.Number}, urlDAO, cacheDAO)
-	jobs := []job.Job{generateKeyJob, expireURLJob}
-	manager := job.NewManager(jobs, logger)
+	// health check 只probe還沒過期的url 連續失敗超過threshold會被標記為broken
+	healthCheckJob := job.NewHealthCheckJob(job.HealthCheckJobConfig{
+		Name:            "HealthCheckJob",
+		TimerFormat:     env.HealthCheckConfig.TimerFormat,
+		BatchSize:       env.HealthCheckConfig.BatchSize,
+		Concurrency:     env.HealthCheckConfig.Concurrency,
+		PerHostInterval: env.HealthCheckConfig.PerHostInterval,
+		Timeout:         env.HealthCheckConfig.Timeout,
+		BrokenThreshold: env.HealthCheckConfig.BrokenThreshold,
+		UserAgent:       env.HealthCheckConfig.UserAgent,
+	}, logger, urlDAO, healthCheckDAO, job.NewHealthCheckHTTPClient())
+	jobs := []job.Job{expireURLJob, healthCheckJob}
+	// 只有pool strategy需要預先產生key
+	if env.IDConfig.Strategy == dao.KeyStrategyPool {
+		jobs = append(jobs, generateKeyJob)
+	}
+	// 有開啟回收才需要把冷卻期過後的id放回keys
+	if env.RecycleConfig.Cooldown > 0 {
+		recycleKeyJob := job.NewRecycleKeyJob(job.RecycleKeyJobConfig{
+			Name:          "RecycleKeyJob",
+			TimerFormat:   env.RecycleConfig.TimerFormat,
+			ReleaseNumber: env.RecycleConfig.ReleaseNumber,
+		}, keyDAO)
+		jobs = append(jobs, recycleKeyJob)
+	}
+	// redis的filter跟database不一致時 定期整個重建 local的filter每次server啟動都會重新載入
+	if rebuilder, ok := filter.(dao.OriginalURLIDFilterRebuilder); ok {
+		rebuildFilterJob := job.NewRebuildFilterJob(job.RebuildFilterJobConfig{
+			Name:        "RebuildFilterJob",
+			TimerFormat: env.FilterConfig.RebuildTimerFormat,
+			PageSize:    env.FilterConfig.RebuildPageSize,
+		}, urlDAO, rebuilder)
+		jobs = append(jobs, rebuildFilterJob)
+	}
+	// cuckoo filter快滿或是擴充過之後false positive rate會變高 定期把使用狀況放到metrics
+	if inspector, ok := filter.(dao.OriginalURLIDFilterInspector); ok {
+		filterStatsJob := job.NewFilterStatsJob(job.FilterStatsJobConfig{
+			Name:          "FilterStatsJob",
+			TimerFormat:   env.FilterConfig.StatsTimerFormat,
+			WarnFillRatio: env.FilterConfig.WarnFillRatio,
+			WarnErrorRate: env.FilterConfig.WarnErrorRate,
+		}, logger, inspector)
+		jobs = append(jobs, filterStatsJob)
+	}
+	manager := job.NewManager(jobs, logger, env.TimeoutConfig.Job)
 
-	graceful.Wrapper(logger, StartFunc(logger, manager))
+	metricsServer := &http.Server{Addr: env.MetricsConfig.Port, Handler: metrics.Handler()}
+
+	if err := graceful.Wrapper(logger, StartFunc(logger, manager, metricsServer)); err != nil {
+		log.Fatalf("fail to start cronjob:%v", err)
+	}
 }
 
-func StartFunc(logger *loglib.Logger, manager *job.Manager) func(ctx context.Context) error {
+func StartFunc(logger *loglib.Logger, manager *job.Manager, metricsServer *http.Server) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
 		go func() {
 			manager.Start()
 			logger.Info("start cronjob...")
 		}()
 
+		go func() {
+			logger.Info("start metrics server...")
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("metrics server fail", zap.Error(err))
+			}
+		}()
+
 		<-ctx.Done()
 
 		ctx1, cancel1 := context.WithCancel(context.Background())
@@ -102,6 +270,9 @@ func StartFunc(logger *loglib.Logger, manager *job.Manager) func(ctx context.Con
 		go func() {
 			logger.Info("shutdown cronjob manager...")
 			manager.Stop()
+			if err := metricsServer.Shutdown(context.Background()); err != nil {
+				logger.Error("fail to shutdown metrics server", zap.Error(err))
+			}
 			cancel1()
 		}()
 
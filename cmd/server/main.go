@@ -2,18 +2,21 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"time"
 
-	"github.com/KennyChenFight/randstr"
-
 	"github.com/KennyChenFight/Shortening-URL/pkg/graceful"
 
 	"github.com/KennyChenFight/golib/ratelimitlib"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/repository"
+	"github.com/KennyChenFight/Shortening-URL/pkg/rpc"
+	"google.golang.org/grpc"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/lock"
 
@@ -31,20 +34,53 @@ import (
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/job"
+	"github.com/KennyChenFight/Shortening-URL/pkg/keylease"
+	"github.com/KennyChenFight/Shortening-URL/pkg/localcache"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+	"github.com/KennyChenFight/Shortening-URL/pkg/ratelimit"
 	"github.com/KennyChenFight/Shortening-URL/pkg/server"
 	"github.com/KennyChenFight/Shortening-URL/pkg/service"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
 	"github.com/gin-gonic/gin"
 	"github.com/jessevdk/go-flags"
 )
 
+const (
+	storageSQLite = "sqlite"
+	storageMemory = "memory"
+)
+
+// memory模式的job設定 資料量不大 不開放調整
+const (
+	memoryKeyLowWatermark  = 1000
+	memoryKeyHighWatermark = 10000
+	memoryExpireURLNumber  = 10000
+)
+
 type PostgresConfig struct {
-	URL              string `long:"url" description:"database url" env:"URL" required:"true"`
+	URL              string `long:"url" description:"database url, required by postgres storage" env:"URL"`
 	MigrationFileDir string `long:"migration-file-dir" description:"migration file dir" env:"MIGRATION_FILE_DIR" default:"file://migrations"`
 }
 
+type SQLiteConfig struct {
+	Path             string `long:"path" description:"database file, shared with cron on the same host" env:"PATH" default:"shortening-url.db"`
+	MigrationFileDir string `long:"migration-file-dir" description:"sqlite migration file dir" env:"MIGRATION_FILE_DIR" default:"file://migrations/sqlite"`
+}
+
 type RedisConfig struct {
-	URL string `long:"url" description:"redis url" env:"URL" required:"true"`
+	URL string `long:"url" description:"redis url, required by postgres and sqlite storage" env:"URL"`
+}
+
+type FilterConfig struct {
+	Backend        string        `long:"backend" description:"where url ids are filtered, auto falls back to local when redis has no RedisBloom module" env:"BACKEND" choice:"auto" choice:"redis" choice:"local" default:"auto"`
+	Capacity       int           `long:"capacity" description:"expected url ids, reserved for the redis filter when it does not exist yet" env:"CAPACITY" default:"1000000"`
+	ErrorRate      float64       `long:"error-rate" description:"false positive rate of the local filter" env:"ERROR_RATE" default:"0.01"`
+	ReloadInterval time.Duration `long:"reload-interval" description:"rebuild the local filter from urls to recover missed changes, 0 only rebuilds on subscribe" env:"RELOAD_INTERVAL" default:"1h"`
+	BucketSize     int           `long:"bucket-size" description:"fingerprints per bucket of the redis filter" env:"BUCKET_SIZE" default:"2"`
+	Expansion      int           `long:"expansion" description:"size of a new sub filter relative to the last one when the redis filter is full" env:"EXPANSION" default:"1"`
 }
 
 type SlideWindowRateLimiterConfig struct {
@@ -57,11 +93,80 @@ type GinConfig struct {
 	Mode string `long:"mode" description:"mode" env:"MODE" default:"debug"`
 }
 
+type AdminConfig struct {
+	Port string `long:"port" description:"port of bulk export/import api, disabled when empty, do not expose it publicly" env:"PORT"`
+}
+
+type GRPCConfig struct {
+	Port         string `long:"port" description:"port" env:"PORT" default:":9090"`
+	MaxBatchSize int    `long:"max-batch-size" description:"max ids of batch resolve" env:"MAX_BATCH_SIZE" default:"100"`
+}
+
+type IDConfig struct {
+	Length   int    `long:"length" description:"length of new ids, existing ids keep their length" env:"LENGTH" default:"6"`
+	Charset  string `long:"charset" description:"charset of new ids" env:"CHARSET" choice:"alphabet" choice:"base62" choice:"lowercase" choice:"unambiguous" default:"alphabet"`
+	Strategy string `long:"strategy" description:"how new ids are assigned" env:"STRATEGY" choice:"pool" choice:"sequence" choice:"hash" default:"pool"`
+	Secret   string `long:"secret" description:"secret to scramble sequence ids, required by sequence strategy" env:"SECRET"`
+}
+
+type RecycleConfig struct {
+	Cooldown time.Duration `long:"cooldown" description:"cooldown before ids of expired or deleted urls go back to the key pool, 0 disables recycling" env:"COOLDOWN" default:"0s"`
+}
+
+type KeyLeaseConfig struct {
+	BlockSize       int `long:"block-size" description:"keys leased into memory at once, 0 disables leasing, pool strategy only" env:"BLOCK_SIZE" default:"0"`
+	RefillThreshold int `long:"refill-threshold" description:"lease another block in background when fewer keys are left in memory" env:"REFILL_THRESHOLD" default:"100"`
+}
+
+type L1CacheConfig struct {
+	Size int           `long:"size" description:"max original urls cached in process, 0 disables the l1 cache" env:"SIZE" default:"0"`
+	TTL  time.Duration `long:"ttl" description:"how long an original url stays in the l1 cache" env:"TTL" default:"10s"`
+}
+
+type NegativeCacheConfig struct {
+	TTL time.Duration `long:"ttl" description:"how long unknown or expired ids are remembered in redis, 0 disables negative caching" env:"TTL" default:"30s"`
+}
+
+type WarmUpConfig struct {
+	Number    int           `long:"number" description:"most recently created active urls put into cache and filter at start, 0 disables warm up" env:"NUMBER" default:"0"`
+	BatchSize int           `long:"batch-size" description:"urls read from database per batch" env:"BATCH_SIZE" default:"500"`
+	Interval  time.Duration `long:"interval" description:"pause between batches" env:"INTERVAL" default:"200ms"`
+}
+
+type TimeoutConfig struct {
+	Read  time.Duration `long:"read" description:"max duration of a redirect or query including waiting for locks, 0 disables the timeout" env:"READ" default:"6s"`
+	Write time.Duration `long:"write" description:"max duration of a create, delete or import, 0 disables the timeout" env:"WRITE" default:"10s"`
+	Job   time.Duration `long:"job" description:"max duration of one round of a background job, 0 disables the timeout" env:"JOB" default:"1h"`
+}
+
+type LockConfig struct {
+	Backend  string `long:"backend" description:"where locks are held, postgres keeps working when redis is flushed" env:"BACKEND" choice:"redis" choice:"postgres" default:"redis"`
+	Watchdog bool   `long:"watchdog" description:"keep extending held redis locks in background until released" env:"WATCHDOG"`
+}
+
+type MetricsConfig struct {
+	Port string `long:"port" description:"prometheus metrics port" env:"PORT" default:":2113"`
+}
+
 type Environment struct {
 	GinConfig                    GinConfig                    `group:"gin" namespace:"gin" env-namespace:"GIN"`
+	GRPCConfig                   GRPCConfig                   `group:"grpc" namespace:"grpc" env-namespace:"GRPC"`
+	AdminConfig                  AdminConfig                  `group:"admin" namespace:"admin" env-namespace:"ADMIN"`
 	PostgresConfig               PostgresConfig               `group:"postgres" namespace:"postgres" env-namespace:"POSTGRES"`
+	SQLiteConfig                 SQLiteConfig                 `group:"sqlite" namespace:"sqlite" env-namespace:"SQLITE"`
 	RedisConfig                  RedisConfig                  `group:"redis" namespace:"redis" env-namespace:"REDIS"`
+	FilterConfig                 FilterConfig                 `group:"filter" namespace:"filter" env-namespace:"FILTER"`
+	IDConfig                     IDConfig                     `group:"id" namespace:"id" env-namespace:"ID"`
+	RecycleConfig                RecycleConfig                `group:"recycle" namespace:"recycle" env-namespace:"RECYCLE"`
+	KeyLeaseConfig               KeyLeaseConfig               `group:"key-lease" namespace:"key-lease" env-namespace:"KEY_LEASE"`
+	L1CacheConfig                L1CacheConfig                `group:"l1-cache" namespace:"l1-cache" env-namespace:"L1_CACHE"`
+	NegativeCacheConfig          NegativeCacheConfig          `group:"negative-cache" namespace:"negative-cache" env-namespace:"NEGATIVE_CACHE"`
+	WarmUpConfig                 WarmUpConfig                 `group:"warm-up" namespace:"warm-up" env-namespace:"WARM_UP"`
+	LockConfig                   LockConfig                   `group:"lock" namespace:"lock" env-namespace:"LOCK"`
+	TimeoutConfig                TimeoutConfig                `group:"timeout" namespace:"timeout" env-namespace:"TIMEOUT"`
+	MetricsConfig                MetricsConfig                `group:"metrics" namespace:"metrics" env-namespace:"METRICS"`
 	SlideWindowRateLimiterConfig SlideWindowRateLimiterConfig `group:"slide-window-rate-limiter" namespace:"slide-window-rate-limiter" env-namespace:"SLIDE_WINDOW_RATE_LIMITER"`
+	Storage                      string                       `long:"storage" description:"where urls and keys are kept, sqlite runs without postgres, memory runs without postgres and redis and loses everything on restart" env:"STORAGE" choice:"postgres" choice:"sqlite" choice:"memory" default:"postgres"`
 	FQDN                         string                       `long:"fqdn" description:"fqdn" env:"FQDN" default:"localhost:8080"`
 }
 
@@ -76,41 +181,205 @@ func main() {
 		}
 	}
 
-	migration := migrationlib.NewMigrateLib(migrationlib.Config{
-		DatabaseDriver: migrationlib.PostgresDriver,
-		DatabaseURL:    env.PostgresConfig.URL,
-		SourceDriver:   migrationlib.FileDriver,
-		SourceURL:      env.PostgresConfig.MigrationFileDir,
-		TableName:      "migrate_version",
-	})
-	if err := migration.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("fail to run database migration fail:%v", err)
-	}
-
-	pgClient, err := pglib.NewDefaultGOPGClient(pglib.GOPGConfig{
-		URL:       env.PostgresConfig.URL,
-		DebugMode: false,
-		PoolSize:  10,
-	})
+	logger, err := loglib.NewProductionLogger()
 	if err != nil {
-		log.Fatalf("fail to init postgres client:%v", err)
+		log.Fatalf("fail to init logger:%v", err)
 	}
 
-	redisClient, err := redislib.NewGORedisClient(redislib.GORedisConfig{URL: env.RedisConfig.URL}, nil)
-	if err != nil {
-		log.Fatalf("fail to init redis client:%v", err)
+	if err := shortid.ValidateLength(env.IDConfig.Length); err != nil {
+		log.Fatalf("invalid id length:%v", err)
 	}
-
-	logger, err := loglib.NewProductionLogger()
+	randomStrGenerator, err := shortid.NewGenerator(env.IDConfig.Charset)
 	if err != nil {
-		log.Fatalf("fail to init logger:%v", err)
+		log.Fatalf("invalid id charset:%v", err)
 	}
 
-	randomStrGenerator := randstr.NewFastGenerator(randstr.CharSetEnglishAlphabet)
+	var (
+		keyDAO         dao.KeyDAO
+		urlDAO         dao.UrlDAO
+		cacheDAO       dao.CacheDAO
+		healthCheckDAO dao.HealthCheckDAO
+		locker         lock.Locker
+		rateLimiter    ratelimitlib.RateLimiter
+		// memory模式沒有cron 由server自己跑產生key、刪除過期url等job
+		jobManager      *job.Manager
+		keyLeaseManager *keylease.Manager
+		// 背景持續在redis上subscribe的工作
+		subscriptions []func(ctx context.Context) *business.Error
+	)
+	if env.Storage == storageMemory {
+		// 所有資料都在process裡 重啟就不見 只適合開發跟測試
+		if env.IDConfig.Strategy != dao.KeyStrategyPool {
+			log.Fatalf("memory storage only supports pool id strategy")
+		}
+		store := dao.NewMemoryStore()
+		keyDAO = dao.NewMemoryKeyDAO(store, randomStrGenerator, env.IDConfig.Length)
+		urlDAO = dao.NewMemoryUrlDAO(store, env.RecycleConfig.Cooldown)
+		cacheDAO = dao.NewMemoryCacheDAO(env.NegativeCacheConfig.TTL, dao.NewMemoryFilter())
+		healthCheckDAO = dao.NewMemoryHealthCheckDAO(store)
+		locker = lock.NewMemoryLocker(lock.NewMemoryLockTable())
+		rateLimiter = ratelimit.NewMemorySlideWindowRateLimiter(env.SlideWindowRateLimiterConfig.Capacity, env.SlideWindowRateLimiterConfig.Interval)
+
+		generateKeyJob := job.NewGenerateKeyJob(job.GenerateKeyJobConfig{
+			Name:          "GenerateKeyJob",
+			TimerFormat:   "* * * * *",
+			LowWatermark:  memoryKeyLowWatermark,
+			HighWatermark: memoryKeyHighWatermark,
+			MaxBatchSize:  memoryKeyHighWatermark,
+		}, keyDAO)
+		// 一開始keys是空的 先補一次key 不用等第一輪cron
+		if _, err := generateKeyJob.Work(context.Background()); err != nil {
+			log.Fatalf("fail to generate keys:%v", err)
+		}
+		jobs := []job.Job{
+			generateKeyJob,
+			job.NewExpiredURLJob(job.ExpiredURLJobConfig{Name: "ExpiredURLJob", TimerFormat: "*/10 * * * *", ExpireURLNumber: memoryExpireURLNumber}, urlDAO, cacheDAO),
+		}
+		if env.RecycleConfig.Cooldown > 0 {
+			jobs = append(jobs, job.NewRecycleKeyJob(job.RecycleKeyJobConfig{
+				Name:          "RecycleKeyJob",
+				TimerFormat:   "* * * * *",
+				ReleaseNumber: memoryKeyHighWatermark,
+			}, keyDAO))
+		}
+		jobManager = job.NewManager(jobs, logger, env.TimeoutConfig.Job)
+	} else {
+		if env.RedisConfig.URL == "" {
+			log.Fatalf("redis url is required by %s storage", env.Storage)
+		}
+		redisClient, err := redislib.NewGORedisClient(redislib.GORedisConfig{URL: env.RedisConfig.URL}, nil)
+		if err != nil {
+			log.Fatalf("fail to init redis client:%v", err)
+		}
 
-	urlDAO := dao.NewPGUrlDAO(logger, pgClient)
-	keyDAO := dao.NewPGKeyDAO(logger, pgClient, randomStrGenerator)
-	cacheDAO := dao.NewRedisCacheDAO(logger, redisClient)
+		var pgClient *pglib.GOPGClient
+		var sqliteDB *sql.DB
+		if env.Storage == storageSQLite {
+			// sqlite只有pool strategy 其他strategy要用到postgres的sequence以及transaction
+			if env.IDConfig.Strategy != dao.KeyStrategyPool {
+				log.Fatalf("sqlite storage only supports pool id strategy")
+			}
+			if env.LockConfig.Backend == "postgres" {
+				log.Fatalf("postgres lock backend is not available with sqlite storage")
+			}
+			sqliteDB, err = dao.NewSQLiteClient(env.SQLiteConfig.Path)
+			if err != nil {
+				log.Fatalf("fail to init sqlite client:%v", err)
+			}
+			if err := dao.MigrateSQLite(sqliteDB, env.SQLiteConfig.MigrationFileDir); err != nil {
+				log.Fatalf("fail to run sqlite migration fail:%v", err)
+			}
+			keyDAO = dao.NewSQLiteKeyDAO(logger, sqliteDB, randomStrGenerator, env.IDConfig.Length)
+		} else {
+			if env.PostgresConfig.URL == "" {
+				log.Fatalf("postgres url is required by postgres storage")
+			}
+			migration := migrationlib.NewMigrateLib(migrationlib.Config{
+				DatabaseDriver: migrationlib.PostgresDriver,
+				DatabaseURL:    env.PostgresConfig.URL,
+				SourceDriver:   migrationlib.FileDriver,
+				SourceURL:      env.PostgresConfig.MigrationFileDir,
+				TableName:      "migrate_version",
+			})
+			if err := migration.Up(); err != nil && err != migrate.ErrNoChange {
+				log.Fatalf("fail to run database migration fail:%v", err)
+			}
+
+			pgClient, err = pglib.NewDefaultGOPGClient(pglib.GOPGConfig{
+				URL:       env.PostgresConfig.URL,
+				DebugMode: false,
+				PoolSize:  10,
+			})
+			if err != nil {
+				log.Fatalf("fail to init postgres client:%v", err)
+			}
+			keyDAO = dao.NewPGKeyDAO(logger, pgClient, randomStrGenerator, env.IDConfig.Length)
+		}
+
+		// 每個instance先lease一批key放在記憶體 建立縮網址時不用每次鎖keys table
+		var keySource dao.KeySource
+		if env.IDConfig.Strategy == dao.KeyStrategyPool && env.KeyLeaseConfig.BlockSize > 0 {
+			keyLeaseManager = keylease.NewManager(keylease.Config{
+				BlockSize:       env.KeyLeaseConfig.BlockSize,
+				RefillThreshold: env.KeyLeaseConfig.RefillThreshold,
+				Timeout:         env.TimeoutConfig.Write,
+			}, logger, keyDAO)
+			keyLeaseManager.Start()
+			keySource = keyLeaseManager
+		}
+
+		if sqliteDB != nil {
+			urlDAO = dao.NewSQLiteUrlDAO(logger, sqliteDB, env.RecycleConfig.Cooldown, keySource)
+			healthCheckDAO = dao.NewSQLiteHealthCheckDAO(logger, sqliteDB)
+		} else {
+			keyStrategy, err := dao.NewKeyStrategy(dao.KeyStrategyConfig{
+				Strategy: env.IDConfig.Strategy,
+				Charset:  env.IDConfig.Charset,
+				Length:   env.IDConfig.Length,
+				Secret:   env.IDConfig.Secret,
+			})
+			if err != nil {
+				log.Fatalf("invalid id strategy:%v", err)
+			}
+			if keySource != nil {
+				keyStrategy = dao.NewLeaseKeyStrategy(keySource, keyStrategy)
+			}
+			urlDAO = dao.NewPGUrlDAO(logger, pgClient, env.RecycleConfig.Cooldown, keyStrategy)
+			healthCheckDAO = dao.NewPGHealthCheckDAO(logger, pgClient)
+		}
+
+		filter, err := dao.NewOriginalURLIDFilter(logger, redisClient, dao.FilterConfig{
+			Backend:        env.FilterConfig.Backend,
+			Capacity:       env.FilterConfig.Capacity,
+			ErrorRate:      env.FilterConfig.ErrorRate,
+			ReloadInterval: env.FilterConfig.ReloadInterval,
+			BucketSize:     env.FilterConfig.BucketSize,
+			Expansion:      env.FilterConfig.Expansion,
+		})
+		if err != nil {
+			log.Fatalf("fail to init filter:%v", err)
+		}
+		// 沒有RedisBloom時 每個instance從urls載入自己的filter 之後透過redis pub/sub同步
+		if localFilter, ok := filter.(*dao.LocalFilter); ok {
+			subscriptions = append(subscriptions, func(ctx context.Context) *business.Error {
+				return localFilter.Sync(ctx, urlDAO)
+			})
+		}
+		redisCacheDAO := dao.NewRedisCacheDAO(logger, redisClient, env.NegativeCacheConfig.TTL, filter)
+		cacheDAO = redisCacheDAO
+		// redis前面再加一層process內的cache 其他instance刪除url時透過redis pub/sub通知清掉
+		if env.L1CacheConfig.Size > 0 {
+			tieredCacheDAO := dao.NewTieredCacheDAO(localcache.New(env.L1CacheConfig.Size, env.L1CacheConfig.TTL), redisCacheDAO)
+			cacheDAO = tieredCacheDAO
+			subscriptions = append(subscriptions, func(ctx context.Context) *business.Error {
+				return redisCacheDAO.SubscribeInvalidation(ctx, tieredCacheDAO.Invalidate)
+			})
+		}
+		locker = lock.NewRedisLocker(logger, redisClient, env.LockConfig.Watchdog)
+		if env.LockConfig.Backend == "postgres" {
+			locker = lock.NewPGLocker(logger, pgClient)
+		}
+
+		rateLimiter = ratelimitlib.NewSlideWindowRateLimiter(redisClient, env.SlideWindowRateLimiterConfig.Capacity, env.SlideWindowRateLimiterConfig.Interval)
+	}
+
+	// redis被清空或是failover之後 先把最近建立的url放進cache 不用等每個id都搶lock去database拿
+	if env.WarmUpConfig.Number > 0 && env.Storage != storageMemory {
+		warmCacheJob := job.NewWarmCacheJob(job.WarmCacheJobConfig{
+			Name:      "WarmCacheJob",
+			Number:    env.WarmUpConfig.Number,
+			BatchSize: env.WarmUpConfig.BatchSize,
+			Interval:  env.WarmUpConfig.Interval,
+		}, urlDAO, cacheDAO)
+		go func() {
+			result, err := warmCacheJob.Work(context.Background())
+			if err != nil {
+				logger.Error("fail to warm up cache", zap.Error(err))
+				return
+			}
+			logger.Info("cache warmed up", zap.Any("result", result))
+		}()
+	}
 
 	bindingValidator, _ := binding.Validator.Engine().(*validator.Validate)
 	CustomValidator, err := validation.NewValidationTranslator(bindingValidator, "en")
@@ -118,29 +387,96 @@ func main() {
 		log.Fatalf("fail to init validation translator:%v", err)
 	}
 
-	locker := lock.NewRedisLocker(logger, redisClient)
-
-	rateLimiter := ratelimitlib.NewSlideWindowRateLimiter(redisClient, env.SlideWindowRateLimiterConfig.Capacity, env.SlideWindowRateLimiterConfig.Interval)
-
 	mwe := middleware.NewMiddleware(logger, CustomValidator, rateLimiter)
 
-	urlRepository := repository.NewURLRepository(logger, urlDAO, keyDAO, cacheDAO, locker)
+	urlRepository := repository.NewURLRepository(logger, urlDAO, keyDAO, cacheDAO, healthCheckDAO, locker, env.IDConfig.Strategy, repository.Timeouts{
+		Read:  env.TimeoutConfig.Read,
+		Write: env.TimeoutConfig.Write,
+	})
 
 	svc := service.NewService(&service.Config{FQDN: env.FQDN}, logger, urlRepository)
 
 	gin.SetMode(env.GinConfig.Mode)
 
-	graceful.Wrapper(logger, StartFunc(logger, server.NewHTTPServer(gin.Default(), env.GinConfig.Port, mwe, svc)))
+	grpcServer := rpc.NewGRPCServer(&rpc.Config{FQDN: env.FQDN, MaxBatchSize: env.GRPCConfig.MaxBatchSize}, logger, urlRepository)
+
+	metricsServer := &http.Server{Addr: env.MetricsConfig.Port, Handler: metrics.Handler()}
+
+	// bulk export/import會讀寫全部的url 預設不開 要開的話只綁在內部網路
+	var adminServer *http.Server
+	if env.AdminConfig.Port != "" {
+		adminServer = server.NewAdminHTTPServer(gin.Default(), env.AdminConfig.Port, mwe, svc)
+	}
+
+	if err := graceful.Wrapper(logger, StartFunc(logger, server.NewHTTPServer(gin.Default(), env.GinConfig.Port, mwe, svc), adminServer, grpcServer, env.GRPCConfig.Port, metricsServer, keyLeaseManager, jobManager, subscriptions)); err != nil {
+		log.Fatalf("fail to start server:%v", err)
+	}
 }
 
-func StartFunc(logger *loglib.Logger, server *http.Server) func(ctx context.Context) error {
+func StartFunc(logger *loglib.Logger, server *http.Server, adminServer *http.Server, grpcServer *grpc.Server, grpcPort string, metricsServer *http.Server, keyLeaseManager *keylease.Manager, jobManager *job.Manager, subscriptions []func(ctx context.Context) *business.Error) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
+		// 先把port都listen起來 任何一個失敗就直接讓啟動失敗 不要只剩一半的server在跑
+		httpListener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			return fmt.Errorf("http server listen error: %w", err)
+		}
+		grpcListener, err := net.Listen("tcp", grpcPort)
+		if err != nil {
+			httpListener.Close()
+			return fmt.Errorf("grpc server listen error: %w", err)
+		}
+		var adminListener net.Listener
+		if adminServer != nil {
+			if adminListener, err = net.Listen("tcp", adminServer.Addr); err != nil {
+				httpListener.Close()
+				grpcListener.Close()
+				return fmt.Errorf("admin server listen error: %w", err)
+			}
+		}
+
+		go func() {
+			logger.Info("start metrics server...")
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("metrics server fail", zap.Error(err))
+			}
+		}()
+
+		if jobManager != nil {
+			jobManager.Start()
+		}
+
+		for _, subscribe := range subscriptions {
+			go func(subscribe func(ctx context.Context) *business.Error) {
+				// 第一次subscribe失敗就一直重試 之後斷線go-redis會自己重連
+				for ctx.Err() == nil {
+					if err := subscribe(ctx); err != nil {
+						logger.Error("fail to subscribe redis channel", zap.Error(err))
+						time.Sleep(time.Second)
+					}
+				}
+			}(subscribe)
+		}
+
+		go func() {
+			if err := server.Serve(httpListener); err != nil && err != http.ErrServerClosed {
+				logger.Error("http server serve error", zap.Error(err))
+			}
+		}()
+
 		go func() {
-			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				logger.Error("http server listen error", zap.Error(err))
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				logger.Error("grpc server serve error", zap.Error(err))
 			}
 		}()
 
+		if adminServer != nil {
+			go func() {
+				if err := adminServer.Serve(adminListener); err != nil && err != http.ErrServerClosed {
+					logger.Error("admin server serve error", zap.Error(err))
+				}
+			}()
+		}
+
 		<-ctx.Done()
 
 		ctx1, cancel1 := context.WithCancel(context.Background())
@@ -148,11 +484,30 @@ func StartFunc(logger *loglib.Logger, server *http.Server) func(ctx context.Cont
 		go func() {
 			logger.Info("shutdown http server...")
 			server.Shutdown(ctx1)
+			if adminServer != nil {
+				logger.Info("shutdown admin server...")
+				adminServer.Shutdown(ctx1)
+			}
+			logger.Info("shutdown grpc server...")
+			grpcServer.GracefulStop()
+			if err := metricsServer.Shutdown(context.Background()); err != nil {
+				logger.Error("fail to shutdown metrics server", zap.Error(err))
+			}
+			if jobManager != nil {
+				jobManager.Stop()
+			}
+			// server都停了才不會再有人拿key
+			if keyLeaseManager != nil {
+				logger.Info("return leased keys...")
+				if err := keyLeaseManager.Close(); err != nil {
+					logger.Error("fail to return leased keys", zap.Error(err))
+				}
+			}
 			cancel1()
 		}()
 
 		<-ctx1.Done()
-		logger.Info("http server existing")
+		logger.Info("http and grpc server existing")
 		return nil
 	}
 }
@@ -1,3 +1,3 @@
 package daomock
 
-//go:generate mockgen -destination=mock.go -package=$GOPACKAGE github.com/KennyChenFight/Shortening-URL/pkg/dao CacheDAO,KeyDAO,UrlDAO
+//go:generate mockgen -destination=mock.go -package=$GOPACKAGE github.com/KennyChenFight/Shortening-URL/pkg/dao CacheDAO,KeyDAO,UrlDAO,HealthCheckDAO,OriginalURLIDFilterRebuilder,OriginalURLIDFilterInspector
@@ -1,10 +1,11 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/KennyChenFight/Shortening-URL/pkg/dao (interfaces: CacheDAO,KeyDAO,UrlDAO)
+// Source: github.com/KennyChenFight/Shortening-URL/pkg/dao (interfaces: CacheDAO,KeyDAO,UrlDAO,HealthCheckDAO,OriginalURLIDFilterRebuilder,OriginalURLIDFilterInspector)
 
 // Package daomock is a generated GoMock package.
 package daomock
 
 import (
+	context "context"
 	reflect "reflect"
 
 	business "github.com/KennyChenFight/Shortening-URL/pkg/business"
@@ -35,120 +36,177 @@ func (m *MockCacheDAO) EXPECT() *MockCacheDAOMockRecorder {
 	return m.recorder
 }
 
+// AddMultiOriginalURLIDInFilters mocks base method.
+func (m *MockCacheDAO) AddMultiOriginalURLIDInFilters(arg0 context.Context, arg1 []string) (int, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMultiOriginalURLIDInFilters", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// AddMultiOriginalURLIDInFilters indicates an expected call of AddMultiOriginalURLIDInFilters.
+func (mr *MockCacheDAOMockRecorder) AddMultiOriginalURLIDInFilters(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMultiOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).AddMultiOriginalURLIDInFilters), arg0, arg1)
+}
+
 // AddOriginalURLIDInFilters mocks base method.
-func (m *MockCacheDAO) AddOriginalURLIDInFilters(arg0 string) *business.Error {
+func (m *MockCacheDAO) AddOriginalURLIDInFilters(arg0 context.Context, arg1 string) *business.Error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddOriginalURLIDInFilters", arg0)
+	ret := m.ctrl.Call(m, "AddOriginalURLIDInFilters", arg0, arg1)
 	ret0, _ := ret[0].(*business.Error)
 	return ret0
 }
 
 // AddOriginalURLIDInFilters indicates an expected call of AddOriginalURLIDInFilters.
-func (mr *MockCacheDAOMockRecorder) AddOriginalURLIDInFilters(arg0 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) AddOriginalURLIDInFilters(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).AddOriginalURLIDInFilters), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).AddOriginalURLIDInFilters), arg0, arg1)
 }
 
 // DeleteMultiOriginalURL mocks base method.
-func (m *MockCacheDAO) DeleteMultiOriginalURL(arg0 []string) *business.Error {
+func (m *MockCacheDAO) DeleteMultiOriginalURL(arg0 context.Context, arg1 []string) *business.Error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMultiOriginalURL", arg0)
+	ret := m.ctrl.Call(m, "DeleteMultiOriginalURL", arg0, arg1)
 	ret0, _ := ret[0].(*business.Error)
 	return ret0
 }
 
 // DeleteMultiOriginalURL indicates an expected call of DeleteMultiOriginalURL.
-func (mr *MockCacheDAOMockRecorder) DeleteMultiOriginalURL(arg0 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) DeleteMultiOriginalURL(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMultiOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).DeleteMultiOriginalURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMultiOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).DeleteMultiOriginalURL), arg0, arg1)
 }
 
 // DeleteMultiOriginalURLIDInFilters mocks base method.
-func (m *MockCacheDAO) DeleteMultiOriginalURLIDInFilters(arg0 []string) (bool, *business.Error) {
+func (m *MockCacheDAO) DeleteMultiOriginalURLIDInFilters(arg0 context.Context, arg1 []string) (bool, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMultiOriginalURLIDInFilters", arg0)
+	ret := m.ctrl.Call(m, "DeleteMultiOriginalURLIDInFilters", arg0, arg1)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // DeleteMultiOriginalURLIDInFilters indicates an expected call of DeleteMultiOriginalURLIDInFilters.
-func (mr *MockCacheDAOMockRecorder) DeleteMultiOriginalURLIDInFilters(arg0 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) DeleteMultiOriginalURLIDInFilters(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMultiOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).DeleteMultiOriginalURLIDInFilters), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMultiOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).DeleteMultiOriginalURLIDInFilters), arg0, arg1)
 }
 
 // DeleteOriginalURL mocks base method.
-func (m *MockCacheDAO) DeleteOriginalURL(arg0 string) *business.Error {
+func (m *MockCacheDAO) DeleteOriginalURL(arg0 context.Context, arg1 string) *business.Error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteOriginalURL", arg0)
+	ret := m.ctrl.Call(m, "DeleteOriginalURL", arg0, arg1)
 	ret0, _ := ret[0].(*business.Error)
 	return ret0
 }
 
 // DeleteOriginalURL indicates an expected call of DeleteOriginalURL.
-func (mr *MockCacheDAOMockRecorder) DeleteOriginalURL(arg0 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) DeleteOriginalURL(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).DeleteOriginalURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).DeleteOriginalURL), arg0, arg1)
 }
 
 // DeleteOriginalURLIDInFilters mocks base method.
-func (m *MockCacheDAO) DeleteOriginalURLIDInFilters(arg0 string) (bool, *business.Error) {
+func (m *MockCacheDAO) DeleteOriginalURLIDInFilters(arg0 context.Context, arg1 string) (bool, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteOriginalURLIDInFilters", arg0)
+	ret := m.ctrl.Call(m, "DeleteOriginalURLIDInFilters", arg0, arg1)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // DeleteOriginalURLIDInFilters indicates an expected call of DeleteOriginalURLIDInFilters.
-func (mr *MockCacheDAOMockRecorder) DeleteOriginalURLIDInFilters(arg0 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) DeleteOriginalURLIDInFilters(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).DeleteOriginalURLIDInFilters), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).DeleteOriginalURLIDInFilters), arg0, arg1)
+}
+
+// DeleteOriginalURLNotFound mocks base method.
+func (m *MockCacheDAO) DeleteOriginalURLNotFound(arg0 context.Context, arg1 []string) *business.Error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOriginalURLNotFound", arg0, arg1)
+	ret0, _ := ret[0].(*business.Error)
+	return ret0
+}
+
+// DeleteOriginalURLNotFound indicates an expected call of DeleteOriginalURLNotFound.
+func (mr *MockCacheDAOMockRecorder) DeleteOriginalURLNotFound(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOriginalURLNotFound", reflect.TypeOf((*MockCacheDAO)(nil).DeleteOriginalURLNotFound), arg0, arg1)
 }
 
 // ExistOriginalURLIDInFilters mocks base method.
-func (m *MockCacheDAO) ExistOriginalURLIDInFilters(arg0 string) (bool, *business.Error) {
+func (m *MockCacheDAO) ExistOriginalURLIDInFilters(arg0 context.Context, arg1 string) (bool, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExistOriginalURLIDInFilters", arg0)
+	ret := m.ctrl.Call(m, "ExistOriginalURLIDInFilters", arg0, arg1)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // ExistOriginalURLIDInFilters indicates an expected call of ExistOriginalURLIDInFilters.
-func (mr *MockCacheDAOMockRecorder) ExistOriginalURLIDInFilters(arg0 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) ExistOriginalURLIDInFilters(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).ExistOriginalURLIDInFilters), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistOriginalURLIDInFilters", reflect.TypeOf((*MockCacheDAO)(nil).ExistOriginalURLIDInFilters), arg0, arg1)
 }
 
 // GetOriginalURL mocks base method.
-func (m *MockCacheDAO) GetOriginalURL(arg0 string) (string, *business.Error) {
+func (m *MockCacheDAO) GetOriginalURL(arg0 context.Context, arg1 string) (string, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOriginalURL", arg0)
+	ret := m.ctrl.Call(m, "GetOriginalURL", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // GetOriginalURL indicates an expected call of GetOriginalURL.
-func (mr *MockCacheDAOMockRecorder) GetOriginalURL(arg0 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) GetOriginalURL(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).GetOriginalURL), arg0, arg1)
+}
+
+// SetMultiOriginalURL mocks base method.
+func (m *MockCacheDAO) SetMultiOriginalURL(arg0 context.Context, arg1 map[string]string) *business.Error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMultiOriginalURL", arg0, arg1)
+	ret0, _ := ret[0].(*business.Error)
+	return ret0
+}
+
+// SetMultiOriginalURL indicates an expected call of SetMultiOriginalURL.
+func (mr *MockCacheDAOMockRecorder) SetMultiOriginalURL(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).GetOriginalURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMultiOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).SetMultiOriginalURL), arg0, arg1)
 }
 
 // SetOriginalURL mocks base method.
-func (m *MockCacheDAO) SetOriginalURL(arg0, arg1 string) *business.Error {
+func (m *MockCacheDAO) SetOriginalURL(arg0 context.Context, arg1, arg2 string) *business.Error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetOriginalURL", arg0, arg1)
+	ret := m.ctrl.Call(m, "SetOriginalURL", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*business.Error)
 	return ret0
 }
 
 // SetOriginalURL indicates an expected call of SetOriginalURL.
-func (mr *MockCacheDAOMockRecorder) SetOriginalURL(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockCacheDAOMockRecorder) SetOriginalURL(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).SetOriginalURL), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOriginalURL", reflect.TypeOf((*MockCacheDAO)(nil).SetOriginalURL), arg0, arg1, arg2)
+}
+
+// SetOriginalURLNotFound mocks base method.
+func (m *MockCacheDAO) SetOriginalURLNotFound(arg0 context.Context, arg1 string) *business.Error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetOriginalURLNotFound", arg0, arg1)
+	ret0, _ := ret[0].(*business.Error)
+	return ret0
+}
+
+// SetOriginalURLNotFound indicates an expected call of SetOriginalURLNotFound.
+func (mr *MockCacheDAOMockRecorder) SetOriginalURLNotFound(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOriginalURLNotFound", reflect.TypeOf((*MockCacheDAO)(nil).SetOriginalURLNotFound), arg0, arg1)
 }
 
 // MockKeyDAO is a mock of KeyDAO interface.
@@ -175,18 +233,93 @@ func (m *MockKeyDAO) EXPECT() *MockKeyDAOMockRecorder {
 }
 
 // BatchCreate mocks base method.
-func (m *MockKeyDAO) BatchCreate(arg0 int) (int, *business.Error) {
+func (m *MockKeyDAO) BatchCreate(arg0 context.Context, arg1 int) (int, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BatchCreate", arg0)
+	ret := m.ctrl.Call(m, "BatchCreate", arg0, arg1)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // BatchCreate indicates an expected call of BatchCreate.
-func (mr *MockKeyDAOMockRecorder) BatchCreate(arg0 interface{}) *gomock.Call {
+func (mr *MockKeyDAOMockRecorder) BatchCreate(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCreate", reflect.TypeOf((*MockKeyDAO)(nil).BatchCreate), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCreate", reflect.TypeOf((*MockKeyDAO)(nil).BatchCreate), arg0, arg1)
+}
+
+// Count mocks base method.
+func (m *MockKeyDAO) Count(arg0 context.Context) (int, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockKeyDAOMockRecorder) Count(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockKeyDAO)(nil).Count), arg0)
+}
+
+// CountQuarantined mocks base method.
+func (m *MockKeyDAO) CountQuarantined(arg0 context.Context) (int, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountQuarantined", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// CountQuarantined indicates an expected call of CountQuarantined.
+func (mr *MockKeyDAOMockRecorder) CountQuarantined(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountQuarantined", reflect.TypeOf((*MockKeyDAO)(nil).CountQuarantined), arg0)
+}
+
+// Lease mocks base method.
+func (m *MockKeyDAO) Lease(arg0 context.Context, arg1 int) ([]string, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lease", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Lease indicates an expected call of Lease.
+func (mr *MockKeyDAOMockRecorder) Lease(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lease", reflect.TypeOf((*MockKeyDAO)(nil).Lease), arg0, arg1)
+}
+
+// ReleaseQuarantined mocks base method.
+func (m *MockKeyDAO) ReleaseQuarantined(arg0 context.Context, arg1 int) (int, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseQuarantined", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// ReleaseQuarantined indicates an expected call of ReleaseQuarantined.
+func (mr *MockKeyDAOMockRecorder) ReleaseQuarantined(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseQuarantined", reflect.TypeOf((*MockKeyDAO)(nil).ReleaseQuarantined), arg0, arg1)
+}
+
+// Return mocks base method.
+func (m *MockKeyDAO) Return(arg0 context.Context, arg1 []string) (int, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Return", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Return indicates an expected call of Return.
+func (mr *MockKeyDAOMockRecorder) Return(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Return", reflect.TypeOf((*MockKeyDAO)(nil).Return), arg0, arg1)
 }
 
 // MockUrlDAO is a mock of UrlDAO interface.
@@ -213,60 +346,278 @@ func (m *MockUrlDAO) EXPECT() *MockUrlDAOMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockUrlDAO) Create(arg0 string) (*dao.URL, *business.Error) {
+func (m *MockUrlDAO) Create(arg0 context.Context, arg1 string) (*dao.URL, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
 	ret0, _ := ret[0].(*dao.URL)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockUrlDAOMockRecorder) Create(arg0 interface{}) *gomock.Call {
+func (mr *MockUrlDAOMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUrlDAO)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUrlDAO)(nil).Create), arg0, arg1)
 }
 
 // Delete mocks base method.
-func (m *MockUrlDAO) Delete(arg0 string) *business.Error {
+func (m *MockUrlDAO) Delete(arg0 context.Context, arg1 string) *business.Error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(*business.Error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockUrlDAOMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+func (mr *MockUrlDAOMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUrlDAO)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUrlDAO)(nil).Delete), arg0, arg1)
 }
 
 // Expire mocks base method.
-func (m *MockUrlDAO) Expire(arg0 int) ([]string, *business.Error) {
+func (m *MockUrlDAO) Expire(arg0 context.Context, arg1 int) ([]string, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Expire", arg0)
+	ret := m.ctrl.Call(m, "Expire", arg0, arg1)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // Expire indicates an expected call of Expire.
-func (mr *MockUrlDAOMockRecorder) Expire(arg0 interface{}) *gomock.Call {
+func (mr *MockUrlDAOMockRecorder) Expire(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockUrlDAO)(nil).Expire), arg0, arg1)
+}
+
+// Find mocks base method.
+func (m *MockUrlDAO) Find(arg0 context.Context, arg1 string) (*dao.URL, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].(*dao.URL)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockUrlDAOMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockUrlDAO)(nil).Expire), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockUrlDAO)(nil).Find), arg0, arg1)
+}
+
+// ForceExpire mocks base method.
+func (m *MockUrlDAO) ForceExpire(arg0 context.Context, arg1 string) *business.Error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceExpire", arg0, arg1)
+	ret0, _ := ret[0].(*business.Error)
+	return ret0
+}
+
+// ForceExpire indicates an expected call of ForceExpire.
+func (mr *MockUrlDAOMockRecorder) ForceExpire(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceExpire", reflect.TypeOf((*MockUrlDAO)(nil).ForceExpire), arg0, arg1)
 }
 
 // Get mocks base method.
-func (m *MockUrlDAO) Get(arg0 string) (*dao.URL, *business.Error) {
+func (m *MockUrlDAO) Get(arg0 context.Context, arg1 string) (*dao.URL, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", arg0)
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
 	ret0, _ := ret[0].(*dao.URL)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockUrlDAOMockRecorder) Get(arg0 interface{}) *gomock.Call {
+func (mr *MockUrlDAOMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUrlDAO)(nil).Get), arg0, arg1)
+}
+
+// Import mocks base method.
+func (m *MockUrlDAO) Import(arg0 context.Context, arg1 []dao.URL) ([]dao.ImportResult, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", arg0, arg1)
+	ret0, _ := ret[0].([]dao.ImportResult)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockUrlDAOMockRecorder) Import(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockUrlDAO)(nil).Import), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockUrlDAO) List(arg0 context.Context, arg1 string, arg2 int, arg3 bool) ([]dao.URL, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]dao.URL)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockUrlDAOMockRecorder) List(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUrlDAO)(nil).List), arg0, arg1, arg2, arg3)
+}
+
+// ListRecent mocks base method.
+func (m *MockUrlDAO) ListRecent(arg0 context.Context, arg1 *dao.URL, arg2 int) ([]dao.URL, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRecent", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]dao.URL)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// ListRecent indicates an expected call of ListRecent.
+func (mr *MockUrlDAOMockRecorder) ListRecent(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRecent", reflect.TypeOf((*MockUrlDAO)(nil).ListRecent), arg0, arg1, arg2)
+}
+
+// MockHealthCheckDAO is a mock of HealthCheckDAO interface.
+type MockHealthCheckDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockHealthCheckDAOMockRecorder
+}
+
+// MockHealthCheckDAOMockRecorder is the mock recorder for MockHealthCheckDAO.
+type MockHealthCheckDAOMockRecorder struct {
+	mock *MockHealthCheckDAO
+}
+
+// NewMockHealthCheckDAO creates a new mock instance.
+func NewMockHealthCheckDAO(ctrl *gomock.Controller) *MockHealthCheckDAO {
+	mock := &MockHealthCheckDAO{ctrl: ctrl}
+	mock.recorder = &MockHealthCheckDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHealthCheckDAO) EXPECT() *MockHealthCheckDAOMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockHealthCheckDAO) Get(arg0 context.Context, arg1 string) (*dao.HealthCheck, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*dao.HealthCheck)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockHealthCheckDAOMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockHealthCheckDAO)(nil).Get), arg0, arg1)
+}
+
+// ListBroken mocks base method.
+func (m *MockHealthCheckDAO) ListBroken(arg0 context.Context, arg1 int) ([]dao.HealthCheck, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBroken", arg0, arg1)
+	ret0, _ := ret[0].([]dao.HealthCheck)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// ListBroken indicates an expected call of ListBroken.
+func (mr *MockHealthCheckDAOMockRecorder) ListBroken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBroken", reflect.TypeOf((*MockHealthCheckDAO)(nil).ListBroken), arg0, arg1)
+}
+
+// Record mocks base method.
+func (m *MockHealthCheckDAO) Record(arg0 context.Context, arg1 *dao.HealthCheck, arg2 int) (*dao.HealthCheck, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*dao.HealthCheck)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockHealthCheckDAOMockRecorder) Record(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockHealthCheckDAO)(nil).Record), arg0, arg1, arg2)
+}
+
+// MockOriginalURLIDFilterRebuilder is a mock of OriginalURLIDFilterRebuilder interface.
+type MockOriginalURLIDFilterRebuilder struct {
+	ctrl     *gomock.Controller
+	recorder *MockOriginalURLIDFilterRebuilderMockRecorder
+}
+
+// MockOriginalURLIDFilterRebuilderMockRecorder is the mock recorder for MockOriginalURLIDFilterRebuilder.
+type MockOriginalURLIDFilterRebuilderMockRecorder struct {
+	mock *MockOriginalURLIDFilterRebuilder
+}
+
+// NewMockOriginalURLIDFilterRebuilder creates a new mock instance.
+func NewMockOriginalURLIDFilterRebuilder(ctrl *gomock.Controller) *MockOriginalURLIDFilterRebuilder {
+	mock := &MockOriginalURLIDFilterRebuilder{ctrl: ctrl}
+	mock.recorder = &MockOriginalURLIDFilterRebuilderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOriginalURLIDFilterRebuilder) EXPECT() *MockOriginalURLIDFilterRebuilderMockRecorder {
+	return m.recorder
+}
+
+// Rebuild mocks base method.
+func (m *MockOriginalURLIDFilterRebuilder) Rebuild(arg0 context.Context, arg1 func() ([]string, *business.Error)) (dao.FilterRebuildResult, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebuild", arg0, arg1)
+	ret0, _ := ret[0].(dao.FilterRebuildResult)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Rebuild indicates an expected call of Rebuild.
+func (mr *MockOriginalURLIDFilterRebuilderMockRecorder) Rebuild(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebuild", reflect.TypeOf((*MockOriginalURLIDFilterRebuilder)(nil).Rebuild), arg0, arg1)
+}
+
+// MockOriginalURLIDFilterInspector is a mock of OriginalURLIDFilterInspector interface.
+type MockOriginalURLIDFilterInspector struct {
+	ctrl     *gomock.Controller
+	recorder *MockOriginalURLIDFilterInspectorMockRecorder
+}
+
+// MockOriginalURLIDFilterInspectorMockRecorder is the mock recorder for MockOriginalURLIDFilterInspector.
+type MockOriginalURLIDFilterInspectorMockRecorder struct {
+	mock *MockOriginalURLIDFilterInspector
+}
+
+// NewMockOriginalURLIDFilterInspector creates a new mock instance.
+func NewMockOriginalURLIDFilterInspector(ctrl *gomock.Controller) *MockOriginalURLIDFilterInspector {
+	mock := &MockOriginalURLIDFilterInspector{ctrl: ctrl}
+	mock.recorder = &MockOriginalURLIDFilterInspectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOriginalURLIDFilterInspector) EXPECT() *MockOriginalURLIDFilterInspectorMockRecorder {
+	return m.recorder
+}
+
+// Info mocks base method.
+func (m *MockOriginalURLIDFilterInspector) Info(arg0 context.Context) (dao.FilterInfo, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Info", arg0)
+	ret0, _ := ret[0].(dao.FilterInfo)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// Info indicates an expected call of Info.
+func (mr *MockOriginalURLIDFilterInspectorMockRecorder) Info(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUrlDAO)(nil).Get), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Info", reflect.TypeOf((*MockOriginalURLIDFilterInspector)(nil).Info), arg0)
 }
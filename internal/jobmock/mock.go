@@ -5,6 +5,7 @@
 package jobmock
 
 import (
+	context "context"
 	reflect "reflect"
 
 	business "github.com/KennyChenFight/Shortening-URL/pkg/business"
@@ -63,16 +64,16 @@ func (mr *MockJobMockRecorder) TimerFormat() *gomock.Call {
 }
 
 // Work mocks base method.
-func (m *MockJob) Work() (map[string]interface{}, *business.Error) {
+func (m *MockJob) Work(arg0 context.Context) (map[string]interface{}, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Work")
+	ret := m.ctrl.Call(m, "Work", arg0)
 	ret0, _ := ret[0].(map[string]interface{})
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // Work indicates an expected call of Work.
-func (mr *MockJobMockRecorder) Work() *gomock.Call {
+func (mr *MockJobMockRecorder) Work(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Work", reflect.TypeOf((*MockJob)(nil).Work))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Work", reflect.TypeOf((*MockJob)(nil).Work), arg0)
 }
@@ -5,6 +5,7 @@
 package lockmock
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -36,30 +37,31 @@ func (m *MockLocker) EXPECT() *MockLockerMockRecorder {
 }
 
 // AcquireLock mocks base method.
-func (m *MockLocker) AcquireLock(arg0 string, arg1, arg2 time.Duration) (bool, *business.Error) {
+func (m *MockLocker) AcquireLock(arg0 context.Context, arg1 string, arg2, arg3 time.Duration) (string, bool, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcquireLock", arg0, arg1, arg2)
-	ret0, _ := ret[0].(bool)
-	ret1, _ := ret[1].(*business.Error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "AcquireLock", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(*business.Error)
+	return ret0, ret1, ret2
 }
 
 // AcquireLock indicates an expected call of AcquireLock.
-func (mr *MockLockerMockRecorder) AcquireLock(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockLockerMockRecorder) AcquireLock(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireLock", reflect.TypeOf((*MockLocker)(nil).AcquireLock), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireLock", reflect.TypeOf((*MockLocker)(nil).AcquireLock), arg0, arg1, arg2, arg3)
 }
 
 // ReleaseLock mocks base method.
-func (m *MockLocker) ReleaseLock(arg0 string) *business.Error {
+func (m *MockLocker) ReleaseLock(arg0 context.Context, arg1, arg2 string) *business.Error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReleaseLock", arg0)
+	ret := m.ctrl.Call(m, "ReleaseLock", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*business.Error)
 	return ret0
 }
 
 // ReleaseLock indicates an expected call of ReleaseLock.
-func (mr *MockLockerMockRecorder) ReleaseLock(arg0 interface{}) *gomock.Call {
+func (mr *MockLockerMockRecorder) ReleaseLock(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLock", reflect.TypeOf((*MockLocker)(nil).ReleaseLock), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLock", reflect.TypeOf((*MockLocker)(nil).ReleaseLock), arg0, arg1, arg2)
 }
@@ -5,6 +5,7 @@
 package repositorymock
 
 import (
+	context "context"
 	reflect "reflect"
 
 	business "github.com/KennyChenFight/Shortening-URL/pkg/business"
@@ -36,60 +37,120 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 }
 
 // BatchCreateKeys mocks base method.
-func (m *MockRepository) BatchCreateKeys(arg0 int) (int, *business.Error) {
+func (m *MockRepository) BatchCreateKeys(arg0 context.Context, arg1 int) (int, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BatchCreateKeys", arg0)
+	ret := m.ctrl.Call(m, "BatchCreateKeys", arg0, arg1)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // BatchCreateKeys indicates an expected call of BatchCreateKeys.
-func (mr *MockRepositoryMockRecorder) BatchCreateKeys(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) BatchCreateKeys(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCreateKeys", reflect.TypeOf((*MockRepository)(nil).BatchCreateKeys), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCreateKeys", reflect.TypeOf((*MockRepository)(nil).BatchCreateKeys), arg0, arg1)
 }
 
 // CreateShorteningURL mocks base method.
-func (m *MockRepository) CreateShorteningURL(arg0 string) (*dao.URL, *business.Error) {
+func (m *MockRepository) CreateShorteningURL(arg0 context.Context, arg1 string) (*dao.URL, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateShorteningURL", arg0)
+	ret := m.ctrl.Call(m, "CreateShorteningURL", arg0, arg1)
 	ret0, _ := ret[0].(*dao.URL)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // CreateShorteningURL indicates an expected call of CreateShorteningURL.
-func (mr *MockRepositoryMockRecorder) CreateShorteningURL(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) CreateShorteningURL(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShorteningURL", reflect.TypeOf((*MockRepository)(nil).CreateShorteningURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShorteningURL", reflect.TypeOf((*MockRepository)(nil).CreateShorteningURL), arg0, arg1)
 }
 
 // DeleteShorteningURL mocks base method.
-func (m *MockRepository) DeleteShorteningURL(arg0 string) *business.Error {
+func (m *MockRepository) DeleteShorteningURL(arg0 context.Context, arg1 string) *business.Error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteShorteningURL", arg0)
+	ret := m.ctrl.Call(m, "DeleteShorteningURL", arg0, arg1)
 	ret0, _ := ret[0].(*business.Error)
 	return ret0
 }
 
 // DeleteShorteningURL indicates an expected call of DeleteShorteningURL.
-func (mr *MockRepositoryMockRecorder) DeleteShorteningURL(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) DeleteShorteningURL(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteShorteningURL", reflect.TypeOf((*MockRepository)(nil).DeleteShorteningURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteShorteningURL", reflect.TypeOf((*MockRepository)(nil).DeleteShorteningURL), arg0, arg1)
 }
 
 // GetOriginalURL mocks base method.
-func (m *MockRepository) GetOriginalURL(arg0 string) (string, *business.Error) {
+func (m *MockRepository) GetOriginalURL(arg0 context.Context, arg1 string) (string, *business.Error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOriginalURL", arg0)
+	ret := m.ctrl.Call(m, "GetOriginalURL", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(*business.Error)
 	return ret0, ret1
 }
 
 // GetOriginalURL indicates an expected call of GetOriginalURL.
-func (mr *MockRepositoryMockRecorder) GetOriginalURL(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetOriginalURL(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockRepository)(nil).GetOriginalURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalURL", reflect.TypeOf((*MockRepository)(nil).GetOriginalURL), arg0, arg1)
+}
+
+// GetURLHealth mocks base method.
+func (m *MockRepository) GetURLHealth(arg0 context.Context, arg1 string) (*dao.HealthCheck, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURLHealth", arg0, arg1)
+	ret0, _ := ret[0].(*dao.HealthCheck)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// GetURLHealth indicates an expected call of GetURLHealth.
+func (mr *MockRepositoryMockRecorder) GetURLHealth(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLHealth", reflect.TypeOf((*MockRepository)(nil).GetURLHealth), arg0, arg1)
+}
+
+// ImportURLs mocks base method.
+func (m *MockRepository) ImportURLs(arg0 context.Context, arg1 []dao.URL) ([]dao.ImportResult, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportURLs", arg0, arg1)
+	ret0, _ := ret[0].([]dao.ImportResult)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// ImportURLs indicates an expected call of ImportURLs.
+func (mr *MockRepositoryMockRecorder) ImportURLs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportURLs", reflect.TypeOf((*MockRepository)(nil).ImportURLs), arg0, arg1)
+}
+
+// ListBrokenURLs mocks base method.
+func (m *MockRepository) ListBrokenURLs(arg0 context.Context, arg1 int) ([]dao.HealthCheck, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBrokenURLs", arg0, arg1)
+	ret0, _ := ret[0].([]dao.HealthCheck)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// ListBrokenURLs indicates an expected call of ListBrokenURLs.
+func (mr *MockRepositoryMockRecorder) ListBrokenURLs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBrokenURLs", reflect.TypeOf((*MockRepository)(nil).ListBrokenURLs), arg0, arg1)
+}
+
+// ListURLs mocks base method.
+func (m *MockRepository) ListURLs(arg0 context.Context, arg1 string, arg2 int, arg3 bool) ([]dao.URL, *business.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]dao.URL)
+	ret1, _ := ret[1].(*business.Error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockRepositoryMockRecorder) ListURLs(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockRepository)(nil).ListURLs), arg0, arg1, arg2, arg3)
 }
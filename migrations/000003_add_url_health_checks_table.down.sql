@@ -0,0 +1 @@
+DROP TABLE IF EXISTS url_health_checks;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS url_health_checks(
+    url_id CHARACTER VARYING(6) PRIMARY KEY NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    error CHARACTER VARYING(512) NOT NULL DEFAULT '',
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    broken BOOLEAN NOT NULL DEFAULT false,
+    checked_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT current_timestamp
+);
+CREATE INDEX IF NOT EXISTS url_health_checks_broken_idx ON url_health_checks(broken) WHERE broken;
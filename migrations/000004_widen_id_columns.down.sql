@@ -0,0 +1,4 @@
+-- 已經有超過6個字元的id時會失敗 需要先處理掉那些id
+ALTER TABLE url_health_checks ALTER COLUMN url_id TYPE CHARACTER VARYING(6);
+ALTER TABLE keys ALTER COLUMN id TYPE CHARACTER VARYING(6);
+ALTER TABLE urls ALTER COLUMN id TYPE CHARACTER VARYING(6);
@@ -0,0 +1,4 @@
+-- 讓不同長度的id可以共存 16要跟shortid.MaxLength一致 varchar加寬不需要rewrite table
+ALTER TABLE urls ALTER COLUMN id TYPE CHARACTER VARYING(16);
+ALTER TABLE keys ALTER COLUMN id TYPE CHARACTER VARYING(16);
+ALTER TABLE url_health_checks ALTER COLUMN url_id TYPE CHARACTER VARYING(16);
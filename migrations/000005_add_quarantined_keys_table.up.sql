@@ -0,0 +1,7 @@
+-- 過期或刪除的url id在release_at之前不能再被發出去 之後由RecycleKeyJob放回keys
+CREATE TABLE IF NOT EXISTS quarantined_keys(
+    id CHARACTER VARYING(16) PRIMARY KEY NOT NULL,
+    quarantined_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT current_timestamp,
+    release_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS quarantined_keys_release_at_idx ON quarantined_keys(release_at);
@@ -0,0 +1,2 @@
+-- sequence strategy用的流水號 經過permutation打散之後才會變成id
+CREATE SEQUENCE IF NOT EXISTS url_id_seq AS BIGINT MINVALUE 0 START WITH 0;
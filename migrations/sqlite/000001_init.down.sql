@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS quarantined_keys;
+DROP TABLE IF EXISTS url_health_checks;
+DROP TABLE IF EXISTS keys;
+DROP TABLE IF EXISTS urls;
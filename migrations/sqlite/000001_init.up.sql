@@ -0,0 +1,28 @@
+-- 跟postgres的000001到000006最後的schema一樣 sqlite沒有timestamp型別 時間存成UTC的unix microsecond
+CREATE TABLE IF NOT EXISTS urls(
+    id VARCHAR(16) PRIMARY KEY NOT NULL,
+    original VARCHAR(2048) NOT NULL,
+    created_at INTEGER NOT NULL,
+    expired_at INTEGER NOT NULL
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS keys(
+    id VARCHAR(16) PRIMARY KEY NOT NULL,
+    created_at INTEGER NOT NULL
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS url_health_checks(
+    url_id VARCHAR(16) PRIMARY KEY NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    error VARCHAR(512) NOT NULL DEFAULT '',
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    broken BOOLEAN NOT NULL DEFAULT false,
+    checked_at INTEGER NOT NULL
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS url_health_checks_broken_idx ON url_health_checks(broken) WHERE broken;
+-- 過期或刪除的url id在release_at之前不能再被發出去 之後由RecycleKeyJob放回keys
+CREATE TABLE IF NOT EXISTS quarantined_keys(
+    id VARCHAR(16) PRIMARY KEY NOT NULL,
+    quarantined_at INTEGER NOT NULL,
+    release_at INTEGER NOT NULL
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS quarantined_keys_release_at_idx ON quarantined_keys(release_at);
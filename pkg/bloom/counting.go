@@ -0,0 +1,96 @@
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// CountingFilter 每個位置是一個counter的bloom filter 所以可以刪除
+// Exist回傳false代表一定沒加過 true則有false positive的可能
+// counter到255之後就不再變動 避免刪除時誤刪別人的
+type CountingFilter struct {
+	mu       sync.RWMutex
+	counters []uint8
+	hashes   int
+}
+
+// New 依照預計放入的數量跟可以接受的false positive rate決定counter數量跟hash次數
+func New(capacity int, falsePositiveRate float64) *CountingFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+	m := math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &CountingFilter{counters: make([]uint8, int(m)), hashes: k}
+}
+
+func (c *CountingFilter) Add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, i := range c.positions(key) {
+		if c.counters[i] < math.MaxUint8 {
+			c.counters[i]++
+		}
+	}
+}
+
+func (c *CountingFilter) Exist(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.exist(c.positions(key))
+}
+
+// Delete 只刪除可能存在的key 回傳是否有刪
+func (c *CountingFilter) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	positions := c.positions(key)
+	if !c.exist(positions) {
+		return false
+	}
+	for _, i := range positions {
+		if c.counters[i] < math.MaxUint8 {
+			c.counters[i]--
+		}
+	}
+	return true
+}
+
+// Reset 清空所有key
+func (c *CountingFilter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.counters {
+		c.counters[i] = 0
+	}
+}
+
+func (c *CountingFilter) exist(positions []uint64) bool {
+	for _, i := range positions {
+		if c.counters[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// positions double hashing 用一次64 bit的fnv切成兩個hash組出k個位置
+func (c *CountingFilter) positions(key string) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&math.MaxUint32, sum>>32|1
+	n := uint64(len(c.counters))
+	positions := make([]uint64, c.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % n
+	}
+	return positions
+}
@@ -0,0 +1,60 @@
+package bloom
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CountingFilter", func() {
+	var filter *CountingFilter
+
+	BeforeEach(func() {
+		filter = New(1000, 0.01)
+	})
+
+	It("exist after add", func() {
+		filter.Add("abcdef")
+		Expect(filter.Exist("abcdef")).To(BeTrue())
+		Expect(filter.Exist("ghijkl")).To(BeFalse())
+	})
+
+	It("not exist after delete", func() {
+		filter.Add("abcdef")
+		Expect(filter.Delete("abcdef")).To(BeTrue())
+		Expect(filter.Exist("abcdef")).To(BeFalse())
+		Expect(filter.Delete("abcdef")).To(BeFalse())
+	})
+
+	It("keep other keys after delete", func() {
+		for i := 0; i < 1000; i++ {
+			filter.Add(fmt.Sprintf("key-%d", i))
+		}
+		for i := 0; i < 500; i++ {
+			filter.Delete(fmt.Sprintf("key-%d", i))
+		}
+		for i := 500; i < 1000; i++ {
+			Expect(filter.Exist(fmt.Sprintf("key-%d", i))).To(BeTrue())
+		}
+	})
+
+	It("false positive rate close to configured", func() {
+		for i := 0; i < 1000; i++ {
+			filter.Add(fmt.Sprintf("key-%d", i))
+		}
+		falsePositives := 0
+		for i := 0; i < 10000; i++ {
+			if filter.Exist(fmt.Sprintf("other-%d", i)) {
+				falsePositives++
+			}
+		}
+		Expect(falsePositives).To(BeNumerically("<", 300))
+	})
+
+	It("reset", func() {
+		filter.Add("abcdef")
+		filter.Reset()
+		Expect(filter.Exist("abcdef")).To(BeFalse())
+	})
+})
@@ -0,0 +1,13 @@
+package bloom
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBloom(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bloom Suite")
+}
@@ -1,6 +1,15 @@
 package business
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// StatusClientClosedRequest 沿用nginx的499 client已經斷線 response其實送不出去
+const StatusClientClosedRequest = 499
 
 func NewError(businessCode int, httpStatusCode int, message string, reason error) *Error {
 	if reason == nil {
@@ -9,6 +18,20 @@ func NewError(businessCode int, httpStatusCode int, message string, reason error
 	return &Error{BusinessCode: businessCode, Base: Base{HTTPStatusCode: httpStatusCode}, Message: message, Reason: reason}
 }
 
+// NewContextError err是超過deadline或是ctx被取消造成的才回傳 其他的回傳nil
+// driver在deadline到的時候通常回傳的是連線的timeout 而不是context.DeadlineExceeded
+func NewContextError(err error) *Error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return NewError(DeadlineExceeded, http.StatusGatewayTimeout, "deadline exceeded", err)
+	case errors.Is(err, context.Canceled):
+		return NewError(Canceled, StatusClientClosedRequest, "request canceled", err)
+	default:
+		return nil
+	}
+}
+
 func NewSuccess(httpStatusCode int, response interface{}) *Success {
 	return &Success{Base: Base{HTTPStatusCode: httpStatusCode}, Response: response}
 }
@@ -23,6 +46,8 @@ type Error struct {
 	Message          string            `json:"message"`
 	ValidationErrors map[string]string `json:"validationErrors,omitempty"`
 	Reason           error             `json:"-"`
+	// RetryAfter 大於0時 response會帶Retry-After header
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (b *Error) Error() string {
@@ -10,6 +10,8 @@ const (
 	MethodNowAllowed  = 1005
 	PathNotFound      = 1006
 	TooManyRequest    = 1007
+	DeadlineExceeded  = 1008
+	Canceled          = 1009
 
 	// postgres
 	PostgresInternalError = 1100
@@ -19,4 +21,10 @@ const (
 
 	// lock
 	AcquireLockURLResourceError = 1300
+
+	// key pool
+	CapacityExhausted = 1400
+
+	// sqlite
+	SQLiteInternalError = 1500
 )
@@ -1,16 +1,25 @@
 package dao
 
 import (
+	"context"
+
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 )
 
 type CacheDAO interface {
-	GetOriginalURL(name string) (string, *business.Error)
-	SetOriginalURL(name string, originalURL string) *business.Error
-	DeleteOriginalURL(name string) *business.Error
-	DeleteMultiOriginalURL(names []string) *business.Error
-	AddOriginalURLIDInFilters(originalURL string) *business.Error
-	ExistOriginalURLIDInFilters(originalURL string) (bool, *business.Error)
-	DeleteOriginalURLIDInFilters(originalURL string) (bool, *business.Error)
-	DeleteMultiOriginalURLIDInFilters(originalURLIDs []string) (bool, *business.Error)
+	GetOriginalURL(ctx context.Context, name string) (string, *business.Error)
+	SetOriginalURL(ctx context.Context, name string, originalURL string) *business.Error
+	SetOriginalURLNotFound(ctx context.Context, name string) *business.Error
+	// DeleteOriginalURLNotFound 只刪掉不存在的記號 已經cache的url不動 id被建立或import時用
+	DeleteOriginalURLNotFound(ctx context.Context, names []string) *business.Error
+	// SetMultiOriginalURL 已經在cache裡的不會被覆蓋 只有不存在的記號會被蓋掉 key是url id
+	SetMultiOriginalURL(ctx context.Context, originalURLs map[string]string) *business.Error
+	DeleteOriginalURL(ctx context.Context, name string) *business.Error
+	DeleteMultiOriginalURL(ctx context.Context, names []string) *business.Error
+	AddOriginalURLIDInFilters(ctx context.Context, originalURL string) *business.Error
+	ExistOriginalURLIDInFilters(ctx context.Context, originalURL string) (bool, *business.Error)
+	DeleteOriginalURLIDInFilters(ctx context.Context, originalURL string) (bool, *business.Error)
+	DeleteMultiOriginalURLIDInFilters(ctx context.Context, originalURLIDs []string) (bool, *business.Error)
+	// AddMultiOriginalURLIDInFilters 只加入還不在filter裡的 回傳加了幾個
+	AddMultiOriginalURLIDInFilters(ctx context.Context, originalURLIDs []string) (int, *business.Error)
 }
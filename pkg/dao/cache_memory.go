@@ -0,0 +1,139 @@
+package dao
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+)
+
+// 每寫入這麼多次就掃一次 清掉已經過期的originalURL
+const memoryCacheSweepInterval = 1024
+
+func NewMemoryCacheDAO(notFoundTTL time.Duration, filter OriginalURLIDFilter) *MemoryCacheDAO {
+	return &MemoryCacheDAO{notFoundTTL: notFoundTTL, filter: filter, entries: make(map[string]memoryCacheEntry)}
+}
+
+// MemoryCacheDAO 取代redis的cache 行為跟RedisCacheDAO一樣 包括miss時回傳的Reason
+type MemoryCacheDAO struct {
+	// 大於0時 查不到的id會在cache裡記住這麼久 0則不記
+	notFoundTTL time.Duration
+	filter      OriginalURLIDFilter
+
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	writes  int
+}
+
+type memoryCacheEntry struct {
+	originalURL string
+	expiredAt   time.Time
+}
+
+func (m *MemoryCacheDAO) GetOriginalURL(ctx context.Context, name string) (string, *business.Error) {
+	m.mu.Lock()
+	entry, ok := m.entries[name]
+	m.mu.Unlock()
+	if !ok || !entry.expiredAt.After(time.Now()) {
+		return "", business.NewError(business.NotFound, http.StatusNotFound, "record not found", RedisErrKeyNotExist)
+	}
+	if entry.originalURL == originalURLNotFoundSentinel {
+		return "", business.NewError(business.NotFound, http.StatusNotFound, "record not found", RedisErrCachedNotFound)
+	}
+	return entry.originalURL, nil
+}
+
+func (m *MemoryCacheDAO) SetOriginalURL(ctx context.Context, name string, originalURL string) *business.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// 加入random seconds to prevent 大量緩存同時失效的問題
+	m.set(name, originalURL, hotOriginalURLBaseTTL+getRandomOriginalURLTTLSecond())
+	return nil
+}
+
+func (m *MemoryCacheDAO) SetMultiOriginalURL(ctx context.Context, originalURLs map[string]string) *business.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for name, originalURL := range originalURLs {
+		if entry, ok := m.entries[name]; ok && entry.expiredAt.After(now) && entry.originalURL != originalURLNotFoundSentinel {
+			continue
+		}
+		m.set(name, originalURL, hotOriginalURLBaseTTL+getRandomOriginalURLTTLSecond())
+	}
+	return nil
+}
+
+func (m *MemoryCacheDAO) SetOriginalURLNotFound(ctx context.Context, name string) *business.Error {
+	if m.notFoundTTL <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(name, originalURLNotFoundSentinel, m.notFoundTTL)
+	return nil
+}
+
+func (m *MemoryCacheDAO) DeleteOriginalURLNotFound(ctx context.Context, names []string) *business.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range names {
+		if entry, ok := m.entries[name]; ok && entry.originalURL == originalURLNotFoundSentinel {
+			delete(m.entries, name)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryCacheDAO) DeleteOriginalURL(ctx context.Context, name string) *business.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, name)
+	return nil
+}
+
+func (m *MemoryCacheDAO) DeleteMultiOriginalURL(ctx context.Context, names []string) *business.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range names {
+		delete(m.entries, name)
+	}
+	return nil
+}
+
+// set 呼叫前要先拿m.mu redis會自己清掉過期的key 這裡每隔一段時間掃一次
+func (m *MemoryCacheDAO) set(name string, originalURL string, ttl time.Duration) {
+	now := time.Now()
+	m.entries[name] = memoryCacheEntry{originalURL: originalURL, expiredAt: now.Add(ttl)}
+	m.writes++
+	if m.writes%memoryCacheSweepInterval != 0 {
+		return
+	}
+	for name, entry := range m.entries {
+		if !entry.expiredAt.After(now) {
+			delete(m.entries, name)
+		}
+	}
+}
+
+func (m *MemoryCacheDAO) AddOriginalURLIDInFilters(ctx context.Context, originalURLID string) *business.Error {
+	return m.filter.Add(ctx, originalURLID)
+}
+
+func (m *MemoryCacheDAO) ExistOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	return m.filter.Exist(ctx, originalURLID)
+}
+
+func (m *MemoryCacheDAO) DeleteOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	return m.filter.Delete(ctx, originalURLID)
+}
+
+func (m *MemoryCacheDAO) DeleteMultiOriginalURLIDInFilters(ctx context.Context, originalURLIDs []string) (bool, *business.Error) {
+	return m.filter.DeleteMulti(ctx, originalURLIDs)
+}
+
+func (m *MemoryCacheDAO) AddMultiOriginalURLIDInFilters(ctx context.Context, originalURLIDs []string) (int, *business.Error) {
+	return m.filter.AddMissing(ctx, originalURLIDs)
+}
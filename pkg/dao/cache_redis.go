@@ -3,91 +3,189 @@ package dao
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/KennyChenFight/golib/redislib"
+	"github.com/go-redis/redis/v8"
 )
 
-func NewRedisCacheDAO(logger *loglib.Logger, client *redislib.GORedisClient) *RedisCacheDAO {
-	return &RedisCacheDAO{logger, client}
+var (
+	// 沒有值或是不存在的記號才寫入 ARGV[1]是記號 之後每個key依序是url以及ttl(秒)
+	setOriginalURLIfMissingScript = redis.NewScript(`
+for i = 1, #KEYS do
+	local current = redis.call("GET", KEYS[i])
+	if current == false or current == ARGV[1] then
+		redis.call("SET", KEYS[i], ARGV[i * 2], "EX", ARGV[i * 2 + 1])
+	end
+end
+return 0
+`)
+	// 值是不存在的記號才刪掉 ARGV[1]是記號
+	deleteOriginalURLNotFoundScript = redis.NewScript(`
+local deleted = 0
+for i = 1, #KEYS do
+	if redis.call("GET", KEYS[i]) == ARGV[1] then
+		deleted = deleted + redis.call("DEL", KEYS[i])
+	end
+end
+return deleted
+`)
+)
+
+func NewRedisCacheDAO(logger *loglib.Logger, client *redislib.GORedisClient, notFoundTTL time.Duration, filter OriginalURLIDFilter) *RedisCacheDAO {
+	return &RedisCacheDAO{logger, client, notFoundTTL, filter}
 }
 
 type RedisCacheDAO struct {
 	logger *loglib.Logger
 	client *redislib.GORedisClient
+	// 大於0時 查不到的id會在cache裡記住這麼久 0則不記
+	notFoundTTL time.Duration
+	filter      OriginalURLIDFilter
 }
 
-func (r *RedisCacheDAO) GetOriginalURL(name string) (string, *business.Error) {
-	originalURL, err := r.client.Get(context.Background(), fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)).Result()
+func (r *RedisCacheDAO) GetOriginalURL(ctx context.Context, name string) (string, *business.Error) {
+	originalURL, err := r.client.Get(ctx, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)).Result()
 	if err != nil {
 		return "", redisErrorHandle(r.logger, err)
 	}
+	if originalURL == originalURLNotFoundSentinel {
+		return "", business.NewError(business.NotFound, http.StatusNotFound, "record not found", RedisErrCachedNotFound)
+	}
 	return originalURL, nil
 }
 
-func (r *RedisCacheDAO) SetOriginalURL(name string, originalURL string) *business.Error {
+func (r *RedisCacheDAO) SetOriginalURL(ctx context.Context, name string, originalURL string) *business.Error {
 	// 加入random seconds to prevent 大量緩存同時失效的問題
 	random := getRandomOriginalURLTTLSecond()
 	expire := hotOriginalURLBaseTTL + random
-	_, err := r.client.Set(context.Background(), fmt.Sprintf("%s-%s", prefixHotOriginalURL, name), originalURL, expire).Result()
+	_, err := r.client.Set(ctx, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name), originalURL, expire).Result()
 	if err != nil {
 		return redisErrorHandle(r.logger, err)
 	}
 	return nil
 }
 
-func (r *RedisCacheDAO) DeleteOriginalURL(name string) *business.Error {
-	err := r.client.Del(context.Background(), fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)).Err()
+func (r *RedisCacheDAO) SetMultiOriginalURL(ctx context.Context, originalURLs map[string]string) *business.Error {
+	if len(originalURLs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(originalURLs))
+	args := make([]interface{}, 0, len(originalURLs)*2+1)
+	args = append(args, originalURLNotFoundSentinel)
+	for name, originalURL := range originalURLs {
+		// 每個都加上不同的random seconds 避免同一批一起失效
+		expire := hotOriginalURLBaseTTL + getRandomOriginalURLTTLSecond()
+		keys = append(keys, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name))
+		args = append(args, originalURL, int(expire/time.Second))
+	}
+	if err := setOriginalURLIfMissingScript.Run(ctx, r.client, keys, args...).Err(); err != nil {
+		return redisErrorHandle(r.logger, err)
+	}
+	return nil
+}
+
+// SetOriginalURLNotFound 記住這個id不存在 之後SetOriginalURL會直接蓋掉
+// ttl不加random 因為本來就很短
+func (r *RedisCacheDAO) SetOriginalURLNotFound(ctx context.Context, name string) *business.Error {
+	if r.notFoundTTL <= 0 {
+		return nil
+	}
+	_, err := r.client.Set(ctx, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name), originalURLNotFoundSentinel, r.notFoundTTL).Result()
 	if err != nil {
 		return redisErrorHandle(r.logger, err)
 	}
 	return nil
 }
 
-func (r *RedisCacheDAO) DeleteMultiOriginalURL(names []string) *business.Error {
-	var formatNames []string
+func (r *RedisCacheDAO) DeleteOriginalURLNotFound(ctx context.Context, names []string) *business.Error {
+	if len(names) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(names))
 	for _, name := range names {
-		formatNames = append(formatNames, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name))
+		keys = append(keys, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name))
 	}
-	err := r.client.Del(context.Background(), formatNames...).Err()
-	if err != nil {
+	if err := deleteOriginalURLNotFoundScript.Run(ctx, r.client, keys, originalURLNotFoundSentinel).Err(); err != nil {
 		return redisErrorHandle(r.logger, err)
 	}
 	return nil
 }
 
-func (r *RedisCacheDAO) AddOriginalURLIDInFilters(originalURLID string) *business.Error {
-	_, err := r.client.Do(context.Background(), "CF.ADD", originalURLIDsFilterName, originalURLID).Result()
+func (r *RedisCacheDAO) DeleteOriginalURL(ctx context.Context, name string) *business.Error {
+	err := r.client.Del(ctx, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)).Err()
 	if err != nil {
 		return redisErrorHandle(r.logger, err)
 	}
-	return nil
+	return r.publishInvalidation(ctx, []string{name})
 }
 
-func (r *RedisCacheDAO) ExistOriginalURLIDInFilters(originalURLID string) (bool, *business.Error) {
-	ok, err := r.client.Do(context.Background(), "CF.EXISTS", originalURLIDsFilterName, originalURLID).Bool()
+func (r *RedisCacheDAO) DeleteMultiOriginalURL(ctx context.Context, names []string) *business.Error {
+	if len(names) == 0 {
+		return nil
+	}
+	var formatNames []string
+	for _, name := range names {
+		formatNames = append(formatNames, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name))
+	}
+	err := r.client.Del(ctx, formatNames...).Err()
 	if err != nil {
-		return ok, redisErrorHandle(r.logger, err)
+		return redisErrorHandle(r.logger, err)
 	}
-	return ok, nil
+	return r.publishInvalidation(ctx, names)
 }
 
-func (r *RedisCacheDAO) DeleteOriginalURLIDInFilters(originalURLID string) (bool, *business.Error) {
-	ok, err := r.client.Do(context.Background(), "CF.DEL", originalURLIDsFilterName, originalURLID).Bool()
+func (r *RedisCacheDAO) publishInvalidation(ctx context.Context, names []string) *business.Error {
+	err := r.client.Publish(ctx, originalURLInvalidationChannel, strings.Join(names, ",")).Err()
 	if err != nil {
-		return ok, redisErrorHandle(r.logger, err)
+		return redisErrorHandle(r.logger, err)
 	}
-	return ok, nil
+	return nil
 }
 
-func (r *RedisCacheDAO) DeleteMultiOriginalURLIDInFilters(originalURLIDs []string) (bool, *business.Error) {
-	// not good enough :(
-	for _, id := range originalURLIDs {
-		ok, err := r.client.Do(context.Background(), "CF.DEL", originalURLIDsFilterName, id).Bool()
-		if err != nil {
-			return ok, redisErrorHandle(r.logger, err)
+// SubscribeInvalidation 有url的cache被刪除時呼叫handler 包含自己刪除的 直到ctx結束才return
+// 斷線期間的通知會收不到 所以L1 cache的ttl不能太長
+func (r *RedisCacheDAO) SubscribeInvalidation(ctx context.Context, handler func(names []string)) *business.Error {
+	pubsub := r.client.Subscribe(ctx, originalURLInvalidationChannel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return redisErrorHandle(r.logger, err)
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			handler(strings.Split(msg.Payload, ","))
 		}
 	}
-	return true, nil
+}
+
+func (r *RedisCacheDAO) AddOriginalURLIDInFilters(ctx context.Context, originalURLID string) *business.Error {
+	return r.filter.Add(ctx, originalURLID)
+}
+
+func (r *RedisCacheDAO) ExistOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	return r.filter.Exist(ctx, originalURLID)
+}
+
+func (r *RedisCacheDAO) DeleteOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	return r.filter.Delete(ctx, originalURLID)
+}
+
+func (r *RedisCacheDAO) DeleteMultiOriginalURLIDInFilters(ctx context.Context, originalURLIDs []string) (bool, *business.Error) {
+	return r.filter.DeleteMulti(ctx, originalURLIDs)
+}
+
+func (r *RedisCacheDAO) AddMultiOriginalURLIDInFilters(ctx context.Context, originalURLIDs []string) (int, *business.Error) {
+	return r.filter.AddMissing(ctx, originalURLIDs)
 }
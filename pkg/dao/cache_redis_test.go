@@ -21,12 +21,15 @@ import (
 )
 
 var _ = Describe("RedisCacheDAO", func() {
+	BeforeEach(requireRedis)
+
+	ctx := context.Background()
 	var redisCacheDAO *RedisCacheDAO
 	var logger *loglib.Logger
 
 	BeforeEach(func() {
 		logger = loglib.NewNopLogger()
-		redisCacheDAO = &RedisCacheDAO{logger, testRedisClient}
+		redisCacheDAO = &RedisCacheDAO{logger, testRedisClient, time.Minute, NewRedisCuckooFilter(logger, testRedisClient, CuckooFilterConfig{})}
 	})
 
 	var _ = Describe("GetOriginalURL", func() {
@@ -35,13 +38,12 @@ var _ = Describe("RedisCacheDAO", func() {
 			getErr            *business.Error
 		)
 
-		ctx := context.Background()
 		name := "testName"
 		key := fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)
 		actualOriginalURL := "http://example.com"
 
 		JustBeforeEach(func() {
-			expectOriginalURL, getErr = redisCacheDAO.GetOriginalURL(name)
+			expectOriginalURL, getErr = redisCacheDAO.GetOriginalURL(ctx, name)
 		})
 
 		Context("success", func() {
@@ -59,6 +61,21 @@ var _ = Describe("RedisCacheDAO", func() {
 			})
 		})
 
+		Context("cached not found", func() {
+			BeforeEach(func() {
+				testRedisClient.Set(ctx, key, originalURLNotFoundSentinel, -1)
+			})
+
+			AfterEach(func() {
+				testRedisClient.Del(ctx, key)
+			})
+
+			It("result", func() {
+				Expect(getErr).To(Equal(business.NewError(business.NotFound, http.StatusNotFound, "record not found", RedisErrCachedNotFound)))
+				Expect(expectOriginalURL).To(Equal(""))
+			})
+		})
+
 		Context("redis key not exist", func() {
 			It("result", func() {
 				Expect(getErr).To(Equal(business.NewError(business.NotFound, http.StatusNotFound, "record not found", RedisErrKeyNotExist)))
@@ -92,13 +109,12 @@ var _ = Describe("RedisCacheDAO", func() {
 			setErr *business.Error
 		)
 
-		ctx := context.Background()
 		name := "testName"
 		key := fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)
 		originalURL := "http://example.com"
 
 		JustBeforeEach(func() {
-			setErr = redisCacheDAO.SetOriginalURL(name, originalURL)
+			setErr = redisCacheDAO.SetOriginalURL(ctx, name, originalURL)
 		})
 
 		Context("success", func() {
@@ -138,27 +154,60 @@ var _ = Describe("RedisCacheDAO", func() {
 		})
 	})
 
-	var _ = Describe("DeleteOriginalURL", func() {
+	var _ = Describe("SetMultiOriginalURL", func() {
+		keyA := fmt.Sprintf("%s-%s", prefixHotOriginalURL, "aaaaaa")
+		keyB := fmt.Sprintf("%s-%s", prefixHotOriginalURL, "bbbbbb")
+
+		AfterEach(func() {
+			testRedisClient.Del(ctx, keyA, keyB)
+		})
+
+		It("keep cached ones", func() {
+			testRedisClient.Set(ctx, keyA, "http://new.com", -1)
+			Expect(redisCacheDAO.SetMultiOriginalURL(ctx, map[string]string{"aaaaaa": "http://old.com", "bbbbbb": "http://b.com"})).To(BeNil())
+			Expect(testRedisClient.Get(ctx, keyA).Val()).To(Equal("http://new.com"))
+			Expect(testRedisClient.Get(ctx, keyB).Val()).To(Equal("http://b.com"))
+			Expect(testRedisClient.TTL(ctx, keyB).Val()).To(BeNumerically(">", hotOriginalURLBaseTTL))
+		})
+	})
+
+	var _ = Describe("SetOriginalURLNotFound", func() {
 		var (
-			deleteErr *business.Error
+			setErr *business.Error
 		)
 
-		ctx := context.Background()
 		name := "testName"
 		key := fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)
 		originalURL := "http://example.com"
 
 		JustBeforeEach(func() {
-			deleteErr = redisCacheDAO.DeleteOriginalURL(name)
+			setErr = redisCacheDAO.SetOriginalURLNotFound(ctx, name)
+		})
+
+		AfterEach(func() {
+			testRedisClient.Del(ctx, key)
 		})
 
 		Context("success", func() {
+			It("result", func() {
+				Expect(setErr).To(BeNil())
+				Expect(testRedisClient.Get(ctx, key).Val()).To(Equal(originalURLNotFoundSentinel))
+				Expect(testRedisClient.TTL(ctx, key).Val()).To(BeNumerically("<=", time.Minute))
+			})
+
+			It("overwritten by set originalURL", func() {
+				Expect(redisCacheDAO.SetOriginalURL(ctx, name, originalURL)).To(BeNil())
+				Expect(redisCacheDAO.GetOriginalURL(ctx, name)).To(Equal(originalURL))
+			})
+		})
+
+		Context("disabled", func() {
 			BeforeEach(func() {
-				testRedisClient.Set(ctx, key, originalURL, -1)
+				redisCacheDAO.notFoundTTL = 0
 			})
 
 			It("result", func() {
-				Expect(deleteErr).To(BeNil())
+				Expect(setErr).To(BeNil())
 				Expect(testRedisClient.Get(ctx, key).Err()).To(Equal(redis.Nil))
 			})
 		})
@@ -168,7 +217,7 @@ var _ = Describe("RedisCacheDAO", func() {
 			internalErr := errors.New("internal error")
 			BeforeEach(func() {
 				redisCacheDAO.client = &redislib.GORedisClient{Client: wrapperClient}
-				redisMock.ExpectDel(key).SetErr(internalErr)
+				redisMock.ExpectSet(key, originalURLNotFoundSentinel, time.Minute).SetErr(internalErr)
 			})
 
 			AfterEach(func() {
@@ -178,40 +227,32 @@ var _ = Describe("RedisCacheDAO", func() {
 			})
 
 			It("result", func() {
-				Expect(deleteErr).To(Equal(business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", internalErr)))
+				Expect(setErr).To(Equal(business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", internalErr)))
 			})
 		})
 	})
 
-	var _ = Describe("DeleteMultiOriginalURL", func() {
+	var _ = Describe("DeleteOriginalURL", func() {
 		var (
 			deleteErr *business.Error
 		)
 
-		ctx := context.Background()
-		names := []string{"testName1", "testName2"}
-		var keys []string
-		for _, name := range names {
-			keys = append(keys, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name))
-		}
+		name := "testName"
+		key := fmt.Sprintf("%s-%s", prefixHotOriginalURL, name)
 		originalURL := "http://example.com"
 
 		JustBeforeEach(func() {
-			deleteErr = redisCacheDAO.DeleteMultiOriginalURL(names)
+			deleteErr = redisCacheDAO.DeleteOriginalURL(ctx, name)
 		})
 
 		Context("success", func() {
 			BeforeEach(func() {
-				for _, key := range keys {
-					testRedisClient.Set(ctx, key, originalURL, -1)
-				}
+				testRedisClient.Set(ctx, key, originalURL, -1)
 			})
 
 			It("result", func() {
 				Expect(deleteErr).To(BeNil())
-				for _, key := range keys {
-					Expect(testRedisClient.Get(ctx, key).Err()).To(Equal(redis.Nil))
-				}
+				Expect(testRedisClient.Get(ctx, key).Err()).To(Equal(redis.Nil))
 			})
 		})
 
@@ -220,7 +261,7 @@ var _ = Describe("RedisCacheDAO", func() {
 			internalErr := errors.New("internal error")
 			BeforeEach(func() {
 				redisCacheDAO.client = &redislib.GORedisClient{Client: wrapperClient}
-				redisMock.ExpectDel(keys...).SetErr(internalErr)
+				redisMock.ExpectDel(key).SetErr(internalErr)
 			})
 
 			AfterEach(func() {
@@ -235,129 +276,79 @@ var _ = Describe("RedisCacheDAO", func() {
 		})
 	})
 
-	var _ = Describe("AddOriginalURLIDInFilters", func() {
-		var (
-			addErr *business.Error
-		)
-
-		ctx := context.Background()
-		id := "random"
-
-		JustBeforeEach(func() {
-			addErr = redisCacheDAO.AddOriginalURLIDInFilters(id)
-		})
-
-		Context("success", func() {
-			AfterEach(func() {
-				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
-				Expect(err).To(BeNil())
-			})
-
-			It("result", func() {
-				Expect(addErr).To(BeNil())
-				ok, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
-				Expect(err).To(BeNil())
-				Expect(ok).To(Equal(true))
-			})
-		})
-	})
+	var _ = Describe("SubscribeInvalidation", func() {
+		It("receive deleted names", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			received := make(chan []string, 2)
+			done := make(chan *business.Error)
+			go func() {
+				done <- redisCacheDAO.SubscribeInvalidation(ctx, func(names []string) {
+					received <- names
+				})
+			}()
 
-	var _ = Describe("ExistOriginalURLIDInFilters", func() {
-		var (
-			existErr *business.Error
-			expectOk bool
-		)
+			// 等subscribe完成
+			Eventually(func() int64 {
+				return testRedisClient.PubSubNumSub(context.Background(), originalURLInvalidationChannel).Val()[originalURLInvalidationChannel]
+			}).Should(Equal(int64(1)))
 
-		ctx := context.Background()
-		id := "random"
+			Expect(redisCacheDAO.DeleteOriginalURL(ctx, "testName")).To(BeNil())
+			Expect(redisCacheDAO.DeleteMultiOriginalURL(ctx, []string{"testName1", "testName2"})).To(BeNil())
+			Eventually(received).Should(Receive(Equal([]string{"testName"})))
+			Eventually(received).Should(Receive(Equal([]string{"testName1", "testName2"})))
 
-		JustBeforeEach(func() {
-			expectOk, existErr = redisCacheDAO.ExistOriginalURLIDInFilters(id)
-		})
-
-		Context("success", func() {
-			BeforeEach(func() {
-				_, err := testRedisClient.Do(ctx, "CF.ADD", originalURLIDsFilterName, id).Result()
-				Expect(err).To(BeNil())
-			})
-			AfterEach(func() {
-				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
-				Expect(err).To(BeNil())
-			})
-
-			It("result", func() {
-				Expect(existErr).To(BeNil())
-				actualOk, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
-				Expect(err).To(BeNil())
-				Expect(actualOk).To(Equal(expectOk))
-			})
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
 		})
 	})
 
-	var _ = Describe("DeleteOriginalURLIDInFilters", func() {
+	var _ = Describe("DeleteMultiOriginalURL", func() {
 		var (
 			deleteErr *business.Error
-			expectOk  bool
 		)
 
-		ctx := context.Background()
-		id := "random"
+		names := []string{"testName1", "testName2"}
+		var keys []string
+		for _, name := range names {
+			keys = append(keys, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name))
+		}
+		originalURL := "http://example.com"
 
 		JustBeforeEach(func() {
-			expectOk, deleteErr = redisCacheDAO.DeleteOriginalURLIDInFilters(id)
+			deleteErr = redisCacheDAO.DeleteMultiOriginalURL(ctx, names)
 		})
 
 		Context("success", func() {
 			BeforeEach(func() {
-				_, err := testRedisClient.Do(ctx, "CF.ADD", originalURLIDsFilterName, id).Result()
-				Expect(err).To(BeNil())
-			})
-			AfterEach(func() {
-				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
-				Expect(err).To(BeNil())
+				for _, key := range keys {
+					testRedisClient.Set(ctx, key, originalURL, -1)
+				}
 			})
 
 			It("result", func() {
 				Expect(deleteErr).To(BeNil())
-				actualOk, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
-				Expect(err).To(BeNil())
-				Expect(!actualOk).To(Equal(expectOk))
+				for _, key := range keys {
+					Expect(testRedisClient.Get(ctx, key).Err()).To(Equal(redis.Nil))
+				}
 			})
 		})
-	})
-
-	var _ = Describe("DeleteMultiOriginalURLIDInFilters", func() {
-		var (
-			deleteErr *business.Error
-			expectOk  bool
-		)
-
-		ctx := context.Background()
-		ids := []string{"random1", "random2"}
-
-		JustBeforeEach(func() {
-			expectOk, deleteErr = redisCacheDAO.DeleteMultiOriginalURLIDInFilters(ids)
-		})
 
-		Context("success", func() {
+		Context("redis internal error", func() {
+			wrapperClient, redisMock := redismock.NewClientMock()
+			internalErr := errors.New("internal error")
 			BeforeEach(func() {
-				for _, id := range ids {
-					_, err := testRedisClient.Do(ctx, "CF.ADD", originalURLIDsFilterName, id).Result()
-					Expect(err).To(BeNil())
-				}
+				redisCacheDAO.client = &redislib.GORedisClient{Client: wrapperClient}
+				redisMock.ExpectDel(keys...).SetErr(internalErr)
 			})
+
 			AfterEach(func() {
-				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
-				Expect(err).To(BeNil())
+				redisMock.ClearExpect()
+				wrapperClient.Close()
+				redisCacheDAO.client = testRedisClient
 			})
 
 			It("result", func() {
-				Expect(deleteErr).To(BeNil())
-				for _, id := range ids {
-					actualOk, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
-					Expect(err).To(BeNil())
-					Expect(!actualOk).To(Equal(expectOk))
-				}
+				Expect(deleteErr).To(Equal(business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", internalErr)))
 			})
 		})
 	})
@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/localcache"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+)
+
+func NewTieredCacheDAO(l1 *localcache.Cache, l2 CacheDAO) *TieredCacheDAO {
+	return &TieredCacheDAO{l1: l1, CacheDAO: l2}
+}
+
+// TieredCacheDAO 在redis前面多一層process內的cache L1 hit時不需要任何redis round trip
+// 其他instance刪除url時透過Invalidate清掉 filter的操作直接交給L2
+type TieredCacheDAO struct {
+	l1 *localcache.Cache
+	CacheDAO
+}
+
+func (t *TieredCacheDAO) GetOriginalURL(ctx context.Context, name string) (string, *business.Error) {
+	if originalURL, ok := t.l1.Get(name); ok {
+		metrics.OriginalURLL1CacheHits.Inc()
+		return originalURL, nil
+	}
+	metrics.OriginalURLL1CacheMisses.Inc()
+
+	originalURL, err := t.CacheDAO.GetOriginalURL(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	t.l1.Set(name, originalURL)
+	return originalURL, nil
+}
+
+func (t *TieredCacheDAO) SetOriginalURL(ctx context.Context, name string, originalURL string) *business.Error {
+	if err := t.CacheDAO.SetOriginalURL(ctx, name, originalURL); err != nil {
+		return err
+	}
+	t.l1.Set(name, originalURL)
+	return nil
+}
+
+// SetOriginalURLNotFound 不存在的id只記在L2 這樣新建立的url在每個instance上都能馬上看到
+func (t *TieredCacheDAO) SetOriginalURLNotFound(ctx context.Context, name string) *business.Error {
+	t.l1.Delete(name)
+	return t.CacheDAO.SetOriginalURLNotFound(ctx, name)
+}
+
+func (t *TieredCacheDAO) DeleteOriginalURL(ctx context.Context, name string) *business.Error {
+	t.l1.Delete(name)
+	return t.CacheDAO.DeleteOriginalURL(ctx, name)
+}
+
+func (t *TieredCacheDAO) DeleteMultiOriginalURL(ctx context.Context, names []string) *business.Error {
+	t.l1.Delete(names...)
+	return t.CacheDAO.DeleteMultiOriginalURL(ctx, names)
+}
+
+// ExistOriginalURLIDInFilters L1裡有的一定存在 不用再問filter
+func (t *TieredCacheDAO) ExistOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	if _, ok := t.l1.Get(originalURLID); ok {
+		return true, nil
+	}
+	return t.CacheDAO.ExistOriginalURLIDInFilters(ctx, originalURLID)
+}
+
+func (t *TieredCacheDAO) DeleteOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	t.l1.Delete(originalURLID)
+	return t.CacheDAO.DeleteOriginalURLIDInFilters(ctx, originalURLID)
+}
+
+func (t *TieredCacheDAO) DeleteMultiOriginalURLIDInFilters(ctx context.Context, originalURLIDs []string) (bool, *business.Error) {
+	t.l1.Delete(originalURLIDs...)
+	return t.CacheDAO.DeleteMultiOriginalURLIDInFilters(ctx, originalURLIDs)
+}
+
+// Invalidate 給SubscribeInvalidation用 只清L1
+func (t *TieredCacheDAO) Invalidate(names []string) {
+	t.l1.Delete(names...)
+}
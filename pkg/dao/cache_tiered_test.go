@@ -0,0 +1,114 @@
+package dao
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/localcache"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 只記錄呼叫次數的L2
+type countingCacheDAO struct {
+	CacheDAO
+	originalURLs map[string]string
+	gets         int
+	exists       int
+}
+
+func (c *countingCacheDAO) GetOriginalURL(ctx context.Context, name string) (string, *business.Error) {
+	c.gets++
+	originalURL, ok := c.originalURLs[name]
+	if !ok {
+		return "", business.NewError(business.NotFound, http.StatusNotFound, "record not found", nil)
+	}
+	return originalURL, nil
+}
+
+func (c *countingCacheDAO) SetOriginalURL(ctx context.Context, name string, originalURL string) *business.Error {
+	c.originalURLs[name] = originalURL
+	return nil
+}
+
+func (c *countingCacheDAO) SetOriginalURLNotFound(ctx context.Context, name string) *business.Error {
+	delete(c.originalURLs, name)
+	return nil
+}
+
+func (c *countingCacheDAO) DeleteOriginalURL(ctx context.Context, name string) *business.Error {
+	delete(c.originalURLs, name)
+	return nil
+}
+
+func (c *countingCacheDAO) ExistOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	c.exists++
+	_, ok := c.originalURLs[originalURLID]
+	return ok, nil
+}
+
+var _ = Describe("TieredCacheDAO", func() {
+	ctx := context.Background()
+	var l2 *countingCacheDAO
+	var tieredCacheDAO *TieredCacheDAO
+	var hits, misses float64
+
+	BeforeEach(func() {
+		l2 = &countingCacheDAO{originalURLs: map[string]string{"abcdef": "http://example.com"}}
+		tieredCacheDAO = NewTieredCacheDAO(localcache.New(10, time.Minute), l2)
+		hits = testutil.ToFloat64(metrics.OriginalURLL1CacheHits)
+		misses = testutil.ToFloat64(metrics.OriginalURLL1CacheMisses)
+	})
+
+	It("serve from L1 after first get", func() {
+		for i := 0; i < 3; i++ {
+			originalURL, err := tieredCacheDAO.GetOriginalURL(ctx, "abcdef")
+			Expect(err).To(BeNil())
+			Expect(originalURL).To(Equal("http://example.com"))
+		}
+		Expect(l2.gets).To(Equal(1))
+		Expect(testutil.ToFloat64(metrics.OriginalURLL1CacheMisses)).To(Equal(misses + 1))
+		Expect(testutil.ToFloat64(metrics.OriginalURLL1CacheHits)).To(Equal(hits + 2))
+
+		exist, err := tieredCacheDAO.ExistOriginalURLIDInFilters(ctx, "abcdef")
+		Expect(err).To(BeNil())
+		Expect(exist).To(BeTrue())
+		Expect(l2.exists).To(Equal(0))
+	})
+
+	It("do not cache L2 miss", func() {
+		_, err := tieredCacheDAO.GetOriginalURL(ctx, "zzzzzz")
+		Expect(err).NotTo(BeNil())
+		_, err = tieredCacheDAO.GetOriginalURL(ctx, "zzzzzz")
+		Expect(err).NotTo(BeNil())
+		Expect(l2.gets).To(Equal(2))
+	})
+
+	It("fall through after delete or invalidate", func() {
+		Expect(tieredCacheDAO.SetOriginalURL(ctx, "ghijkl", "http://example.com/g")).To(BeNil())
+		Expect(tieredCacheDAO.GetOriginalURL(ctx, "ghijkl")).To(Equal("http://example.com/g"))
+		Expect(l2.gets).To(Equal(0))
+
+		Expect(tieredCacheDAO.DeleteOriginalURL(ctx, "ghijkl")).To(BeNil())
+		_, err := tieredCacheDAO.GetOriginalURL(ctx, "ghijkl")
+		Expect(err).NotTo(BeNil())
+
+		tieredCacheDAO.GetOriginalURL(ctx, "abcdef")
+		tieredCacheDAO.Invalidate([]string{"abcdef"})
+		tieredCacheDAO.GetOriginalURL(ctx, "abcdef")
+		Expect(l2.gets).To(Equal(3))
+	})
+
+	It("drop L1 entry when set not found", func() {
+		Expect(tieredCacheDAO.GetOriginalURL(ctx, "abcdef")).To(Equal("http://example.com"))
+		Expect(tieredCacheDAO.SetOriginalURLNotFound(ctx, "abcdef")).To(BeNil())
+		_, err := tieredCacheDAO.GetOriginalURL(ctx, "abcdef")
+		Expect(err).NotTo(BeNil())
+		Expect(l2.gets).To(Equal(2))
+	})
+})
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// NewSQLiteClient 開啟path的sqlite檔案 不存在就建立
+// 每個connection都打開foreign key(url_health_checks的ON DELETE CASCADE需要) 用WAL讓讀不會被寫擋住
+// transaction一律BEGIN IMMEDIATE 一開始就拿write lock 同時寫入的人排隊等busy timeout 不會讀到一半才發現要重來
+func NewSQLiteClient(path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// MigrateSQLite 跑sourceURL底下sqlite的migration 已經是最新的不算錯誤
+func MigrateSQLite(db *sql.DB, sourceURL string) error {
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{MigrationsTable: "migrate_version"})
+	if err != nil {
+		return err
+	}
+	// 不呼叫Close 會把db一起關掉
+	migration, err := migrate.NewWithDatabaseInstance(sourceURL, "sqlite3", driver)
+	if err != nil {
+		return err
+	}
+	if err := migration.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+	return nil
+}
+
+// runInSQLiteTx fn回傳error就rollback
+func runInSQLiteTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqlite沒有timestamp型別 時間存成unix microsecond 精度跟postgres一樣 也可以直接比大小
+func toSQLiteTime(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+func fromSQLiteTime(us int64) time.Time {
+	return time.Unix(0, us*int64(time.Microsecond)).UTC()
+}
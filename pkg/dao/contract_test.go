@@ -0,0 +1,480 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/randstr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// storageDAOs 同一個storage的DAO 共用同一份資料
+type storageDAOs struct {
+	urlDAO         UrlDAO
+	keyDAO         KeyDAO
+	healthCheckDAO HealthCheckDAO
+}
+
+// describeStorage 每個storage實作都要通過的共用測試
+// newStorage回傳空的storage recycleCooldown是url刪除之後id的冷卻期 cleanup在每個測試後清掉寫入的資料
+func describeStorage(name string, newStorage func(recycleCooldown time.Duration) storageDAOs, cleanup func()) {
+	Describe(name, func() {
+		ctx := context.Background()
+		var s storageDAOs
+		// 時間只到millisecond postgres存得下
+		now := time.Now().UTC().Truncate(time.Millisecond)
+
+		BeforeEach(func() {
+			s = newStorage(0)
+		})
+
+		AfterEach(func() {
+			cleanup()
+		})
+
+		ids := func(urls []URL) []string {
+			result := make([]string, 0, len(urls))
+			for _, u := range urls {
+				result = append(result, u.ID)
+			}
+			return result
+		}
+
+		mustImport := func(urls ...URL) {
+			results, err := s.urlDAO.Import(ctx, urls)
+			Expect(err).To(BeNil())
+			for _, result := range results {
+				Expect(result.Preserved()).To(BeTrue())
+			}
+		}
+
+		expectBusinessError := func(err *business.Error, businessCode int, httpStatusCode int) {
+			Expect(err).NotTo(BeNil())
+			Expect(err.BusinessCode).To(Equal(businessCode))
+			Expect(err.HTTPStatusCode).To(Equal(httpStatusCode))
+		}
+
+		Describe("KeyDAO", func() {
+			It("batch create unused keys", func() {
+				inserted, err := s.keyDAO.BatchCreate(ctx, 10)
+				Expect(err).To(BeNil())
+				Expect(inserted).To(BeNumerically(">", 0))
+				Expect(s.keyDAO.Count(ctx)).To(Equal(inserted))
+			})
+
+			It("return skips used ids", func() {
+				mustImport(URL{ID: "cccccc", Original: "http://example.com/c"})
+				Expect(s.keyDAO.Return(ctx, []string{"aaaaaa", "bbbbbb", "bbbbbb", "cccccc"})).To(Equal(2))
+				Expect(s.keyDAO.Return(ctx, []string{"aaaaaa"})).To(Equal(0))
+				Expect(s.keyDAO.Return(ctx, nil)).To(Equal(0))
+				Expect(s.keyDAO.Count(ctx)).To(Equal(2))
+			})
+
+			It("lease takes keys out of the pool", func() {
+				Expect(s.keyDAO.Return(ctx, []string{"aaaaaa", "bbbbbb", "cccccc"})).To(Equal(3))
+				leased, err := s.keyDAO.Lease(ctx, 2)
+				Expect(err).To(BeNil())
+				Expect(leased).To(HaveLen(2))
+				Expect(s.keyDAO.Count(ctx)).To(Equal(1))
+
+				rest, err := s.keyDAO.Lease(ctx, 5)
+				Expect(err).To(BeNil())
+				Expect(append(leased, rest...)).To(ConsistOf("aaaaaa", "bbbbbb", "cccccc"))
+				Expect(s.keyDAO.Lease(ctx, 5)).To(BeEmpty())
+			})
+		})
+
+		Describe("UrlDAO", func() {
+			It("create with a key from the pool", func() {
+				Expect(s.keyDAO.Return(ctx, []string{"aaaaaa"})).To(Equal(1))
+				url, err := s.urlDAO.Create(ctx, "http://example.com")
+				Expect(err).To(BeNil())
+				Expect(url.ID).To(Equal("aaaaaa"))
+				Expect(url.ExpiredAt).To(BeTemporally(">", url.CreatedAt))
+				Expect(s.keyDAO.Count(ctx)).To(Equal(0))
+
+				got, err := s.urlDAO.Get(ctx, "aaaaaa")
+				Expect(err).To(BeNil())
+				Expect(got.Original).To(Equal("http://example.com"))
+
+				_, err = s.urlDAO.Create(ctx, "http://example.com")
+				expectBusinessError(err, business.CapacityExhausted, http.StatusServiceUnavailable)
+			})
+
+			It("never hand out the same key to concurrent creates", func() {
+				keys := []string{"aaaaaa", "bbbbbb", "cccccc", "dddddd", "eeeeee", "ffffff", "gggggg", "hhhhhh"}
+				Expect(s.keyDAO.Return(ctx, keys)).To(Equal(len(keys)))
+
+				var wg sync.WaitGroup
+				var mu sync.Mutex
+				var created []string
+				exhausted := 0
+				for i := 0; i < 2*len(keys); i++ {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						url, err := s.urlDAO.Create(ctx, "http://example.com")
+						mu.Lock()
+						defer mu.Unlock()
+						if err != nil {
+							expectBusinessError(err, business.CapacityExhausted, http.StatusServiceUnavailable)
+							exhausted++
+							return
+						}
+						created = append(created, url.ID)
+					}()
+				}
+				wg.Wait()
+				Expect(created).To(ConsistOf(keys))
+				Expect(exhausted).To(Equal(len(keys)))
+			})
+
+			It("get only active urls and find expired ones", func() {
+				mustImport(URL{ID: "aaaaaa", Original: "http://example.com/a", CreatedAt: now.Add(-time.Hour), ExpiredAt: now.Add(-time.Minute)})
+
+				_, err := s.urlDAO.Get(ctx, "aaaaaa")
+				expectBusinessError(err, business.NotFound, http.StatusNotFound)
+				url, err := s.urlDAO.Find(ctx, "aaaaaa")
+				Expect(err).To(BeNil())
+				Expect(url.Original).To(Equal("http://example.com/a"))
+
+				_, err = s.urlDAO.Find(ctx, "bbbbbb")
+				expectBusinessError(err, business.NotFound, http.StatusNotFound)
+			})
+
+			It("force expire active urls only", func() {
+				mustImport(URL{ID: "aaaaaa", Original: "http://example.com/a"})
+				Expect(s.urlDAO.ForceExpire(ctx, "aaaaaa")).To(BeNil())
+				_, err := s.urlDAO.Get(ctx, "aaaaaa")
+				expectBusinessError(err, business.NotFound, http.StatusNotFound)
+				expectBusinessError(s.urlDAO.ForceExpire(ctx, "aaaaaa"), business.NotFound, http.StatusNotFound)
+			})
+
+			It("import keeps free ids and reassigns used ones", func() {
+				Expect(s.keyDAO.Return(ctx, []string{"bbbbbb", "kkkkkk"})).To(Equal(2))
+				// bbbbbb還在keys裡 一樣可以沿用
+				results, err := s.urlDAO.Import(ctx, []URL{
+					{ID: "bbbbbb", Original: "http://example.com/b"},
+					{ID: "aaaaaa", Original: "http://example.com/a"},
+					{ID: "aaaaaa", Original: "http://example.com/a2"},
+				})
+				Expect(err).To(BeNil())
+				Expect(results).To(HaveLen(3))
+				Expect(results[0].ID).To(Equal("bbbbbb"))
+				Expect(results[1].ID).To(Equal("aaaaaa"))
+				Expect(results[2].OriginalID).To(Equal("aaaaaa"))
+				Expect(results[2].ID).To(Equal("kkkkkk"))
+				Expect(results[2].Preserved()).To(BeFalse())
+				Expect(s.keyDAO.Count(ctx)).To(Equal(0))
+
+				url, err := s.urlDAO.Get(ctx, "kkkkkk")
+				Expect(err).To(BeNil())
+				Expect(url.Original).To(Equal("http://example.com/a2"))
+			})
+
+			It("import nothing when running out of keys", func() {
+				Expect(s.keyDAO.Return(ctx, []string{"kkkkkk"})).To(Equal(1))
+				_, err := s.urlDAO.Import(ctx, []URL{
+					{ID: "aaaaaa", Original: "http://example.com/a"},
+					{ID: "invalid!", Original: "http://example.com/b"},
+					{ID: "invalid?", Original: "http://example.com/c"},
+				})
+				expectBusinessError(err, business.CapacityExhausted, http.StatusServiceUnavailable)
+
+				_, err = s.urlDAO.Find(ctx, "aaaaaa")
+				expectBusinessError(err, business.NotFound, http.StatusNotFound)
+				_, err = s.urlDAO.Find(ctx, "kkkkkk")
+				expectBusinessError(err, business.NotFound, http.StatusNotFound)
+				Expect(s.keyDAO.Count(ctx)).To(Equal(1))
+			})
+
+			It("delete and expire without recycling", func() {
+				mustImport(
+					URL{ID: "aaaaaa", Original: "http://example.com/a"},
+					URL{ID: "bbbbbb", Original: "http://example.com/b", CreatedAt: now.Add(-time.Hour), ExpiredAt: now.Add(-time.Minute)},
+					URL{ID: "cccccc", Original: "http://example.com/c", CreatedAt: now.Add(-time.Hour), ExpiredAt: now.Add(-time.Minute)},
+				)
+				Expect(s.urlDAO.Delete(ctx, "aaaaaa")).To(BeNil())
+				Expect(s.urlDAO.Delete(ctx, "aaaaaa")).To(BeNil())
+				_, err := s.urlDAO.Find(ctx, "aaaaaa")
+				expectBusinessError(err, business.NotFound, http.StatusNotFound)
+
+				expired, err := s.urlDAO.Expire(ctx, 1)
+				Expect(err).To(BeNil())
+				Expect(expired).To(HaveLen(1))
+				rest, err := s.urlDAO.Expire(ctx, 10)
+				Expect(err).To(BeNil())
+				Expect(append(expired, rest...)).To(ConsistOf("bbbbbb", "cccccc"))
+				Expect(s.keyDAO.CountQuarantined(ctx)).To(Equal(0))
+				// 沒有冷卻 馬上可以放回keys
+				Expect(s.keyDAO.Return(ctx, []string{"aaaaaa", "bbbbbb"})).To(Equal(2))
+			})
+
+			Context("with recycling", func() {
+				It("quarantine ids until cooldown passes", func() {
+					s = newStorage(time.Hour)
+					mustImport(URL{ID: "aaaaaa", Original: "http://example.com/a"})
+					Expect(s.urlDAO.Delete(ctx, "aaaaaa")).To(BeNil())
+					Expect(s.keyDAO.CountQuarantined(ctx)).To(Equal(1))
+					Expect(s.keyDAO.Return(ctx, []string{"aaaaaa"})).To(Equal(0))
+					Expect(s.keyDAO.ReleaseQuarantined(ctx, 10)).To(Equal(0))
+
+					// 冷卻中的id不能被import沿用
+					Expect(s.keyDAO.Return(ctx, []string{"kkkkkk"})).To(Equal(1))
+					results, err := s.urlDAO.Import(ctx, []URL{{ID: "aaaaaa", Original: "http://example.com/a2"}})
+					Expect(err).To(BeNil())
+					Expect(results[0].ID).To(Equal("kkkkkk"))
+				})
+
+				It("release ids after cooldown", func() {
+					s = newStorage(time.Millisecond)
+					mustImport(
+						URL{ID: "aaaaaa", Original: "http://example.com/a"},
+						URL{ID: "bbbbbb", Original: "http://example.com/b", CreatedAt: now.Add(-time.Hour), ExpiredAt: now.Add(-time.Minute)},
+					)
+					Expect(s.urlDAO.Delete(ctx, "aaaaaa")).To(BeNil())
+					Expect(s.urlDAO.Expire(ctx, 10)).To(Equal([]string{"bbbbbb"}))
+					Expect(s.keyDAO.CountQuarantined(ctx)).To(Equal(2))
+
+					time.Sleep(10 * time.Millisecond)
+					Expect(s.keyDAO.ReleaseQuarantined(ctx, 1)).To(Equal(1))
+					Expect(s.keyDAO.ReleaseQuarantined(ctx, 10)).To(Equal(1))
+					Expect(s.keyDAO.CountQuarantined(ctx)).To(Equal(0))
+					leased, err := s.keyDAO.Lease(ctx, 10)
+					Expect(err).To(BeNil())
+					Expect(leased).To(ConsistOf("aaaaaa", "bbbbbb"))
+				})
+			})
+
+			Context("list", func() {
+				BeforeEach(func() {
+					mustImport(
+						URL{ID: "aaaaaa", Original: "http://example.com/a", CreatedAt: now.Add(-3 * time.Minute), ExpiredAt: now.Add(time.Hour)},
+						URL{ID: "bbbbbb", Original: "http://example.com/b", CreatedAt: now.Add(-2 * time.Minute), ExpiredAt: now.Add(-time.Minute)},
+						URL{ID: "cccccc", Original: "http://example.com/c", CreatedAt: now.Add(-time.Minute), ExpiredAt: now.Add(time.Hour)},
+						URL{ID: "dddddd", Original: "http://example.com/d", CreatedAt: now.Add(-time.Minute), ExpiredAt: now.Add(time.Hour)},
+					)
+				})
+
+				It("page by id", func() {
+					page, err := s.urlDAO.List(ctx, "", 2, true)
+					Expect(err).To(BeNil())
+					Expect(ids(page)).To(Equal([]string{"aaaaaa", "bbbbbb"}))
+					page, err = s.urlDAO.List(ctx, "bbbbbb", 2, true)
+					Expect(err).To(BeNil())
+					Expect(ids(page)).To(Equal([]string{"cccccc", "dddddd"}))
+					Expect(s.urlDAO.List(ctx, "dddddd", 2, true)).To(BeEmpty())
+
+					page, err = s.urlDAO.List(ctx, "", 10, false)
+					Expect(err).To(BeNil())
+					Expect(ids(page)).To(Equal([]string{"aaaaaa", "cccccc", "dddddd"}))
+				})
+
+				It("page active urls from newest", func() {
+					page, err := s.urlDAO.ListRecent(ctx, nil, 2)
+					Expect(err).To(BeNil())
+					Expect(ids(page)).To(Equal([]string{"dddddd", "cccccc"}))
+					page, err = s.urlDAO.ListRecent(ctx, &page[1], 2)
+					Expect(err).To(BeNil())
+					Expect(ids(page)).To(Equal([]string{"aaaaaa"}))
+				})
+			})
+		})
+
+		Describe("HealthCheckDAO", func() {
+			BeforeEach(func() {
+				mustImport(URL{ID: "aaaaaa", Original: "http://example.com/a"}, URL{ID: "bbbbbb", Original: "http://example.com/b"})
+			})
+
+			record := func(id string, failures int, checkedAt time.Time) *HealthCheck {
+				check, err := s.healthCheckDAO.Record(ctx, &HealthCheck{URLID: id, ConsecutiveFailures: failures, CheckedAt: checkedAt}, 2)
+				Expect(err).To(BeNil())
+				return check
+			}
+
+			It("accumulate failures until broken and reset on success", func() {
+				check := record("aaaaaa", 1, now)
+				Expect(check.ConsecutiveFailures).To(Equal(1))
+				Expect(check.Broken).To(BeFalse())
+				check = record("aaaaaa", 1, now)
+				Expect(check.ConsecutiveFailures).To(Equal(2))
+				Expect(check.Broken).To(BeTrue())
+
+				got, err := s.healthCheckDAO.Get(ctx, "aaaaaa")
+				Expect(err).To(BeNil())
+				Expect(got.ConsecutiveFailures).To(Equal(2))
+				Expect(got.Broken).To(BeTrue())
+
+				check = record("aaaaaa", 0, now)
+				Expect(check.ConsecutiveFailures).To(Equal(0))
+				Expect(check.Broken).To(BeFalse())
+			})
+
+			It("list broken from last checked", func() {
+				record("aaaaaa", 2, now.Add(-time.Minute))
+				record("bbbbbb", 2, now)
+				broken, err := s.healthCheckDAO.ListBroken(ctx, 10)
+				Expect(err).To(BeNil())
+				Expect(broken).To(HaveLen(2))
+				Expect(broken[0].URLID).To(Equal("bbbbbb"))
+				Expect(broken[1].URLID).To(Equal("aaaaaa"))
+			})
+
+			It("drop health check with the url", func() {
+				record("aaaaaa", 1, now)
+				Expect(s.urlDAO.Delete(ctx, "aaaaaa")).To(BeNil())
+				_, err := s.healthCheckDAO.Get(ctx, "aaaaaa")
+				expectBusinessError(err, business.NotFound, http.StatusNotFound)
+			})
+		})
+	})
+}
+
+// describeCache 每個CacheDAO實作都要通過的共用測試 newCacheDAO回傳的notFoundTTL要大於0
+func describeCache(name string, newCacheDAO func() CacheDAO, cleanup func(names []string)) {
+	Describe(name, func() {
+		ctx := context.Background()
+		var cacheDAO CacheDAO
+		names := []string{"aaaaaa", "bbbbbb", "cccccc"}
+
+		BeforeEach(func() {
+			cacheDAO = newCacheDAO()
+		})
+
+		AfterEach(func() {
+			cleanup(names)
+		})
+
+		It("miss with key not exist", func() {
+			_, err := cacheDAO.GetOriginalURL(ctx, "aaaaaa")
+			Expect(err).NotTo(BeNil())
+			Expect(err.BusinessCode).To(Equal(business.NotFound))
+			Expect(err.Reason).To(Equal(RedisErrKeyNotExist))
+		})
+
+		It("get what was set and deleted", func() {
+			Expect(cacheDAO.SetOriginalURL(ctx, "aaaaaa", "http://example.com/a")).To(BeNil())
+			Expect(cacheDAO.SetOriginalURL(ctx, "bbbbbb", "http://example.com/b")).To(BeNil())
+			Expect(cacheDAO.GetOriginalURL(ctx, "aaaaaa")).To(Equal("http://example.com/a"))
+
+			Expect(cacheDAO.DeleteOriginalURL(ctx, "aaaaaa")).To(BeNil())
+			_, err := cacheDAO.GetOriginalURL(ctx, "aaaaaa")
+			Expect(err.Reason).To(Equal(RedisErrKeyNotExist))
+			Expect(cacheDAO.DeleteMultiOriginalURL(ctx, []string{"bbbbbb", "cccccc"})).To(BeNil())
+			_, err = cacheDAO.GetOriginalURL(ctx, "bbbbbb")
+			Expect(err.Reason).To(Equal(RedisErrKeyNotExist))
+		})
+
+		It("set multi without overwriting", func() {
+			Expect(cacheDAO.SetOriginalURL(ctx, "aaaaaa", "http://example.com/a")).To(BeNil())
+			Expect(cacheDAO.SetMultiOriginalURL(ctx, map[string]string{"aaaaaa": "http://example.com/other", "bbbbbb": "http://example.com/b"})).To(BeNil())
+			Expect(cacheDAO.GetOriginalURL(ctx, "aaaaaa")).To(Equal("http://example.com/a"))
+			Expect(cacheDAO.GetOriginalURL(ctx, "bbbbbb")).To(Equal("http://example.com/b"))
+		})
+
+		It("remember not found until set", func() {
+			Expect(cacheDAO.SetOriginalURLNotFound(ctx, "aaaaaa")).To(BeNil())
+			_, err := cacheDAO.GetOriginalURL(ctx, "aaaaaa")
+			Expect(err.BusinessCode).To(Equal(business.NotFound))
+			Expect(err.Reason).To(Equal(RedisErrCachedNotFound))
+
+			Expect(cacheDAO.SetOriginalURL(ctx, "aaaaaa", "http://example.com/a")).To(BeNil())
+			Expect(cacheDAO.GetOriginalURL(ctx, "aaaaaa")).To(Equal("http://example.com/a"))
+		})
+
+		It("set multi overwrites not found", func() {
+			Expect(cacheDAO.SetOriginalURLNotFound(ctx, "aaaaaa")).To(BeNil())
+			Expect(cacheDAO.SetMultiOriginalURL(ctx, map[string]string{"aaaaaa": "http://example.com/a"})).To(BeNil())
+			Expect(cacheDAO.GetOriginalURL(ctx, "aaaaaa")).To(Equal("http://example.com/a"))
+		})
+
+		It("delete not found only", func() {
+			Expect(cacheDAO.SetOriginalURL(ctx, "aaaaaa", "http://example.com/a")).To(BeNil())
+			Expect(cacheDAO.SetOriginalURLNotFound(ctx, "bbbbbb")).To(BeNil())
+			Expect(cacheDAO.DeleteOriginalURLNotFound(ctx, names)).To(BeNil())
+			Expect(cacheDAO.GetOriginalURL(ctx, "aaaaaa")).To(Equal("http://example.com/a"))
+			_, err := cacheDAO.GetOriginalURL(ctx, "bbbbbb")
+			Expect(err.Reason).To(Equal(RedisErrKeyNotExist))
+		})
+
+		It("filter ids", func() {
+			Expect(cacheDAO.AddOriginalURLIDInFilters(ctx, "aaaaaa")).To(BeNil())
+			Expect(cacheDAO.AddMultiOriginalURLIDInFilters(ctx, []string{"aaaaaa", "bbbbbb"})).To(Equal(1))
+			Expect(cacheDAO.ExistOriginalURLIDInFilters(ctx, "bbbbbb")).To(BeTrue())
+
+			Expect(cacheDAO.DeleteOriginalURLIDInFilters(ctx, "aaaaaa")).To(BeTrue())
+			Expect(cacheDAO.ExistOriginalURLIDInFilters(ctx, "aaaaaa")).To(BeFalse())
+			Expect(cacheDAO.DeleteMultiOriginalURLIDInFilters(ctx, []string{"bbbbbb", "cccccc"})).To(BeFalse())
+			Expect(cacheDAO.ExistOriginalURLIDInFilters(ctx, "bbbbbb")).To(BeFalse())
+		})
+	})
+}
+
+var _ = Describe("Storage", func() {
+	generator := randstr.NewFastGenerator(randstr.CharSetEnglishAlphabet)
+
+	describeStorage("memory", func(recycleCooldown time.Duration) storageDAOs {
+		store := NewMemoryStore()
+		return storageDAOs{
+			urlDAO:         NewMemoryUrlDAO(store, recycleCooldown),
+			keyDAO:         NewMemoryKeyDAO(store, generator, 6),
+			healthCheckDAO: NewMemoryHealthCheckDAO(store),
+		}
+	}, func() {})
+
+	truncatePG := func() {
+		_, err := testPGClient.Exec("TRUNCATE urls, keys, quarantined_keys, url_health_checks")
+		Expect(err).To(BeNil())
+	}
+	describeStorage("postgres", func(recycleCooldown time.Duration) storageDAOs {
+		requirePG()
+		truncatePG()
+		logger := loglib.NewNopLogger()
+		return storageDAOs{
+			urlDAO:         NewPGUrlDAO(logger, testPGClient, recycleCooldown, NewPoolKeyStrategy()),
+			keyDAO:         NewPGKeyDAO(logger, testPGClient, generator, 6),
+			healthCheckDAO: NewPGHealthCheckDAO(logger, testPGClient),
+		}
+	}, truncatePG)
+
+	truncateSQLite := func() {
+		for _, table := range []string{"url_health_checks", "urls", "keys", "quarantined_keys"} {
+			_, err := testSQLiteDB.Exec("DELETE FROM " + table)
+			Expect(err).To(BeNil())
+		}
+	}
+	describeStorage("sqlite", func(recycleCooldown time.Duration) storageDAOs {
+		truncateSQLite()
+		logger := loglib.NewNopLogger()
+		return storageDAOs{
+			urlDAO:         NewSQLiteUrlDAO(logger, testSQLiteDB, recycleCooldown, nil),
+			keyDAO:         NewSQLiteKeyDAO(logger, testSQLiteDB, generator, 6),
+			healthCheckDAO: NewSQLiteHealthCheckDAO(logger, testSQLiteDB),
+		}
+	}, truncateSQLite)
+})
+
+var _ = Describe("Cache", func() {
+	describeCache("memory", func() CacheDAO {
+		return NewMemoryCacheDAO(time.Minute, NewMemoryFilter())
+	}, func(names []string) {})
+
+	describeCache("redis", func() CacheDAO {
+		requireRedis()
+		return NewRedisCacheDAO(loglib.NewNopLogger(), testRedisClient, time.Minute, NewRedisCuckooFilter(loglib.NewNopLogger(), testRedisClient, CuckooFilterConfig{}))
+	}, func(names []string) {
+		keys := []string{originalURLIDsFilterName}
+		for _, name := range names {
+			keys = append(keys, fmt.Sprintf("%s-%s", prefixHotOriginalURL, name))
+		}
+		Expect(testRedisClient.Del(context.Background(), keys...).Err()).To(BeNil())
+	})
+})
@@ -11,14 +11,21 @@ import (
 const (
 	PGErrMsgNoRowsFound      = "pg: no rows in result set"
 	PGErrMsgNoMultiRowsFound = "pg: multiple rows in result set"
+	PGErrMsgNoKeyAvailable   = "pg: no key available in keys table"
 )
 
 func pgErrorHandle(logger *loglib.Logger, err error) *business.Error {
+	if contextErr := business.NewContextError(err); contextErr != nil {
+		logger.Warn("postgres canceled", zap.Error(err))
+		return contextErr
+	}
 	switch err.Error() {
 	case PGErrMsgNoRowsFound:
 		return business.NewError(business.NotFound, http.StatusNotFound, "record not found", err)
 	case PGErrMsgNoMultiRowsFound:
 		return business.NewError(business.NotFound, http.StatusNotFound, "multi record not found", err)
+	case PGErrMsgNoKeyAvailable:
+		return business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", err)
 	default:
 		logger.Error("postgres internal error", zap.Error(err))
 		return business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", err)
@@ -41,6 +41,18 @@ var _ = Describe("pgErrorHandle", func() {
 		})
 	})
 
+	Context("when err == PGErrMsgNoKeyAvailable", func() {
+		BeforeEach(func() {
+			originalError = errors.New(PGErrMsgNoKeyAvailable)
+		})
+		AfterEach(func() {
+			originalError = nil
+		})
+		It("result", func() {
+			Expect(businessError).To(Equal(business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", originalError)))
+		})
+	})
+
 	Context("internal error", func() {
 		internalError := errors.New("internal error")
 		BeforeEach(func() {
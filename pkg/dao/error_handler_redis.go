@@ -12,9 +12,15 @@ import (
 
 var (
 	RedisErrKeyNotExist = errors.New("redis key not exist")
+	// cache裡記錄著這個id不存在 不需要再查database
+	RedisErrCachedNotFound = errors.New("redis cached not found")
 )
 
 func redisErrorHandle(logger *loglib.Logger, err error) *business.Error {
+	if contextErr := business.NewContextError(err); contextErr != nil {
+		logger.Warn("redis canceled", zap.Error(err))
+		return contextErr
+	}
 	switch {
 	case err == redis.Nil:
 		return business.NewError(business.NotFound, http.StatusNotFound, "record not found", RedisErrKeyNotExist)
@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"go.uber.org/zap"
+)
+
+const SQLiteErrMsgNoKeyAvailable = "sqlite: no key available in keys table"
+
+func sqliteErrorHandle(logger *loglib.Logger, err error) *business.Error {
+	if contextErr := business.NewContextError(err); contextErr != nil {
+		logger.Warn("sqlite canceled", zap.Error(err))
+		return contextErr
+	}
+	if err == sql.ErrNoRows {
+		return business.NewError(business.NotFound, http.StatusNotFound, "record not found", err)
+	}
+	if err.Error() == SQLiteErrMsgNoKeyAvailable {
+		return business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", err)
+	}
+	logger.Error("sqlite internal error", zap.Error(err))
+	return business.NewError(business.SQLiteInternalError, http.StatusInternalServerError, "internal error", err)
+}
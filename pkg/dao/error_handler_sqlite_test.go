@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sqliteErrorHandle", func() {
+	var originalError error
+	var businessError *business.Error
+	JustBeforeEach(func() {
+		businessError = sqliteErrorHandle(loglib.NewNopLogger(), originalError)
+	})
+
+	Context("when err == sql.ErrNoRows", func() {
+		BeforeEach(func() {
+			originalError = sql.ErrNoRows
+		})
+		AfterEach(func() {
+			originalError = nil
+		})
+		It("result", func() {
+			Expect(businessError).To(Equal(business.NewError(business.NotFound, http.StatusNotFound, "record not found", originalError)))
+		})
+	})
+
+	Context("when err == SQLiteErrMsgNoKeyAvailable", func() {
+		BeforeEach(func() {
+			originalError = errors.New(SQLiteErrMsgNoKeyAvailable)
+		})
+		AfterEach(func() {
+			originalError = nil
+		})
+		It("result", func() {
+			Expect(businessError).To(Equal(business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", originalError)))
+		})
+	})
+
+	Context("when context deadline exceeded", func() {
+		BeforeEach(func() {
+			originalError = context.DeadlineExceeded
+		})
+		AfterEach(func() {
+			originalError = nil
+		})
+		It("result", func() {
+			Expect(businessError.BusinessCode).To(Equal(business.DeadlineExceeded))
+		})
+	})
+
+	Context("internal error", func() {
+		internalError := errors.New("internal error")
+		BeforeEach(func() {
+			originalError = internalError
+		})
+		AfterEach(func() {
+			originalError = nil
+		})
+		It("result", func() {
+			Expect(businessError).To(Equal(business.NewError(business.SQLiteInternalError, http.StatusInternalServerError, "internal error", internalError)))
+		})
+	})
+})
@@ -0,0 +1,120 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/redislib"
+	"go.uber.org/zap"
+)
+
+const (
+	// FilterBackendAuto redis有RedisBloom module就用cuckoo filter 沒有就用process內的filter
+	FilterBackendAuto = "auto"
+	// FilterBackendRedis RedisBloom的cuckoo filter 所有instance共用
+	FilterBackendRedis = "redis"
+	// FilterBackendLocal 每個instance自己一份counting bloom filter 透過redis pub/sub同步
+	FilterBackendLocal = "local"
+)
+
+// OriginalURLIDFilter 記錄哪些url id存在 Exist回傳false代表一定不存在 true則可能是false positive
+type OriginalURLIDFilter interface {
+	Add(ctx context.Context, id string) *business.Error
+	Exist(ctx context.Context, id string) (bool, *business.Error)
+	Delete(ctx context.Context, id string) (bool, *business.Error)
+	DeleteMulti(ctx context.Context, ids []string) (bool, *business.Error)
+	// AddMissing 只加入還不在filter裡的id 回傳加了幾個
+	AddMissing(ctx context.Context, ids []string) (int, *business.Error)
+}
+
+// OriginalURLIDFilterRebuilder 可以從頭重建的filter 建好之前舊的filter照常使用
+type OriginalURLIDFilterRebuilder interface {
+	// Rebuild 一直呼叫next拿id直到回傳空的 寫進新的filter後一次換掉目前的
+	Rebuild(ctx context.Context, next func() ([]string, *business.Error)) (FilterRebuildResult, *business.Error)
+}
+
+// FilterRebuildResult Missing是不在舊filter裡的id數量
+// Extra是舊filter裡多出來的數量 filter無法列舉 所以是用元素數量減掉對到的推算 重複加入的也會算進去
+// Replayed是重建期間加進舊filter 換掉之後補進新filter的id數量
+type FilterRebuildResult struct {
+	Scanned  int
+	Missing  int
+	Extra    int
+	Replayed int
+}
+
+// OriginalURLIDFilterInspector 可以查看容量使用狀況的filter
+type OriginalURLIDFilterInspector interface {
+	Info(ctx context.Context) (FilterInfo, *business.Error)
+}
+
+// FilterInfo Capacity是所有sub filter加起來的格子數 ErrorRate是依照目前sub filter數量估計的false positive rate
+type FilterInfo struct {
+	Items      int     `json:"items"`
+	Capacity   int     `json:"capacity"`
+	FillRatio  float64 `json:"fillRatio"`
+	Filters    int     `json:"filters"`
+	BucketSize int     `json:"bucketSize"`
+	Expansion  int     `json:"expansion"`
+	ErrorRate  float64 `json:"errorRate"`
+	SizeBytes  int     `json:"sizeBytes"`
+}
+
+type FilterConfig struct {
+	Backend string
+	// 預計放入的id數量 redis用來CF.RESERVE 0則交給第一次CF.ADD用RedisBloom的預設值建立
+	Capacity int
+	// 只有local用到 可以接受的false positive rate 以及多久從urls重建一次 0則只在subscribe時重建
+	ErrorRate      float64
+	ReloadInterval time.Duration
+	// 只有redis用到 每個bucket放幾個fingerprint 以及滿了之後新的sub filter是上一個的幾倍大
+	BucketSize int
+	Expansion  int
+}
+
+func FilterBackends() []string {
+	return []string{FilterBackendAuto, FilterBackendRedis, FilterBackendLocal}
+}
+
+func NewOriginalURLIDFilter(logger *loglib.Logger, client *redislib.GORedisClient, cfg FilterConfig) (OriginalURLIDFilter, error) {
+	backend := cfg.Backend
+	if backend == FilterBackendAuto {
+		supported, err := SupportRedisBloom(client)
+		if err != nil {
+			return nil, err
+		}
+		backend = FilterBackendLocal
+		if supported {
+			backend = FilterBackendRedis
+		}
+		logger.Info("detect filter backend", zap.String("backend", backend))
+	}
+
+	switch backend {
+	case FilterBackendRedis:
+		filter := NewRedisCuckooFilter(logger, client, CuckooFilterConfig{Capacity: cfg.Capacity, BucketSize: cfg.BucketSize, Expansion: cfg.Expansion})
+		if err := filter.Reserve(context.Background()); err != nil {
+			return nil, err
+		}
+		return filter, nil
+	case FilterBackendLocal:
+		return NewLocalFilter(logger, client, cfg.Capacity, cfg.ErrorRate, cfg.ReloadInterval), nil
+	default:
+		return nil, fmt.Errorf("unsupported filter backend %q, should be one of %v", cfg.Backend, FilterBackends())
+	}
+}
+
+// SupportRedisBloom 直接試一次CF.EXISTS 很多managed redis不允許MODULE LIST
+func SupportRedisBloom(client *redislib.GORedisClient) (bool, error) {
+	err := client.Do(context.Background(), "CF.EXISTS", originalURLIDsFilterName, "").Err()
+	if err == nil {
+		return true, nil
+	}
+	if isUnknownCommandErr(err) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,251 @@
+package dao
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/bloom"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/redislib"
+	"go.uber.org/zap"
+)
+
+const (
+	localFilterOpAdd    = "add"
+	localFilterOpDelete = "del"
+)
+
+// 從urls載入filter時每次讀幾筆
+const localFilterLoadPageSize = 1000
+
+func NewLocalFilter(logger *loglib.Logger, client *redislib.GORedisClient, capacity int, errorRate float64, reloadInterval time.Duration) *LocalFilter {
+	return &LocalFilter{
+		logger:         logger,
+		client:         client,
+		capacity:       capacity,
+		errorRate:      errorRate,
+		reloadInterval: reloadInterval,
+		filter:         bloom.New(capacity, errorRate),
+		instanceID:     strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatInt(rand.Int63(), 36),
+	}
+}
+
+// LocalFilter 不需要RedisBloom module 每個instance自己一份counting bloom filter
+// 自己的變動馬上生效 再透過redis pub/sub通知其他instance
+// 還沒從urls載入完之前Exist一律回傳true 讓request照原本的流程去查cache跟database
+// pub/sub斷線期間的訊息會漏掉 所以每次(重新)subscribe以及每reloadInterval都從urls重建一份再換上去
+type LocalFilter struct {
+	logger         *loglib.Logger
+	client         *redislib.GORedisClient
+	capacity       int
+	errorRate      float64
+	reloadInterval time.Duration
+	instanceID     string
+	loaded         int32
+
+	mu     sync.RWMutex
+	filter *bloom.CountingFilter
+	// 重建期間加進來的id 換上新的filter之前要補進去
+	reloading   bool
+	pendingAdds []string
+}
+
+func (l *LocalFilter) Add(ctx context.Context, id string) *business.Error {
+	l.add([]string{id})
+	return l.publish(ctx, localFilterOpAdd, []string{id})
+}
+
+func (l *LocalFilter) Exist(ctx context.Context, id string) (bool, *business.Error) {
+	if atomic.LoadInt32(&l.loaded) == 0 {
+		return true, nil
+	}
+	return l.current().Exist(id), nil
+}
+
+func (l *LocalFilter) Delete(ctx context.Context, id string) (bool, *business.Error) {
+	ok := l.current().Delete(id)
+	return ok, l.publish(ctx, localFilterOpDelete, []string{id})
+}
+
+func (l *LocalFilter) DeleteMulti(ctx context.Context, ids []string) (bool, *business.Error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+	filter := l.current()
+	for _, id := range ids {
+		filter.Delete(id)
+	}
+	return true, l.publish(ctx, localFilterOpDelete, ids)
+}
+
+func (l *LocalFilter) AddMissing(ctx context.Context, ids []string) (int, *business.Error) {
+	var missing []string
+	filter := l.current()
+	for _, id := range ids {
+		if !filter.Exist(id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	l.add(missing)
+	return len(missing), l.publish(ctx, localFilterOpAdd, missing)
+}
+
+// Sync 先subscribe其他instance的變動 再從urls重建filter 之後每reloadInterval重建一次 直到ctx結束才return
+// 斷線後再呼叫一次Sync就會重新subscribe並重建 把斷線期間漏掉的變動補回來
+func (l *LocalFilter) Sync(ctx context.Context, urlDAO UrlDAO) *business.Error {
+	pubsub := l.client.Subscribe(ctx, originalURLIDFilterChannel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return redisErrorHandle(l.logger, err)
+	}
+
+	ch := pubsub.Channel()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				l.apply(msg.Payload)
+			}
+		}
+	}()
+
+	if err := l.reload(ctx, urlDAO); err != nil {
+		return err
+	}
+	if l.reloadInterval <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(l.reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// 重建失敗就先繼續用舊的 下次再試
+			if err := l.reload(ctx, urlDAO); err != nil {
+				l.logger.Error("fail to reload local filter", zap.Error(err))
+			}
+		}
+	}
+}
+
+// reload 把urls裡還在的id(包含已過期但還沒被刪掉的 之後刪掉時會收到del)載入新的filter再換上去
+// 重建期間收到的add會補進新的filter 重複加只會變成false positive
+// 重建期間收到的del不補 因為分不出是在讀到那筆之前還是之後刪的 多刪會變成false negative 留著只是false positive 下次重建就會清掉
+func (l *LocalFilter) reload(ctx context.Context, urlDAO UrlDAO) *business.Error {
+	l.mu.Lock()
+	l.reloading = true
+	l.pendingAdds = nil
+	l.mu.Unlock()
+
+	next, total, err := l.load(ctx, urlDAO)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pendingAdds := l.pendingAdds
+	l.reloading = false
+	l.pendingAdds = nil
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		return nil
+	}
+	for _, id := range pendingAdds {
+		next.Add(id)
+	}
+	l.filter = next
+	atomic.StoreInt32(&l.loaded, 1)
+	l.logger.Info("local filter loaded", zap.Int("ids", total), zap.Int("pendingAdds", len(pendingAdds)))
+	return nil
+}
+
+// load ctx結束的話回傳nil filter
+func (l *LocalFilter) load(ctx context.Context, urlDAO UrlDAO) (*bloom.CountingFilter, int, *business.Error) {
+	next := bloom.New(l.capacity, l.errorRate)
+	afterID := ""
+	total := 0
+	for ctx.Err() == nil {
+		urls, err := urlDAO.List(ctx, afterID, localFilterLoadPageSize, true)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, url := range urls {
+			next.Add(url.ID)
+		}
+		total += len(urls)
+		if len(urls) < localFilterLoadPageSize {
+			return next, total, nil
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+	return nil, 0, nil
+}
+
+func (l *LocalFilter) current() *bloom.CountingFilter {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.filter
+}
+
+func (l *LocalFilter) add(ids []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		l.filter.Add(id)
+	}
+	if l.reloading {
+		l.pendingAdds = append(l.pendingAdds, ids...)
+	}
+}
+
+// payload: instanceID op id1,id2,...
+func (l *LocalFilter) publish(ctx context.Context, op string, ids []string) *business.Error {
+	payload := strings.Join([]string{l.instanceID, op, strings.Join(ids, ",")}, " ")
+	err := l.client.Publish(ctx, originalURLIDFilterChannel, payload).Err()
+	if err != nil {
+		return redisErrorHandle(l.logger, err)
+	}
+	return nil
+}
+
+func (l *LocalFilter) apply(payload string) {
+	parts := strings.SplitN(payload, " ", 3)
+	if len(parts) != 3 {
+		l.logger.Error("invalid filter message", zap.String("payload", payload))
+		return
+	}
+	// 自己發出去的已經生效了
+	if parts[0] == l.instanceID {
+		return
+	}
+	ids := strings.Split(parts[2], ",")
+	switch parts[1] {
+	case localFilterOpAdd:
+		l.add(ids)
+	case localFilterOpDelete:
+		filter := l.current()
+		for _, id := range ids {
+			filter.Delete(id)
+		}
+	}
+}
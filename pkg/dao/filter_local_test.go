@@ -0,0 +1,171 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 只實作List的UrlDAO
+type listUrlDAO struct {
+	UrlDAO
+	ids []string
+	// 已過期但還沒被刪掉的 includeExpired才會列出來
+	expired []string
+	// 每次List前呼叫 模擬載入期間其他instance的變動
+	onList func()
+}
+
+func (l *listUrlDAO) List(ctx context.Context, afterID string, limit int, includeExpired bool) ([]URL, *business.Error) {
+	if l.onList != nil {
+		l.onList()
+	}
+	ids := append([]string{}, l.ids...)
+	if includeExpired {
+		ids = append(ids, l.expired...)
+	}
+	sort.Strings(ids)
+	var urls []URL
+	for _, id := range ids {
+		if id > afterID && len(urls) < limit {
+			urls = append(urls, URL{ID: id})
+		}
+	}
+	return urls, nil
+}
+
+var _ = Describe("LocalFilter", func() {
+	BeforeEach(requireRedis)
+
+	ctx := context.Background()
+	var logger *loglib.Logger
+	var localFilter *LocalFilter
+
+	BeforeEach(func() {
+		logger = loglib.NewNopLogger()
+		localFilter = NewLocalFilter(logger, testRedisClient, 1000, 0.01, 0)
+	})
+
+	mustExist := func(filter *LocalFilter, id string) bool {
+		exist, err := filter.Exist(ctx, id)
+		Expect(err).To(BeNil())
+		return exist
+	}
+
+	It("exist before loaded", func() {
+		Expect(mustExist(localFilter, "abcdef")).To(BeTrue())
+	})
+
+	It("load from urls and sync with other instances", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		other := NewLocalFilter(logger, testRedisClient, 1000, 0.01, 0)
+		ids := make([]string, 0, localFilterLoadPageSize+1)
+		for i := 0; i < localFilterLoadPageSize+1; i++ {
+			ids = append(ids, fmt.Sprintf("%06d", i))
+		}
+		urlDAO := &listUrlDAO{ids: ids}
+		go localFilter.Sync(ctx, urlDAO)
+		go other.Sync(ctx, urlDAO)
+
+		Eventually(func() bool { return mustExist(localFilter, "zzzzzz") }).Should(BeFalse())
+		Eventually(func() bool { return mustExist(other, "zzzzzz") }).Should(BeFalse())
+		for _, id := range ids {
+			Expect(mustExist(localFilter, id)).To(BeTrue())
+		}
+
+		Expect(localFilter.Add(ctx, "zzzzzz")).To(BeNil())
+		Expect(mustExist(localFilter, "zzzzzz")).To(BeTrue())
+		Eventually(func() bool { return mustExist(other, "zzzzzz") }, time.Second).Should(BeTrue())
+
+		Expect(other.DeleteMulti(ctx, []string{"zzzzzz", ids[0]})).To(BeTrue())
+		Eventually(func() bool { return mustExist(localFilter, "zzzzzz") }, time.Second).Should(BeFalse())
+		Expect(mustExist(localFilter, ids[0])).To(BeFalse())
+	})
+
+	It("add missing only", func() {
+		localFilter.Add(ctx, "abcdef")
+		Expect(localFilter.AddMissing(ctx, []string{"abcdef", "ghijkl"})).To(Equal(1))
+		Expect(localFilter.Delete(ctx, "abcdef")).To(BeTrue())
+		Expect(localFilter.filter.Exist("abcdef")).To(BeFalse())
+		Expect(localFilter.filter.Exist("ghijkl")).To(BeTrue())
+	})
+
+	It("ignore own messages", func() {
+		localFilter.Add(ctx, "abcdef")
+		localFilter.apply(localFilter.instanceID + " " + localFilterOpAdd + " abcdef")
+		Expect(localFilter.Delete(ctx, "abcdef")).To(BeTrue())
+		Expect(localFilter.filter.Exist("abcdef")).To(BeFalse())
+	})
+})
+
+var _ = Describe("LocalFilter reload", func() {
+	ctx := context.Background()
+	var localFilter *LocalFilter
+
+	BeforeEach(func() {
+		// reload只讀urls 不需要redis
+		localFilter = NewLocalFilter(loglib.NewNopLogger(), nil, 1000, 0.01, 0)
+	})
+
+	mustExist := func(id string) bool {
+		exist, err := localFilter.Exist(ctx, id)
+		Expect(err).To(BeNil())
+		return exist
+	}
+
+	It("load expired urls still present so later deletes have a matching add", func() {
+		Expect(localFilter.reload(ctx, &listUrlDAO{ids: []string{"aaaaaa"}, expired: []string{"bbbbbb"}})).To(BeNil())
+		Expect(mustExist("aaaaaa")).To(BeTrue())
+		Expect(mustExist("bbbbbb")).To(BeTrue())
+
+		// ExpiredURLJob刪掉之後發出的del
+		localFilter.apply("other " + localFilterOpDelete + " bbbbbb")
+		Expect(mustExist("bbbbbb")).To(BeFalse())
+		Expect(mustExist("aaaaaa")).To(BeTrue())
+	})
+
+	It("reconcile changes missed while unsubscribed", func() {
+		Expect(localFilter.reload(ctx, &listUrlDAO{ids: []string{"aaaaaa", "bbbbbb"}})).To(BeNil())
+		Expect(mustExist("cccccc")).To(BeFalse())
+
+		// 斷線期間bbbbbb被刪掉 cccccc被建立 都沒收到通知
+		Expect(localFilter.reload(ctx, &listUrlDAO{ids: []string{"aaaaaa", "cccccc"}})).To(BeNil())
+		Expect(mustExist("aaaaaa")).To(BeTrue())
+		Expect(mustExist("bbbbbb")).To(BeFalse())
+		Expect(mustExist("cccccc")).To(BeTrue())
+	})
+
+	It("keep adds received while reloading", func() {
+		Expect(localFilter.reload(ctx, &listUrlDAO{ids: []string{"aaaaaa"}})).To(BeNil())
+		urlDAO := &listUrlDAO{ids: []string{"aaaaaa"}}
+		urlDAO.onList = func() {
+			localFilter.apply("other " + localFilterOpAdd + " dddddd")
+		}
+		Expect(localFilter.reload(ctx, urlDAO)).To(BeNil())
+		Expect(mustExist("dddddd")).To(BeTrue())
+		Expect(localFilter.pendingAdds).To(BeEmpty())
+	})
+
+	It("keep the old filter when reload fails", func() {
+		Expect(localFilter.reload(ctx, &listUrlDAO{ids: []string{"aaaaaa"}})).To(BeNil())
+		Expect(localFilter.reload(ctx, &failListUrlDAO{})).NotTo(BeNil())
+		Expect(mustExist("aaaaaa")).To(BeTrue())
+		Expect(mustExist("zzzzzz")).To(BeFalse())
+	})
+})
+
+type failListUrlDAO struct {
+	UrlDAO
+}
+
+func (f *failListUrlDAO) List(ctx context.Context, afterID string, limit int, includeExpired bool) ([]URL, *business.Error) {
+	return nil, business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", nil)
+}
@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"context"
+	"sync"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+)
+
+func NewMemoryFilter() *MemoryFilter {
+	return &MemoryFilter{ids: make(map[string]struct{})}
+}
+
+// MemoryFilter 直接記住每個id 沒有false positive 只有一個instance的memory storage用
+type MemoryFilter struct {
+	mu  sync.RWMutex
+	ids map[string]struct{}
+}
+
+func (m *MemoryFilter) Add(ctx context.Context, id string) *business.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ids[id] = struct{}{}
+	return nil
+}
+
+func (m *MemoryFilter) Exist(ctx context.Context, id string) (bool, *business.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.ids[id]
+	return ok, nil
+}
+
+func (m *MemoryFilter) Delete(ctx context.Context, id string) (bool, *business.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.ids[id]
+	delete(m.ids, id)
+	return ok, nil
+}
+
+// DeleteMulti ok代表每個id都有在filter裡
+func (m *MemoryFilter) DeleteMulti(ctx context.Context, ids []string) (bool, *business.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ok := true
+	for _, id := range ids {
+		_, exist := m.ids[id]
+		ok = ok && exist
+		delete(m.ids, id)
+	}
+	return ok, nil
+}
+
+func (m *MemoryFilter) AddMissing(ctx context.Context, ids []string) (int, *business.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	added := 0
+	for _, id := range ids {
+		if _, ok := m.ids[id]; !ok {
+			m.ids[id] = struct{}{}
+			added++
+		}
+	}
+	return added, nil
+}
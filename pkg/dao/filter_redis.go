@@ -0,0 +1,292 @@
+package dao
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/redislib"
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisBloom的cuckoo filter fingerprint固定8 bits
+const cuckooFingerprintBits = 8
+
+// cron在重建途中掛掉的話 過了這麼久就不再記錄重建期間加入的id
+const rebuildingFilterTTL = 24 * time.Hour
+
+// 補回重建期間加入的id時 一次script處理幾個
+const rebuildReplayBatchSize = 1000
+
+var (
+	// 重建期間加入的id也記下來 重建時已經掃過的範圍才不會漏掉(例如import沿用舊的created_at)
+	addFilterScript = redis.NewScript(`
+local added = 0
+local rebuilding = redis.call("EXISTS", KEYS[2]) == 1
+for i = 2, #ARGV do
+	added = added + redis.call(ARGV[1], KEYS[1], ARGV[i])
+	if rebuilding then
+		redis.call("SADD", KEYS[3], ARGV[i])
+	end
+end
+return added
+`)
+)
+
+func NewRedisCuckooFilter(logger *loglib.Logger, client *redislib.GORedisClient, cfg CuckooFilterConfig) *RedisCuckooFilter {
+	return &RedisCuckooFilter{logger, client, cfg}
+}
+
+// CuckooFilterConfig CF.RESERVE的參數 Capacity是0時不主動建立 BucketSize、Expansion是0時用RedisBloom的預設值
+type CuckooFilterConfig struct {
+	Capacity   int
+	BucketSize int
+	Expansion  int
+}
+
+// RedisCuckooFilter 需要RedisBloom module
+type RedisCuckooFilter struct {
+	logger *loglib.Logger
+	client *redislib.GORedisClient
+	cfg    CuckooFilterConfig
+}
+
+// Reserve filter還不存在時依照設定建立 已經存在的不會改變大小 要換設定只能重建
+func (r *RedisCuckooFilter) Reserve(ctx context.Context) *business.Error {
+	return r.reserve(ctx, originalURLIDsFilterName)
+}
+
+func (r *RedisCuckooFilter) reserve(ctx context.Context, name string) *business.Error {
+	if r.cfg.Capacity <= 0 {
+		return nil
+	}
+	args := []interface{}{"CF.RESERVE", name, r.cfg.Capacity}
+	if r.cfg.BucketSize > 0 {
+		args = append(args, "BUCKETSIZE", r.cfg.BucketSize)
+	}
+	if r.cfg.Expansion > 0 {
+		args = append(args, "EXPANSION", r.cfg.Expansion)
+	}
+	err := r.client.Do(ctx, args...).Err()
+	if err != nil && !isItemExistsErr(err) {
+		return redisErrorHandle(r.logger, err)
+	}
+	return nil
+}
+
+// Info 透過CF.INFO計算 filter還不存在時全部是0
+func (r *RedisCuckooFilter) Info(ctx context.Context) (FilterInfo, *business.Error) {
+	var info FilterInfo
+	exists, err := r.client.Exists(ctx, originalURLIDsFilterName).Result()
+	if err != nil {
+		return info, redisErrorHandle(r.logger, err)
+	}
+	if exists == 0 {
+		return info, nil
+	}
+	reply, err := r.client.Do(ctx, "CF.INFO", originalURLIDsFilterName).Result()
+	if err != nil {
+		return info, redisErrorHandle(r.logger, err)
+	}
+	fields, _ := reply.([]interface{})
+	var buckets, inserted, deleted int64
+	for i := 0; i+1 < len(fields); i += 2 {
+		value, _ := fields[i+1].(int64)
+		switch fields[i] {
+		case "Size":
+			info.SizeBytes = int(value)
+		case "Number of buckets":
+			buckets = value
+		case "Number of filters":
+			info.Filters = int(value)
+		case "Number of items inserted":
+			inserted = value
+		case "Number of items deleted":
+			deleted = value
+		case "Bucket size":
+			info.BucketSize = int(value)
+		case "Expansion rate":
+			info.Expansion = int(value)
+		}
+	}
+	info.Items = int(inserted - deleted)
+
+	// 每次擴充的sub filter是上一個的Expansion倍 expansion是0或1時都一樣大
+	filterBuckets := buckets
+	for i := 0; i < info.Filters; i++ {
+		info.Capacity += int(filterBuckets) * info.BucketSize
+		if info.Expansion > 1 {
+			filterBuckets *= int64(info.Expansion)
+		}
+	}
+	if info.Capacity > 0 {
+		info.FillRatio = float64(info.Items) / float64(info.Capacity)
+	}
+	// 查詢時每個sub filter都要檢查兩個bucket 每個格子撞到fingerprint的機率是1/2^f
+	info.ErrorRate = math.Min(1, float64(info.Filters*2*info.BucketSize)/float64(uint(1)<<cuckooFingerprintBits))
+	return info, nil
+}
+
+func (r *RedisCuckooFilter) Add(ctx context.Context, id string) *business.Error {
+	if _, err := r.add(ctx, "CF.ADD", []string{id}); err != nil {
+		return redisErrorHandle(r.logger, err)
+	}
+	return nil
+}
+
+// add 用command把ids加進filter 回傳command回傳1的數量
+func (r *RedisCuckooFilter) add(ctx context.Context, command string, ids []string) (int, error) {
+	keys := []string{originalURLIDsFilterName, rebuildingOriginalURLIDsFilterName, rebuildAddedOriginalURLIDsName}
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, command)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return addFilterScript.Run(ctx, r.client, keys, args...).Int()
+}
+
+func (r *RedisCuckooFilter) Exist(ctx context.Context, id string) (bool, *business.Error) {
+	ok, err := r.client.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
+	if err != nil {
+		return ok, redisErrorHandle(r.logger, err)
+	}
+	return ok, nil
+}
+
+func (r *RedisCuckooFilter) Delete(ctx context.Context, id string) (bool, *business.Error) {
+	ok, err := r.client.Do(ctx, "CF.DEL", originalURLIDsFilterName, id).Bool()
+	if err != nil {
+		return ok, redisErrorHandle(r.logger, err)
+	}
+	return ok, nil
+}
+
+// DeleteMulti 一個id失敗不會影響其他的 全部刪完才回傳第一個錯誤 ok代表每個id都有在filter裡
+func (r *RedisCuckooFilter) DeleteMulti(ctx context.Context, ids []string) (bool, *business.Error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipe.Do(ctx, "CF.DEL", originalURLIDsFilterName, id))
+	}
+	// pipeline裡每個指令都會執行 Exec只回傳第一個錯誤
+	_, err := pipe.Exec(ctx)
+	ok := true
+	for _, cmd := range cmds {
+		deleted, cmdErr := cmd.Bool()
+		ok = ok && deleted && cmdErr == nil
+	}
+	if err != nil {
+		return ok, redisErrorHandle(r.logger, err)
+	}
+	return ok, nil
+}
+
+// Rebuild 開始掃之前先標記重建中 之後加進舊filter的id會另外記下來 換掉之後再補進新的filter
+func (r *RedisCuckooFilter) Rebuild(ctx context.Context, next func() ([]string, *business.Error)) (FilterRebuildResult, *business.Error) {
+	var result FilterRebuildResult
+	// 上次沒建完的直接丟掉
+	if err := r.client.Del(ctx, rebuildOriginalURLIDsFilterName, rebuildAddedOriginalURLIDsName).Err(); err != nil {
+		return result, redisErrorHandle(r.logger, err)
+	}
+	if err := r.client.Set(ctx, rebuildingOriginalURLIDsFilterName, 1, rebuildingFilterTTL).Err(); err != nil {
+		return result, redisErrorHandle(r.logger, err)
+	}
+	defer r.client.Del(context.Background(), rebuildingOriginalURLIDsFilterName, rebuildAddedOriginalURLIDsName)
+	if err := r.reserve(ctx, rebuildOriginalURLIDsFilterName); err != nil {
+		return result, err
+	}
+
+	for {
+		ids, bErr := next()
+		if bErr != nil {
+			return result, bErr
+		}
+		if len(ids) == 0 {
+			break
+		}
+		pipe := r.client.Pipeline()
+		exists := make([]*redis.Cmd, 0, len(ids))
+		for _, id := range ids {
+			exists = append(exists, pipe.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id))
+			pipe.Do(ctx, "CF.ADD", rebuildOriginalURLIDsFilterName, id)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return result, redisErrorHandle(r.logger, err)
+		}
+		for _, cmd := range exists {
+			if ok, _ := cmd.Bool(); !ok {
+				result.Missing++
+			}
+		}
+		result.Scanned += len(ids)
+	}
+
+	info, bErr := r.Info(ctx)
+	if bErr != nil {
+		return result, bErr
+	}
+	if extra := info.Items - (result.Scanned - result.Missing); extra > 0 {
+		result.Extra = extra
+	}
+
+	// 沒有設定capacity又沒有任何id時 新的filter不會被建立
+	rebuilt, err := r.client.Exists(ctx, rebuildOriginalURLIDsFilterName).Result()
+	if err != nil {
+		return result, redisErrorHandle(r.logger, err)
+	}
+	// RENAME是atomic的 換掉之前的request照樣用舊的filter
+	if rebuilt == 0 {
+		err = r.client.Del(ctx, originalURLIDsFilterName).Err()
+	} else {
+		err = r.client.Rename(ctx, rebuildOriginalURLIDsFilterName, originalURLIDsFilterName).Err()
+	}
+	if err != nil {
+		return result, redisErrorHandle(r.logger, err)
+	}
+
+	// 先停止記錄再讀 停止之後加入的id會直接加進新的filter
+	if err := r.client.Del(ctx, rebuildingOriginalURLIDsFilterName).Err(); err != nil {
+		return result, redisErrorHandle(r.logger, err)
+	}
+	added, err := r.client.SMembers(ctx, rebuildAddedOriginalURLIDsName).Result()
+	if err != nil {
+		return result, redisErrorHandle(r.logger, err)
+	}
+	for start := 0; start < len(added); start += rebuildReplayBatchSize {
+		end := start + rebuildReplayBatchSize
+		if end > len(added) {
+			end = len(added)
+		}
+		replayed, err := r.add(ctx, "CF.ADDNX", added[start:end])
+		if err != nil {
+			return result, redisErrorHandle(r.logger, err)
+		}
+		result.Replayed += replayed
+	}
+	return result, nil
+}
+
+func (r *RedisCuckooFilter) AddMissing(ctx context.Context, ids []string) (int, *business.Error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	added, err := r.add(ctx, "CF.ADDNX", ids)
+	if err != nil {
+		return 0, redisErrorHandle(r.logger, err)
+	}
+	return added, nil
+}
+
+func isItemExistsErr(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "item exists")
+}
+
+func isUnknownCommandErr(err error) bool {
+	return strings.HasPrefix(strings.ToLower(err.Error()), "err unknown command")
+}
@@ -0,0 +1,286 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RedisCuckooFilter", func() {
+	BeforeEach(requireRedis)
+
+	ctx := context.Background()
+	var redisCuckooFilter *RedisCuckooFilter
+
+	BeforeEach(func() {
+		redisCuckooFilter = NewRedisCuckooFilter(loglib.NewNopLogger(), testRedisClient, CuckooFilterConfig{})
+	})
+
+	var _ = Describe("Add", func() {
+		var (
+			addErr *business.Error
+		)
+
+		id := "random"
+
+		JustBeforeEach(func() {
+			addErr = redisCuckooFilter.Add(ctx, id)
+		})
+
+		Context("success", func() {
+			AfterEach(func() {
+				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(addErr).To(BeNil())
+				ok, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
+				Expect(err).To(BeNil())
+				Expect(ok).To(Equal(true))
+			})
+		})
+	})
+
+	var _ = Describe("Exist", func() {
+		var (
+			existErr *business.Error
+			expectOk bool
+		)
+
+		id := "random"
+
+		JustBeforeEach(func() {
+			expectOk, existErr = redisCuckooFilter.Exist(ctx, id)
+		})
+
+		Context("success", func() {
+			BeforeEach(func() {
+				_, err := testRedisClient.Do(ctx, "CF.ADD", originalURLIDsFilterName, id).Result()
+				Expect(err).To(BeNil())
+			})
+			AfterEach(func() {
+				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(existErr).To(BeNil())
+				actualOk, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
+				Expect(err).To(BeNil())
+				Expect(actualOk).To(Equal(expectOk))
+			})
+		})
+	})
+
+	var _ = Describe("Delete", func() {
+		var (
+			deleteErr *business.Error
+			expectOk  bool
+		)
+
+		id := "random"
+
+		JustBeforeEach(func() {
+			expectOk, deleteErr = redisCuckooFilter.Delete(ctx, id)
+		})
+
+		Context("success", func() {
+			BeforeEach(func() {
+				_, err := testRedisClient.Do(ctx, "CF.ADD", originalURLIDsFilterName, id).Result()
+				Expect(err).To(BeNil())
+			})
+			AfterEach(func() {
+				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(deleteErr).To(BeNil())
+				actualOk, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
+				Expect(err).To(BeNil())
+				Expect(!actualOk).To(Equal(expectOk))
+			})
+		})
+	})
+
+	var _ = Describe("DeleteMulti", func() {
+		var (
+			deleteErr *business.Error
+			expectOk  bool
+		)
+
+		ids := []string{"random1", "random2"}
+
+		JustBeforeEach(func() {
+			expectOk, deleteErr = redisCuckooFilter.DeleteMulti(ctx, ids)
+		})
+
+		Context("success", func() {
+			BeforeEach(func() {
+				for _, id := range ids {
+					_, err := testRedisClient.Do(ctx, "CF.ADD", originalURLIDsFilterName, id).Result()
+					Expect(err).To(BeNil())
+				}
+			})
+			AfterEach(func() {
+				_, err := testRedisClient.Del(ctx, originalURLIDsFilterName).Result()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(deleteErr).To(BeNil())
+				for _, id := range ids {
+					actualOk, err := testRedisClient.Do(ctx, "CF.EXISTS", originalURLIDsFilterName, id).Bool()
+					Expect(err).To(BeNil())
+					Expect(!actualOk).To(Equal(expectOk))
+				}
+			})
+		})
+	})
+
+	var _ = Describe("Reserve and Info", func() {
+
+		AfterEach(func() {
+			testRedisClient.Del(ctx, originalURLIDsFilterName)
+		})
+
+		It("empty before reserve", func() {
+			Expect(redisCuckooFilter.Info(ctx)).To(Equal(FilterInfo{}))
+		})
+
+		It("reserve with config once", func() {
+			redisCuckooFilter = NewRedisCuckooFilter(loglib.NewNopLogger(), testRedisClient, CuckooFilterConfig{Capacity: 1000, BucketSize: 4, Expansion: 2})
+			Expect(redisCuckooFilter.Reserve(ctx)).To(BeNil())
+			Expect(redisCuckooFilter.Reserve(ctx)).To(BeNil())
+			Expect(redisCuckooFilter.Add(ctx, "a")).To(BeNil())
+			Expect(redisCuckooFilter.Add(ctx, "b")).To(BeNil())
+			_, err := redisCuckooFilter.Delete(ctx, "b")
+			Expect(err).To(BeNil())
+
+			info, err := redisCuckooFilter.Info(ctx)
+			Expect(err).To(BeNil())
+			Expect(info.Items).To(Equal(1))
+			Expect(info.Filters).To(Equal(1))
+			Expect(info.BucketSize).To(Equal(4))
+			Expect(info.Expansion).To(Equal(2))
+			Expect(info.Capacity).To(BeNumerically(">=", 1000))
+			Expect(info.FillRatio).To(Equal(float64(1) / float64(info.Capacity)))
+			Expect(info.ErrorRate).To(Equal(float64(8) / 256))
+		})
+	})
+
+	var _ = Describe("Rebuild", func() {
+
+		AfterEach(func() {
+			testRedisClient.Del(ctx, originalURLIDsFilterName, rebuildOriginalURLIDsFilterName, rebuildingOriginalURLIDsFilterName, rebuildAddedOriginalURLIDsName)
+		})
+
+		It("swap in ids from next and report drift", func() {
+			// b 不見了 x y 已經不存在
+			for _, id := range []string{"a", "c", "x", "y"} {
+				Expect(redisCuckooFilter.Add(ctx, id)).To(BeNil())
+			}
+			pages := [][]string{{"a", "b"}, {"c"}}
+			result, err := redisCuckooFilter.Rebuild(ctx, func() ([]string, *business.Error) {
+				if len(pages) == 0 {
+					return nil, nil
+				}
+				page := pages[0]
+				pages = pages[1:]
+				return page, nil
+			})
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(FilterRebuildResult{Scanned: 3, Missing: 1, Extra: 2}))
+			for id, exist := range map[string]bool{"a": true, "b": true, "c": true, "x": false, "y": false} {
+				Expect(redisCuckooFilter.Exist(ctx, id)).To(Equal(exist))
+			}
+			Expect(testRedisClient.Exists(ctx, rebuildOriginalURLIDsFilterName).Val()).To(Equal(int64(0)))
+		})
+
+		It("replay ids added while rebuilding", func() {
+			// z 在重建途中被import 落在已經掃過的範圍
+			pages := [][]string{{"a"}, {"c"}}
+			result, err := redisCuckooFilter.Rebuild(ctx, func() ([]string, *business.Error) {
+				if len(pages) == 0 {
+					return nil, nil
+				}
+				if len(pages) == 1 {
+					Expect(redisCuckooFilter.AddMissing(ctx, []string{"z"})).To(Equal(1))
+				}
+				page := pages[0]
+				pages = pages[1:]
+				return page, nil
+			})
+			Expect(err).To(BeNil())
+			Expect(result.Replayed).To(Equal(1))
+			Expect(redisCuckooFilter.Exist(ctx, "z")).To(BeTrue())
+			Expect(testRedisClient.Exists(ctx, rebuildingOriginalURLIDsFilterName, rebuildAddedOriginalURLIDsName).Val()).To(Equal(int64(0)))
+
+			// 重建完之後加入的不再記錄
+			Expect(redisCuckooFilter.Add(ctx, "y")).To(BeNil())
+			Expect(testRedisClient.Exists(ctx, rebuildAddedOriginalURLIDsName).Val()).To(Equal(int64(0)))
+		})
+
+		It("drop filter without ids", func() {
+			Expect(redisCuckooFilter.Add(ctx, "x")).To(BeNil())
+			result, err := redisCuckooFilter.Rebuild(ctx, func() ([]string, *business.Error) { return nil, nil })
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(FilterRebuildResult{Extra: 1}))
+			Expect(testRedisClient.Exists(ctx, originalURLIDsFilterName).Val()).To(Equal(int64(0)))
+		})
+
+		It("keep old filter when next fail", func() {
+			Expect(redisCuckooFilter.Add(ctx, "x")).To(BeNil())
+			nextErr := business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", errors.New("internal error"))
+			_, err := redisCuckooFilter.Rebuild(ctx, func() ([]string, *business.Error) { return nil, nextErr })
+			Expect(err).To(Equal(nextErr))
+			Expect(redisCuckooFilter.Exist(ctx, "x")).To(BeTrue())
+		})
+	})
+
+	var _ = Describe("AddMissing", func() {
+
+		AfterEach(func() {
+			testRedisClient.Del(ctx, originalURLIDsFilterName)
+		})
+
+		It("only add ids not in filter", func() {
+			Expect(redisCuckooFilter.Add(ctx, "a")).To(BeNil())
+			Expect(redisCuckooFilter.AddMissing(ctx, []string{"a", "b"})).To(Equal(1))
+			Expect(redisCuckooFilter.Exist(ctx, "b")).To(BeTrue())
+		})
+	})
+
+	var _ = Describe("DeleteMulti partially exist", func() {
+
+		AfterEach(func() {
+			testRedisClient.Del(ctx, originalURLIDsFilterName)
+		})
+
+		It("delete the rest", func() {
+			Expect(redisCuckooFilter.Add(ctx, "a")).To(BeNil())
+			Expect(redisCuckooFilter.Add(ctx, "c")).To(BeNil())
+			Expect(redisCuckooFilter.DeleteMulti(ctx, []string{"a", "b", "c"})).To(BeFalse())
+			Expect(redisCuckooFilter.Exist(ctx, "a")).To(BeFalse())
+			Expect(redisCuckooFilter.Exist(ctx, "c")).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("SupportRedisBloom", func() {
+	It("supported", func() {
+		requireRedis()
+		Expect(SupportRedisBloom(testRedisClient)).To(BeTrue())
+	})
+
+	It("detect unknown command", func() {
+		Expect(isUnknownCommandErr(errors.New("ERR unknown command `CF.EXISTS`, with args beginning with: "))).To(BeTrue())
+		Expect(isUnknownCommandErr(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"))).To(BeFalse())
+	})
+})
@@ -0,0 +1,26 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+)
+
+type HealthCheck struct {
+	tableName           struct{}  `pg:"url_health_checks"`
+	URLID               string    `json:"urlId" pg:"url_id,pk"`
+	StatusCode          int       `json:"statusCode" pg:",use_zero"`
+	LatencyMs           int64     `json:"latencyMs" pg:",use_zero"`
+	Error               string    `json:"error,omitempty" pg:",use_zero"`
+	ConsecutiveFailures int       `json:"consecutiveFailures" pg:",use_zero"`
+	Broken              bool      `json:"broken" pg:",use_zero"`
+	CheckedAt           time.Time `json:"checkedAt"`
+}
+
+type HealthCheckDAO interface {
+	// Record 寫入這一輪的結果 ConsecutiveFailures 傳入 0 代表成功 1 代表失敗 回傳累計之後的結果
+	Record(ctx context.Context, check *HealthCheck, brokenThreshold int) (*HealthCheck, *business.Error)
+	Get(ctx context.Context, urlID string) (*HealthCheck, *business.Error)
+	ListBroken(ctx context.Context, limit int) ([]HealthCheck, *business.Error)
+}
@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"context"
+	"sort"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+)
+
+func NewMemoryHealthCheckDAO(store *MemoryStore) *MemoryHealthCheckDAO {
+	return &MemoryHealthCheckDAO{store: store}
+}
+
+type MemoryHealthCheckDAO struct {
+	store *MemoryStore
+}
+
+func (m *MemoryHealthCheckDAO) Record(ctx context.Context, check *HealthCheck, brokenThreshold int) (*HealthCheck, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	check.Broken = check.ConsecutiveFailures >= brokenThreshold
+	if previous, ok := m.store.healthChecks[check.URLID]; ok {
+		if check.ConsecutiveFailures == 0 {
+			check.Broken = false
+		} else {
+			check.ConsecutiveFailures += previous.ConsecutiveFailures
+			check.Broken = check.ConsecutiveFailures >= brokenThreshold
+		}
+	}
+	m.store.healthChecks[check.URLID] = *check
+	return check, nil
+}
+
+func (m *MemoryHealthCheckDAO) Get(ctx context.Context, urlID string) (*HealthCheck, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	check, ok := m.store.healthChecks[urlID]
+	if !ok {
+		return nil, memoryNotFoundError()
+	}
+	return &check, nil
+}
+
+func (m *MemoryHealthCheckDAO) ListBroken(ctx context.Context, limit int) ([]HealthCheck, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	var checks []HealthCheck
+	for _, check := range m.store.healthChecks {
+		if check.Broken {
+			checks = append(checks, check)
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].CheckedAt.After(checks[j].CheckedAt)
+	})
+	if len(checks) > limit {
+		checks = checks[:limit]
+	}
+	return checks, nil
+}
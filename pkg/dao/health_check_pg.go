@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/pglib"
+)
+
+func NewPGHealthCheckDAO(logger *loglib.Logger, client *pglib.GOPGClient) *PGHealthCheckDAO {
+	return &PGHealthCheckDAO{logger: logger, client: client}
+}
+
+type PGHealthCheckDAO struct {
+	logger *loglib.Logger
+	client *pglib.GOPGClient
+}
+
+func (p *PGHealthCheckDAO) Record(ctx context.Context, check *HealthCheck, brokenThreshold int) (*HealthCheck, *business.Error) {
+	check.Broken = check.ConsecutiveFailures >= brokenThreshold
+	_, err := p.client.ModelContext(ctx, check).
+		OnConflict("(url_id) DO UPDATE").
+		Set("status_code = EXCLUDED.status_code").
+		Set("latency_ms = EXCLUDED.latency_ms").
+		Set("error = EXCLUDED.error").
+		Set("consecutive_failures = CASE WHEN EXCLUDED.consecutive_failures = 0 THEN 0 ELSE health_check.consecutive_failures + EXCLUDED.consecutive_failures END").
+		Set("broken = CASE WHEN EXCLUDED.consecutive_failures = 0 THEN false ELSE health_check.consecutive_failures + EXCLUDED.consecutive_failures >= ? END", brokenThreshold).
+		Set("checked_at = EXCLUDED.checked_at").
+		Returning("*").
+		Insert()
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return check, nil
+}
+
+func (p *PGHealthCheckDAO) Get(ctx context.Context, urlID string) (*HealthCheck, *business.Error) {
+	check := &HealthCheck{URLID: urlID}
+	err := p.client.ModelContext(ctx, check).WherePK().Select()
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return check, nil
+}
+
+func (p *PGHealthCheckDAO) ListBroken(ctx context.Context, limit int) ([]HealthCheck, *business.Error) {
+	var checks []HealthCheck
+	err := p.client.ModelContext(ctx, &checks).
+		Where("broken").
+		Order("checked_at DESC").
+		Limit(limit).
+		Select()
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return checks, nil
+}
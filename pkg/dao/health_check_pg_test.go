@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PGHealthCheckDAO", func() {
+	BeforeEach(requirePG)
+
+	ctx := context.Background()
+	var pgHealthCheckDAO *PGHealthCheckDAO
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	actualURL := &URL{
+		ID:        "random",
+		Original:  "http://example.com",
+		CreatedAt: now,
+		ExpiredAt: now.Add(time.Minute),
+	}
+	brokenThreshold := 2
+
+	BeforeEach(func() {
+		pgHealthCheckDAO = NewPGHealthCheckDAO(loglib.NewNopLogger(), testPGClient)
+		_, err := testPGClient.Model(actualURL).Insert()
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		// health check 會跟著 url cascade 刪除
+		_, err := testPGClient.Model(actualURL).WherePK().Delete()
+		Expect(err).To(BeNil())
+	})
+
+	var _ = Describe("Record", func() {
+		record := func(failures int) *HealthCheck {
+			check, err := pgHealthCheckDAO.Record(ctx, &HealthCheck{URLID: actualURL.ID, StatusCode: http.StatusOK, LatencyMs: 10, ConsecutiveFailures: failures, CheckedAt: now}, brokenThreshold)
+			Expect(err).To(BeNil())
+			return check
+		}
+
+		It("mark broken after keep failing", func() {
+			Expect(record(1).Broken).To(BeFalse())
+			check := record(1)
+			Expect(check.ConsecutiveFailures).To(Equal(2))
+			Expect(check.Broken).To(BeTrue())
+
+			broken, err := pgHealthCheckDAO.ListBroken(ctx, 10)
+			Expect(err).To(BeNil())
+			Expect(broken).To(HaveLen(1))
+			Expect(broken[0].URLID).To(Equal(actualURL.ID))
+		})
+
+		It("reset after success", func() {
+			record(1)
+			record(1)
+			check := record(0)
+			Expect(check.ConsecutiveFailures).To(Equal(0))
+			Expect(check.Broken).To(BeFalse())
+		})
+	})
+
+	var _ = Describe("Get", func() {
+		It("success", func() {
+			_, err := pgHealthCheckDAO.Record(ctx, &HealthCheck{URLID: actualURL.ID, StatusCode: http.StatusOK, LatencyMs: 10, CheckedAt: now}, brokenThreshold)
+			Expect(err).To(BeNil())
+
+			check, getErr := pgHealthCheckDAO.Get(ctx, actualURL.ID)
+			Expect(getErr).To(BeNil())
+			Expect(check.StatusCode).To(Equal(http.StatusOK))
+			Expect(check.LatencyMs).To(Equal(int64(10)))
+			Expect(check.CheckedAt).To(Equal(now))
+		})
+
+		It("not found", func() {
+			_, getErr := pgHealthCheckDAO.Get(ctx, actualURL.ID)
+			Expect(getErr.BusinessCode).To(Equal(business.NotFound))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+)
+
+const sqliteHealthCheckColumns = "url_id, status_code, latency_ms, error, consecutive_failures, broken, checked_at"
+
+func NewSQLiteHealthCheckDAO(logger *loglib.Logger, db *sql.DB) *SQLiteHealthCheckDAO {
+	return &SQLiteHealthCheckDAO{logger: logger, db: db}
+}
+
+type SQLiteHealthCheckDAO struct {
+	logger *loglib.Logger
+	db     *sql.DB
+}
+
+func (s *SQLiteHealthCheckDAO) Record(ctx context.Context, check *HealthCheck, brokenThreshold int) (*HealthCheck, *business.Error) {
+	check.Broken = check.ConsecutiveFailures >= brokenThreshold
+	result, err := scanSQLiteHealthCheck(s.db.QueryRowContext(ctx, `INSERT INTO url_health_checks (`+sqliteHealthCheckColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url_id) DO UPDATE SET
+			status_code = excluded.status_code,
+			latency_ms = excluded.latency_ms,
+			error = excluded.error,
+			consecutive_failures = CASE WHEN excluded.consecutive_failures = 0 THEN 0 ELSE url_health_checks.consecutive_failures + excluded.consecutive_failures END,
+			broken = CASE WHEN excluded.consecutive_failures = 0 THEN false ELSE url_health_checks.consecutive_failures + excluded.consecutive_failures >= ? END,
+			checked_at = excluded.checked_at
+		RETURNING `+sqliteHealthCheckColumns,
+		check.URLID, check.StatusCode, check.LatencyMs, check.Error, check.ConsecutiveFailures, check.Broken, toSQLiteTime(check.CheckedAt), brokenThreshold))
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return result, nil
+}
+
+func (s *SQLiteHealthCheckDAO) Get(ctx context.Context, urlID string) (*HealthCheck, *business.Error) {
+	check, err := scanSQLiteHealthCheck(s.db.QueryRowContext(ctx, "SELECT "+sqliteHealthCheckColumns+" FROM url_health_checks WHERE url_id = ?", urlID))
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return check, nil
+}
+
+func (s *SQLiteHealthCheckDAO) ListBroken(ctx context.Context, limit int) ([]HealthCheck, *business.Error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+sqliteHealthCheckColumns+" FROM url_health_checks WHERE broken ORDER BY checked_at DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	defer rows.Close()
+
+	var checks []HealthCheck
+	for rows.Next() {
+		check, err := scanSQLiteHealthCheck(rows)
+		if err != nil {
+			return nil, sqliteErrorHandle(s.logger, err)
+		}
+		checks = append(checks, *check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return checks, nil
+}
+
+func scanSQLiteHealthCheck(row sqliteScanner) (*HealthCheck, error) {
+	var check HealthCheck
+	var checkedAt int64
+	err := row.Scan(&check.URLID, &check.StatusCode, &check.LatencyMs, &check.Error, &check.ConsecutiveFailures, &check.Broken, &checkedAt)
+	if err != nil {
+		return nil, err
+	}
+	check.CheckedAt = fromSQLiteTime(checkedAt)
+	return &check, nil
+}
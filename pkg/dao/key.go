@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"time"
 )
@@ -10,6 +11,24 @@ type Key struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// QuarantinedKey 過期或刪除的url id 在ReleaseAt之前不會被再發出去
+type QuarantinedKey struct {
+	ID            string    `json:"id"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+	ReleaseAt     time.Time `json:"releaseAt"`
+}
+
 type KeyDAO interface {
-	BatchCreate(num int) (int, *business.Error)
+	// BatchCreate 回傳實際insert的數量 已經在keys、urls或quarantined_keys裡的id會被略過
+	BatchCreate(ctx context.Context, num int) (int, *business.Error)
+	// Count 還沒被拿去用的key數量
+	Count(ctx context.Context) (int, *business.Error)
+	// Lease 一次從keys拿走最多num個key 回傳的數量可能比num少
+	Lease(ctx context.Context, num int) ([]string, *business.Error)
+	// Return 把lease了但是沒用到的key放回keys 已經被用掉的會被略過
+	Return(ctx context.Context, ids []string) (int, *business.Error)
+	// ReleaseQuarantined 把最多num個冷卻期已過的id放回keys 回傳放回的數量
+	ReleaseQuarantined(ctx context.Context, num int) (int, *business.Error)
+	// CountQuarantined 還在冷卻期以及等著被放回keys的id數量
+	CountQuarantined(ctx context.Context) (int, *business.Error)
 }
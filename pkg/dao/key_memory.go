@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/randstr"
+)
+
+func NewMemoryKeyDAO(store *MemoryStore, randomStrGenerator randstr.RandomStrGenerator, keyLength int) *MemoryKeyDAO {
+	return &MemoryKeyDAO{store: store, randomStrGenerator: randomStrGenerator, keyLength: keyLength}
+}
+
+type MemoryKeyDAO struct {
+	store              *MemoryStore
+	randomStrGenerator randstr.RandomStrGenerator
+	// 新產生的key的長度 舊的key不受影響
+	keyLength int
+}
+
+func (m *MemoryKeyDAO) BatchCreate(ctx context.Context, num int) (int, *business.Error) {
+	ids := make([]string, 0, num)
+	for i := 0; i < num; i++ {
+		ids = append(ids, m.randomStrGenerator.GenerateRandomStr(m.keyLength))
+	}
+	return m.insertUnused(ctx, ids)
+}
+
+func (m *MemoryKeyDAO) insertUnused(ctx context.Context, ids []string) (int, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.store.mu.Unlock()
+
+	inserted := 0
+	now := time.Now()
+	for _, id := range ids {
+		if m.store.used(id) {
+			continue
+		}
+		m.store.keys[id] = Key{ID: id, CreatedAt: now}
+		inserted++
+	}
+	return inserted, nil
+}
+
+func (m *MemoryKeyDAO) Lease(ctx context.Context, num int) ([]string, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	var ids []string
+	for id := range m.store.keys {
+		if len(ids) >= num {
+			break
+		}
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		delete(m.store.keys, id)
+	}
+	return ids, nil
+}
+
+func (m *MemoryKeyDAO) Return(ctx context.Context, ids []string) (int, *business.Error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return m.insertUnused(ctx, ids)
+}
+
+func (m *MemoryKeyDAO) Count(ctx context.Context) (int, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.store.mu.Unlock()
+	return len(m.store.keys), nil
+}
+
+func (m *MemoryKeyDAO) ReleaseQuarantined(ctx context.Context, num int) (int, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+	var released []QuarantinedKey
+	for _, key := range m.store.quarantinedKeys {
+		if !key.ReleaseAt.After(now) {
+			released = append(released, key)
+		}
+	}
+	sort.Slice(released, func(i, j int) bool {
+		return released[i].ReleaseAt.Before(released[j].ReleaseAt)
+	})
+	if len(released) > num {
+		released = released[:num]
+	}
+
+	inserted := 0
+	for _, key := range released {
+		delete(m.store.quarantinedKeys, key.ID)
+		// 冷卻中的id不會再被發出去 保險起見還是略過urls裡已經有的
+		if m.store.used(key.ID) {
+			continue
+		}
+		m.store.keys[key.ID] = Key{ID: key.ID, CreatedAt: now}
+		inserted++
+	}
+	return inserted, nil
+}
+
+func (m *MemoryKeyDAO) CountQuarantined(ctx context.Context) (int, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.store.mu.Unlock()
+	return len(m.store.quarantinedKeys), nil
+}
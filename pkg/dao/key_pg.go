@@ -1,35 +1,97 @@
 package dao
 
 import (
+	"context"
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/KennyChenFight/golib/pglib"
 	"github.com/KennyChenFight/randstr"
+	"github.com/go-pg/pg/v10"
 	"time"
 )
 
-func NewPGKeyDAO(logger *loglib.Logger, client *pglib.GOPGClient, randomStrGenerator randstr.RandomStrGenerator) *PGKeyDAO {
-	return &PGKeyDAO{logger: logger, client: client, randomStrGenerator: randomStrGenerator}
+func NewPGKeyDAO(logger *loglib.Logger, client *pglib.GOPGClient, randomStrGenerator randstr.RandomStrGenerator, keyLength int) *PGKeyDAO {
+	return &PGKeyDAO{logger: logger, client: client, randomStrGenerator: randomStrGenerator, keyLength: keyLength}
 }
 
 type PGKeyDAO struct {
 	logger             *loglib.Logger
 	client             *pglib.GOPGClient
 	randomStrGenerator randstr.RandomStrGenerator
+	// 新產生的key的長度 舊的key不受影響
+	keyLength int
 }
 
-func (p *PGKeyDAO) BatchCreate(num int) (int, *business.Error) {
-	var keys []Key
-	now := time.Now()
+// BatchCreate 產生num個隨機key 已經在keys、urls或quarantined_keys裡的id 以及同一批重複的id都會被略過
+// 回傳實際insert的數量
+func (p *PGKeyDAO) BatchCreate(ctx context.Context, num int) (int, *business.Error) {
+	ids := make([]string, 0, num)
 	for i := 0; i < num; i++ {
-		keys = append(keys, Key{
-			ID:        p.randomStrGenerator.GenerateRandomStr(randStrLength),
-			CreatedAt: now,
-		})
+		ids = append(ids, p.randomStrGenerator.GenerateRandomStr(p.keyLength))
 	}
-	res, err := p.client.Model(&keys).OnConflict("DO NOTHING").Insert()
+	return p.insertUnused(ctx, ids)
+}
+
+func (p *PGKeyDAO) insertUnused(ctx context.Context, ids []string) (int, *business.Error) {
+	res, err := p.client.ExecContext(ctx, `INSERT INTO keys (id, created_at)
+		SELECT DISTINCT candidate.id, ?::timestamp FROM unnest(?::varchar[]) AS candidate(id)
+		WHERE NOT EXISTS (SELECT 1 FROM urls WHERE urls.id = candidate.id)
+		AND NOT EXISTS (SELECT 1 FROM quarantined_keys WHERE quarantined_keys.id = candidate.id)
+		ON CONFLICT DO NOTHING`, time.Now(), pg.Array(ids))
 	if err != nil {
 		return 0, pgErrorHandle(p.logger, err)
 	}
 	return res.RowsAffected(), nil
 }
+
+func (p *PGKeyDAO) Lease(ctx context.Context, num int) ([]string, *business.Error) {
+	var ids []string
+	_, err := p.client.QueryContext(ctx, &ids, `DELETE FROM keys WHERE id IN (
+			SELECT id FROM keys LIMIT ? FOR UPDATE SKIP LOCKED
+		) RETURNING id`, num)
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return ids, nil
+}
+
+func (p *PGKeyDAO) Return(ctx context.Context, ids []string) (int, *business.Error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return p.insertUnused(ctx, ids)
+}
+
+func (p *PGKeyDAO) Count(ctx context.Context) (int, *business.Error) {
+	count, err := p.client.ModelContext(ctx, (*Key)(nil)).Count()
+	if err != nil {
+		return 0, pgErrorHandle(p.logger, err)
+	}
+	return count, nil
+}
+
+func (p *PGKeyDAO) ReleaseQuarantined(ctx context.Context, num int) (int, *business.Error) {
+	now := time.Now()
+	// 冷卻中的id不會再被發出去 保險起見還是略過urls裡已經有的
+	res, err := p.client.ExecContext(ctx, `WITH released AS (
+			DELETE FROM quarantined_keys WHERE id IN (
+				SELECT id FROM quarantined_keys WHERE release_at <= ? ORDER BY release_at LIMIT ? FOR UPDATE SKIP LOCKED
+			) RETURNING id
+		)
+		INSERT INTO keys (id, created_at)
+		SELECT released.id, ?::timestamp FROM released
+		WHERE NOT EXISTS (SELECT 1 FROM urls WHERE urls.id = released.id)
+		ON CONFLICT DO NOTHING`, now, num, now)
+	if err != nil {
+		return 0, pgErrorHandle(p.logger, err)
+	}
+	return res.RowsAffected(), nil
+}
+
+func (p *PGKeyDAO) CountQuarantined(ctx context.Context) (int, *business.Error) {
+	count, err := p.client.ModelContext(ctx, (*QuarantinedKey)(nil)).Count()
+	if err != nil {
+		return 0, pgErrorHandle(p.logger, err)
+	}
+	return count, nil
+}
@@ -1,8 +1,12 @@
 package dao
 
 import (
+	"context"
+	"time"
+
 	"github.com/KennyChenFight/Shortening-URL/internal/randomstrgeneratormock"
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/KennyChenFight/randstr"
 	"github.com/golang/mock/gomock"
@@ -11,6 +15,9 @@ import (
 )
 
 var _ = Describe("PGKeyDAO", func() {
+	BeforeEach(requirePG)
+
+	ctx := context.Background()
 	var pgKeyDAO *PGKeyDAO
 	var logger *loglib.Logger
 	var randomStrGeneratorMock *randomstrgeneratormock.MockRandomStrGenerator
@@ -20,7 +27,7 @@ var _ = Describe("PGKeyDAO", func() {
 		mockCtrl = gomock.NewController(GinkgoT())
 		randomStrGeneratorMock = randomstrgeneratormock.NewMockRandomStrGenerator(mockCtrl)
 		logger = loglib.NewNopLogger()
-		pgKeyDAO = &PGKeyDAO{logger, testPGClient, randomStrGeneratorMock}
+		pgKeyDAO = &PGKeyDAO{logger, testPGClient, randomStrGeneratorMock, shortid.DefaultLength}
 	})
 
 	AfterEach(func() {
@@ -33,18 +40,22 @@ var _ = Describe("PGKeyDAO", func() {
 			createErr          *business.Error
 		)
 
-		num := 2
+		var num int
+
+		BeforeEach(func() {
+			num = 2
+		})
 
 		JustBeforeEach(func() {
-			expectCreateNumber, createErr = pgKeyDAO.BatchCreate(num)
+			expectCreateNumber, createErr = pgKeyDAO.BatchCreate(ctx, num)
 		})
 
 		Context("success", func() {
 			actualRandStrGenerator := randstr.NewFastGenerator(randstr.CharSetEnglishAlphabet)
-			ids := []string{actualRandStrGenerator.GenerateRandomStr(randStrLength), actualRandStrGenerator.GenerateRandomStr(randStrLength)}
+			ids := []string{actualRandStrGenerator.GenerateRandomStr(shortid.DefaultLength), actualRandStrGenerator.GenerateRandomStr(shortid.DefaultLength)}
 			BeforeEach(func() {
 				for _, id := range ids {
-					randomStrGeneratorMock.EXPECT().GenerateRandomStr(randStrLength).Return(id)
+					randomStrGeneratorMock.EXPECT().GenerateRandomStr(shortid.DefaultLength).Return(id)
 				}
 			})
 
@@ -64,7 +75,7 @@ var _ = Describe("PGKeyDAO", func() {
 			ids := []string{"random", "random"}
 			BeforeEach(func() {
 				for _, id := range ids {
-					randomStrGeneratorMock.EXPECT().GenerateRandomStr(randStrLength).Return(id)
+					randomStrGeneratorMock.EXPECT().GenerateRandomStr(shortid.DefaultLength).Return(id)
 				}
 			})
 
@@ -79,5 +90,181 @@ var _ = Describe("PGKeyDAO", func() {
 				Expect(expectCreateNumber).To(Equal(len(ids) - 1))
 			})
 		})
+
+		Context("skip ids in urls and keys", func() {
+			usedURL := URL{ID: "used01", Original: "http://example.com", CreatedAt: time.Now(), ExpiredAt: time.Now().Add(time.Hour)}
+			existKey := Key{ID: "exist1", CreatedAt: time.Now()}
+			quarantinedKey := QuarantinedKey{ID: "quara1", QuarantinedAt: time.Now(), ReleaseAt: time.Now().Add(time.Hour)}
+			ids := []string{usedURL.ID, existKey.ID, quarantinedKey.ID, "fresh1", "fresh1"}
+
+			BeforeEach(func() {
+				num = len(ids)
+				_, err := testPGClient.Model(&usedURL).Insert()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model(&existKey).Insert()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model(&quarantinedKey).Insert()
+				Expect(err).To(BeNil())
+				for _, id := range ids {
+					randomStrGeneratorMock.EXPECT().GenerateRandomStr(shortid.DefaultLength).Return(id)
+				}
+			})
+
+			AfterEach(func() {
+				_, err := testPGClient.Model(&usedURL).WherePK().Delete()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model(&quarantinedKey).WherePK().Delete()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model((*Key)(nil)).WhereIn("id in (?)", ids).Delete()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(createErr).To(BeNil())
+				Expect(expectCreateNumber).To(Equal(1))
+				Ω(testPGClient.Model(&Key{}).Where("id = ?", usedURL.ID).Count()).To(Equal(0))
+				Ω(testPGClient.Model(&Key{}).Where("id = ?", quarantinedKey.ID).Count()).To(Equal(0))
+				Ω(testPGClient.Model(&Key{}).Where("id = ?", "fresh1").Count()).To(Equal(1))
+			})
+		})
+	})
+
+	var _ = Describe("Count", func() {
+		var (
+			expectCount int
+			countErr    *business.Error
+		)
+
+		actualKeys := []Key{{ID: "count1", CreatedAt: time.Now()}, {ID: "count2", CreatedAt: time.Now()}}
+
+		BeforeEach(func() {
+			_, err := testPGClient.Model(&actualKeys).Insert()
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model(&actualKeys).WherePK().Delete()
+			Expect(err).To(BeNil())
+		})
+
+		JustBeforeEach(func() {
+			expectCount, countErr = pgKeyDAO.Count(ctx)
+		})
+
+		Context("success", func() {
+			It("result", func() {
+				Expect(countErr).To(BeNil())
+				Expect(expectCount).To(Equal(len(actualKeys)))
+			})
+		})
+	})
+
+	var _ = Describe("ReleaseQuarantined", func() {
+		var (
+			expectReleaseNumber int
+			releaseErr          *business.Error
+		)
+
+		now := time.Now()
+		dueKeys := []QuarantinedKey{
+			{ID: "due001", QuarantinedAt: now.Add(-2 * time.Hour), ReleaseAt: now.Add(-2 * time.Minute)},
+			{ID: "due002", QuarantinedAt: now.Add(-2 * time.Hour), ReleaseAt: now.Add(-time.Minute)},
+		}
+		coolingKey := QuarantinedKey{ID: "cool01", QuarantinedAt: now, ReleaseAt: now.Add(time.Hour)}
+		ids := []string{dueKeys[0].ID, dueKeys[1].ID, coolingKey.ID}
+
+		BeforeEach(func() {
+			_, err := testPGClient.Model(&[]QuarantinedKey{dueKeys[0], dueKeys[1], coolingKey}).Insert()
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model((*QuarantinedKey)(nil)).WhereIn("id in (?)", ids).Delete()
+			Expect(err).To(BeNil())
+			_, err = testPGClient.Model((*Key)(nil)).WhereIn("id in (?)", ids).Delete()
+			Expect(err).To(BeNil())
+		})
+
+		JustBeforeEach(func() {
+			expectReleaseNumber, releaseErr = pgKeyDAO.ReleaseQuarantined(ctx, 1)
+		})
+
+		Context("release the earliest due id only", func() {
+			It("result", func() {
+				Expect(releaseErr).To(BeNil())
+				Expect(expectReleaseNumber).To(Equal(1))
+				Ω(testPGClient.Model(&Key{}).Where("id = ?", dueKeys[0].ID).Count()).To(Equal(1))
+				Ω(testPGClient.Model(&QuarantinedKey{}).WhereIn("id in (?)", ids).Count()).To(Equal(2))
+			})
+		})
+	})
+
+	var _ = Describe("CountQuarantined", func() {
+		var (
+			expectCount int
+			countErr    *business.Error
+		)
+
+		actualKeys := []QuarantinedKey{
+			{ID: "count1", QuarantinedAt: time.Now(), ReleaseAt: time.Now().Add(time.Hour)},
+			{ID: "count2", QuarantinedAt: time.Now(), ReleaseAt: time.Now().Add(time.Hour)},
+		}
+
+		BeforeEach(func() {
+			_, err := testPGClient.Model(&actualKeys).Insert()
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model(&actualKeys).WherePK().Delete()
+			Expect(err).To(BeNil())
+		})
+
+		JustBeforeEach(func() {
+			expectCount, countErr = pgKeyDAO.CountQuarantined(ctx)
+		})
+
+		Context("success", func() {
+			It("result", func() {
+				Expect(countErr).To(BeNil())
+				Expect(expectCount).To(Equal(len(actualKeys)))
+			})
+		})
+	})
+
+	var _ = Describe("Lease and Return", func() {
+		actualKeys := []Key{{ID: "lease1", CreatedAt: time.Now()}, {ID: "lease2", CreatedAt: time.Now()}}
+		usedURL := URL{ID: "lease3", Original: "http://example.com", CreatedAt: time.Now(), ExpiredAt: time.Now().Add(time.Hour)}
+		ids := []string{actualKeys[0].ID, actualKeys[1].ID, usedURL.ID}
+
+		BeforeEach(func() {
+			_, err := testPGClient.Model(&actualKeys).Insert()
+			Expect(err).To(BeNil())
+			_, err = testPGClient.Model(&usedURL).Insert()
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model(&usedURL).WherePK().Delete()
+			Expect(err).To(BeNil())
+			_, err = testPGClient.Model((*Key)(nil)).WhereIn("id in (?)", ids).Delete()
+			Expect(err).To(BeNil())
+		})
+
+		It("lease removes keys and return skips used ids", func() {
+			leased, err := pgKeyDAO.Lease(ctx, 100)
+			Expect(err).To(BeNil())
+			Expect(leased).To(ContainElements(actualKeys[0].ID, actualKeys[1].ID))
+			Ω(testPGClient.Model(&Key{}).WhereIn("id in (?)", ids).Count()).To(Equal(0))
+
+			returned, err := pgKeyDAO.Return(ctx, ids)
+			Expect(err).To(BeNil())
+			Expect(returned).To(Equal(2))
+			Ω(testPGClient.Model(&Key{}).Where("id = ?", usedURL.ID).Count()).To(Equal(0))
+
+			// 其他測試留下的key也可能被lease到 放回去
+			_, err = pgKeyDAO.Return(ctx, leased)
+			Expect(err).To(BeNil())
+		})
 	})
 })
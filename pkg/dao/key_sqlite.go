@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/randstr"
+)
+
+func NewSQLiteKeyDAO(logger *loglib.Logger, db *sql.DB, randomStrGenerator randstr.RandomStrGenerator, keyLength int) *SQLiteKeyDAO {
+	return &SQLiteKeyDAO{logger: logger, db: db, randomStrGenerator: randomStrGenerator, keyLength: keyLength}
+}
+
+type SQLiteKeyDAO struct {
+	logger             *loglib.Logger
+	db                 *sql.DB
+	randomStrGenerator randstr.RandomStrGenerator
+	// 新產生的key的長度 舊的key不受影響
+	keyLength int
+}
+
+// BatchCreate 產生num個隨機key 已經在keys、urls或quarantined_keys裡的id 以及同一批重複的id都會被略過
+// 回傳實際insert的數量
+func (s *SQLiteKeyDAO) BatchCreate(ctx context.Context, num int) (int, *business.Error) {
+	ids := make([]string, 0, num)
+	for i := 0; i < num; i++ {
+		ids = append(ids, s.randomStrGenerator.GenerateRandomStr(s.keyLength))
+	}
+	return s.insertUnused(ctx, ids)
+}
+
+// insertUnused sqlite沒有array 整批id用json傳進去再用json_each展開
+func (s *SQLiteKeyDAO) insertUnused(ctx context.Context, ids []string) (int, *business.Error) {
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return 0, sqliteErrorHandle(s.logger, err)
+	}
+	res, err := s.db.ExecContext(ctx, sqliteInsertUnusedKeys, toSQLiteTime(time.Now()), string(encoded))
+	if err != nil {
+		return 0, sqliteErrorHandle(s.logger, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, sqliteErrorHandle(s.logger, err)
+	}
+	return int(affected), nil
+}
+
+const sqliteInsertUnusedKeys = `INSERT INTO keys (id, created_at)
+	SELECT DISTINCT candidate.value, ? FROM json_each(?) AS candidate
+	WHERE NOT EXISTS (SELECT 1 FROM urls WHERE urls.id = candidate.value)
+	AND NOT EXISTS (SELECT 1 FROM quarantined_keys WHERE quarantined_keys.id = candidate.value)
+	ON CONFLICT DO NOTHING`
+
+// Lease 跟sqliteClaimKey一樣 同時只有一個人能寫 不會有兩個人lease到同一個key
+func (s *SQLiteKeyDAO) Lease(ctx context.Context, num int) ([]string, *business.Error) {
+	var ids []string
+	err := runInSQLiteTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		ids, err = sqliteDeleteReturningIDs(ctx, tx, "DELETE FROM keys WHERE id IN (SELECT id FROM keys LIMIT ?) RETURNING id", num)
+		return err
+	})
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return ids, nil
+}
+
+func (s *SQLiteKeyDAO) Return(ctx context.Context, ids []string) (int, *business.Error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return s.insertUnused(ctx, ids)
+}
+
+func (s *SQLiteKeyDAO) Count(ctx context.Context) (int, *business.Error) {
+	return s.count(ctx, "SELECT count(*) FROM keys")
+}
+
+func (s *SQLiteKeyDAO) ReleaseQuarantined(ctx context.Context, num int) (int, *business.Error) {
+	now := toSQLiteTime(time.Now())
+	var released int64
+	// sqlite的CTE裡不能DELETE 拆成同一個transaction裡的兩句
+	err := runInSQLiteTx(ctx, s.db, func(tx *sql.Tx) error {
+		ids, err := sqliteDeleteReturningIDs(ctx, tx, `DELETE FROM quarantined_keys WHERE id IN (
+				SELECT id FROM quarantined_keys WHERE release_at <= ? ORDER BY release_at LIMIT ?
+			) RETURNING id`, now, num)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		encoded, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+		// 冷卻中的id不會再被發出去 保險起見還是略過urls裡已經有的
+		res, err := tx.ExecContext(ctx, sqliteInsertUnusedKeys, now, string(encoded))
+		if err != nil {
+			return err
+		}
+		released, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, sqliteErrorHandle(s.logger, err)
+	}
+	return int(released), nil
+}
+
+func (s *SQLiteKeyDAO) CountQuarantined(ctx context.Context) (int, *business.Error) {
+	return s.count(ctx, "SELECT count(*) FROM quarantined_keys")
+}
+
+func (s *SQLiteKeyDAO) count(ctx context.Context, query string) (int, *business.Error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, sqliteErrorHandle(s.logger, err)
+	}
+	return count, nil
+}
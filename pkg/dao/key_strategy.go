@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"fmt"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/idgen"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+	"github.com/go-pg/pg/v10"
+)
+
+const (
+	// KeyStrategyPool 從GenerateKeyJob預先產生的keys裡拿一個隨機id
+	KeyStrategyPool = "pool"
+	// KeyStrategySequence 流水號經過以secret為key的permutation打散 不會重複也猜不到下一個
+	KeyStrategySequence = "sequence"
+	// KeyStrategyHash 原始網址的hash 撞到已經被用過的id就往下probe
+	KeyStrategyHash = "hash"
+)
+
+// 撞到舊的id時最多再試幾次 超過就當作沒有id可以用
+const sequenceKeyMaxProbes = 16
+const hashKeyMaxProbes = 16
+
+// KeyStrategy 決定新的url要用哪個id
+type KeyStrategy interface {
+	// Assign 在tx裡替url挑一個沒被用過也不在冷卻中的id並寫入urls 沒有id可以用時回傳PGErrMsgNoKeyAvailable
+	Assign(tx *pg.Tx, url *URL) error
+}
+
+// KeySource 記憶體裡已經從keys拿出來的key 沒有了就回傳false
+type KeySource interface {
+	Take() (string, bool)
+	// Release 拿了但是沒用到的key 例如transaction rollback 要放回去
+	Release(ids []string)
+}
+
+// KeyStrategyFinisher Assign在transaction以外還有狀態的strategy transaction結束後要告訴它有沒有commit
+type KeyStrategyFinisher interface {
+	Finish(tx *pg.Tx, committed bool)
+}
+
+type KeyStrategyConfig struct {
+	Strategy string
+	Charset  string
+	Length   int
+	// sequence strategy打散流水號用 換掉之後新的id可能會撞到舊的 只是要多probe幾次
+	Secret string
+}
+
+func KeyStrategies() []string {
+	return []string{KeyStrategyPool, KeyStrategySequence, KeyStrategyHash}
+}
+
+func NewKeyStrategy(cfg KeyStrategyConfig) (KeyStrategy, error) {
+	if cfg.Strategy == KeyStrategyPool {
+		return NewPoolKeyStrategy(), nil
+	}
+	if cfg.Strategy != KeyStrategySequence && cfg.Strategy != KeyStrategyHash {
+		return nil, fmt.Errorf("unsupported key strategy %q, should be one of %v", cfg.Strategy, KeyStrategies())
+	}
+
+	if err := shortid.ValidateLength(cfg.Length); err != nil {
+		return nil, err
+	}
+	alphabet, err := shortid.Alphabet(cfg.Charset)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := idgen.NewCodec(alphabet, cfg.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Strategy == KeyStrategyHash {
+		return NewHashKeyStrategy(codec), nil
+	}
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("sequence key strategy requires a secret")
+	}
+	return NewSequenceKeyStrategy(codec, idgen.NewPermutation(codec.Size(), []byte(cfg.Secret))), nil
+}
@@ -0,0 +1,131 @@
+package dao
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/idgen"
+	"github.com/go-pg/pg/v10"
+)
+
+func NewPoolKeyStrategy() *PoolKeyStrategy {
+	return &PoolKeyStrategy{}
+}
+
+type PoolKeyStrategy struct{}
+
+func (s *PoolKeyStrategy) Assign(tx *pg.Tx, url *URL) error {
+	return claimKey(tx, url)
+}
+
+// claimKey 從keys拿一個沒被鎖住的key當作url的id 並把key刪掉
+func claimKey(tx *pg.Tx, url *URL) error {
+	res, err := tx.Model((*URL)(nil)).Query(pg.Scan(&url.ID), "INSERT INTO urls SELECT id, ?, ?, ? FROM keys FOR UPDATE SKIP LOCKED LIMIT 1 RETURNING id", url.Original, url.CreatedAt, url.ExpiredAt)
+	if err != nil {
+		return err
+	}
+	if res.RowsReturned() == 0 {
+		return errors.New(PGErrMsgNoKeyAvailable)
+	}
+
+	_, err = tx.Model(&Key{ID: url.ID}).WherePK().Delete()
+	return err
+}
+
+// NewLeaseKeyStrategy source沒有key的時候改用fallback 一般是pool strategy
+func NewLeaseKeyStrategy(source KeySource, fallback KeyStrategy) *LeaseKeyStrategy {
+	return &LeaseKeyStrategy{source: source, fallback: fallback, pending: make(map[*pg.Tx][]string)}
+}
+
+// LeaseKeyStrategy 優先用已經lease到記憶體的key 不用再鎖keys table 沒有的時候退回fallback
+// lease出來的key已經不在keys裡 transaction rollback的話要放回source 不然就不見了
+type LeaseKeyStrategy struct {
+	source   KeySource
+	fallback KeyStrategy
+
+	mu sync.Mutex
+	// 還沒結束的transaction用掉的lease key
+	pending map[*pg.Tx][]string
+}
+
+func (s *LeaseKeyStrategy) Assign(tx *pg.Tx, url *URL) error {
+	for {
+		id, ok := s.source.Take()
+		if !ok {
+			return s.fallback.Assign(tx, url)
+		}
+		// lease出來的key已經不在keys裡 也不會在冷卻中 只要確認沒被import之類的用掉
+		url.ID = id
+		res, err := tx.Model(url).OnConflict("DO NOTHING").Insert()
+		if err != nil {
+			s.source.Release([]string{id})
+			return err
+		}
+		if res.RowsAffected() == 1 {
+			s.mu.Lock()
+			s.pending[tx] = append(s.pending[tx], id)
+			s.mu.Unlock()
+			return nil
+		}
+	}
+}
+
+// Finish 沒有commit的話 這個transaction用掉的lease key放回source
+func (s *LeaseKeyStrategy) Finish(tx *pg.Tx, committed bool) {
+	s.mu.Lock()
+	ids := s.pending[tx]
+	delete(s.pending, tx)
+	s.mu.Unlock()
+	if !committed && len(ids) > 0 {
+		s.source.Release(ids)
+	}
+}
+
+func NewSequenceKeyStrategy(codec *idgen.Codec, permutation *idgen.Permutation) *SequenceKeyStrategy {
+	return &SequenceKeyStrategy{codec: codec, permutation: permutation}
+}
+
+type SequenceKeyStrategy struct {
+	codec       *idgen.Codec
+	permutation *idgen.Permutation
+}
+
+func (s *SequenceKeyStrategy) Assign(tx *pg.Tx, url *URL) error {
+	for probe := 0; probe < sequenceKeyMaxProbes; probe++ {
+		// nextval不會因為rollback而退回 流水號有空洞沒關係
+		var next int64
+		if _, err := tx.QueryOne(pg.Scan(&next), "SELECT nextval('url_id_seq')"); err != nil {
+			return err
+		}
+		if uint64(next) >= s.codec.Size() {
+			return errors.New(PGErrMsgNoKeyAvailable)
+		}
+
+		// 切換strategy或是換secret之前產生的id可能剛好一樣 撞到就拿下一個流水號
+		url.ID = s.codec.Encode(s.permutation.Forward(uint64(next)))
+		inserted, err := insertIfFree(tx, url)
+		if err != nil || inserted {
+			return err
+		}
+	}
+	return errors.New(PGErrMsgNoKeyAvailable)
+}
+
+func NewHashKeyStrategy(codec *idgen.Codec) *HashKeyStrategy {
+	return &HashKeyStrategy{codec: codec}
+}
+
+type HashKeyStrategy struct {
+	codec *idgen.Codec
+}
+
+func (s *HashKeyStrategy) Assign(tx *pg.Tx, url *URL) error {
+	for attempt := 0; attempt < hashKeyMaxProbes; attempt++ {
+		url.ID = s.codec.Encode(idgen.HashIndex(url.Original, attempt, s.codec.Size()))
+		inserted, err := insertIfFree(tx, url)
+		if err != nil || inserted {
+			return err
+		}
+	}
+	return errors.New(PGErrMsgNoKeyAvailable)
+}
@@ -0,0 +1,255 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/idgen"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/randstr"
+	"github.com/go-pg/pg/v10"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 每個strategy都要通過的共用測試 prepare在每個測試前準備至少n個可以用的id
+func describeKeyStrategy(name string, newStrategy func() KeyStrategy, prepare func(n int) []string) {
+	Describe(name, func() {
+		var strategy KeyStrategy
+		var prepared []string
+		var assigned []string
+
+		n := 5
+
+		BeforeEach(func() {
+			strategy = newStrategy()
+			prepared = prepare(n)
+			assigned = nil
+		})
+
+		AfterEach(func() {
+			ids := append(append([]string{}, assigned...), prepared...)
+			if len(ids) == 0 {
+				return
+			}
+			_, err := testPGClient.Model((*URL)(nil)).WhereIn("id in (?)", ids).Delete()
+			Expect(err).To(BeNil())
+			_, err = testPGClient.Model((*Key)(nil)).WhereIn("id in (?)", ids).Delete()
+			Expect(err).To(BeNil())
+		})
+
+		assign := func(original string) (*URL, error) {
+			now := time.Now()
+			url := &URL{Original: original, CreatedAt: now, ExpiredAt: now.Add(time.Hour)}
+			err := testPGClient.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+				return strategy.Assign(tx, url)
+			})
+			if err == nil {
+				assigned = append(assigned, url.ID)
+			}
+			return url, err
+		}
+
+		It("assign distinct valid ids", func() {
+			seen := make(map[string]bool)
+			for i := 0; i < n; i++ {
+				url, err := assign(fmt.Sprintf("http://example.com/%s/%d", name, i))
+				Expect(err).To(BeNil())
+				Expect(shortid.Valid(url.ID)).To(BeTrue())
+				Expect(seen[url.ID]).To(BeFalse())
+				seen[url.ID] = true
+
+				var stored URL
+				Expect(testPGClient.Model(&stored).Where("id = ?", url.ID).Select()).To(BeNil())
+				Expect(stored.Original).To(Equal(url.Original))
+			}
+			Ω(testPGClient.Model(&Key{}).WhereIn("id in (?)", assigned).Count()).To(Equal(0))
+		})
+
+		It("assign another id to the same original url", func() {
+			first, err := assign("http://example.com/" + name + "/same")
+			Expect(err).To(BeNil())
+			second, err := assign("http://example.com/" + name + "/same")
+			Expect(err).To(BeNil())
+			Expect(second.ID).NotTo(Equal(first.ID))
+		})
+	})
+}
+
+type sliceKeySource struct {
+	keys []string
+}
+
+func (s *sliceKeySource) Take() (string, bool) {
+	if len(s.keys) == 0 {
+		return "", false
+	}
+	id := s.keys[0]
+	s.keys = s.keys[1:]
+	return id, true
+}
+
+func (s *sliceKeySource) Release(ids []string) {
+	s.keys = append(s.keys, ids...)
+}
+
+// noKeyStrategy 一律回傳沒有id可以用
+type noKeyStrategy struct{}
+
+func (noKeyStrategy) Assign(tx *pg.Tx, url *URL) error {
+	return errors.New(PGErrMsgNoKeyAvailable)
+}
+
+var _ = Describe("KeyStrategy", func() {
+	BeforeEach(requirePG)
+
+	alphabet, _ := shortid.Alphabet(shortid.DefaultCharset)
+	codec, _ := idgen.NewCodec(alphabet, shortid.DefaultLength)
+	permutation := idgen.NewPermutation(codec.Size(), []byte("test-secret"))
+
+	insertPoolKeys := func(n int) []string {
+		generator := randstr.NewFastGenerator(randstr.CharSetEnglishAlphabet)
+		keys := make([]Key, 0, n)
+		ids := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			id := generator.GenerateRandomStr(shortid.DefaultLength)
+			keys = append(keys, Key{ID: id, CreatedAt: time.Now()})
+			ids = append(ids, id)
+		}
+		_, err := testPGClient.Model(&keys).Insert()
+		Expect(err).To(BeNil())
+		return ids
+	}
+
+	describeKeyStrategy(KeyStrategyPool, func() KeyStrategy { return NewPoolKeyStrategy() }, insertPoolKeys)
+
+	// 一半從記憶體拿 用完之後退回pool
+	leaseSource := &sliceKeySource{}
+	describeKeyStrategy("lease", func() KeyStrategy { return NewLeaseKeyStrategy(leaseSource, NewPoolKeyStrategy()) }, func(n int) []string {
+		generator := randstr.NewFastGenerator(randstr.CharSetEnglishAlphabet)
+		leaseSource.keys = nil
+		for i := 0; i < n/2; i++ {
+			leaseSource.keys = append(leaseSource.keys, generator.GenerateRandomStr(shortid.DefaultLength))
+		}
+		return append(append([]string{}, leaseSource.keys...), insertPoolKeys(n-n/2)...)
+	})
+
+	describeKeyStrategy(KeyStrategySequence, func() KeyStrategy { return NewSequenceKeyStrategy(codec, permutation) }, func(n int) []string {
+		return nil
+	})
+
+	describeKeyStrategy(KeyStrategyHash, func() KeyStrategy { return NewHashKeyStrategy(codec) }, func(n int) []string {
+		return nil
+	})
+
+	Context("sequence skip taken and quarantined ids", func() {
+		var next int64
+		var takenURL URL
+		var quarantinedKey QuarantinedKey
+		var url *URL
+		var assignErr error
+
+		BeforeEach(func() {
+			_, err := testPGClient.QueryOne(pg.Scan(&next), "SELECT nextval('url_id_seq')")
+			Expect(err).To(BeNil())
+			now := time.Now()
+			takenURL = URL{ID: codec.Encode(permutation.Forward(uint64(next + 1))), Original: "http://example.com/taken", CreatedAt: now, ExpiredAt: now.Add(time.Hour)}
+			quarantinedKey = QuarantinedKey{ID: codec.Encode(permutation.Forward(uint64(next + 2))), QuarantinedAt: now, ReleaseAt: now.Add(time.Hour)}
+			_, err = testPGClient.Model(&takenURL).Insert()
+			Expect(err).To(BeNil())
+			_, err = testPGClient.Model(&quarantinedKey).Insert()
+			Expect(err).To(BeNil())
+
+			url = &URL{Original: "http://example.com/new", CreatedAt: now, ExpiredAt: now.Add(time.Hour)}
+			assignErr = testPGClient.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+				return NewSequenceKeyStrategy(codec, permutation).Assign(tx, url)
+			})
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model((*URL)(nil)).WhereIn("id in (?)", []string{takenURL.ID, url.ID}).Delete()
+			Expect(err).To(BeNil())
+			_, err = testPGClient.Model(&quarantinedKey).WherePK().Delete()
+			Expect(err).To(BeNil())
+		})
+
+		It("result", func() {
+			Expect(assignErr).To(BeNil())
+			Expect(url.ID).To(Equal(codec.Encode(permutation.Forward(uint64(next + 3)))))
+		})
+	})
+
+	Context("hash give up after max probes", func() {
+		var takenURLs []URL
+		var assignErr error
+
+		BeforeEach(func() {
+			now := time.Now()
+			original := "http://example.com/crowded"
+			takenURLs = nil
+			for attempt := 0; attempt < hashKeyMaxProbes; attempt++ {
+				takenURLs = append(takenURLs, URL{ID: codec.Encode(idgen.HashIndex(original, attempt, codec.Size())), Original: "http://example.com/other", CreatedAt: now, ExpiredAt: now.Add(time.Hour)})
+			}
+			_, err := testPGClient.Model(&takenURLs).Insert()
+			Expect(err).To(BeNil())
+
+			assignErr = testPGClient.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+				return NewHashKeyStrategy(codec).Assign(tx, &URL{Original: original, CreatedAt: now, ExpiredAt: now.Add(time.Hour)})
+			})
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model(&takenURLs).WherePK().Delete()
+			Expect(err).To(BeNil())
+		})
+
+		It("result", func() {
+			Expect(assignErr).To(Equal(errors.New(PGErrMsgNoKeyAvailable)))
+		})
+	})
+
+	Context("lease release keys on rollback", func() {
+		var source *sliceKeySource
+		var urlDAO *PGUrlDAO
+
+		BeforeEach(func() {
+			generator := randstr.NewFastGenerator(randstr.CharSetEnglishAlphabet)
+			source = &sliceKeySource{keys: []string{generator.GenerateRandomStr(shortid.DefaultLength)}}
+			urlDAO = NewPGUrlDAO(loglib.NewNopLogger(), testPGClient, 0, NewLeaseKeyStrategy(source, noKeyStrategy{}))
+		})
+
+		It("result", func() {
+			leased := source.keys[0]
+			// 第一個用掉lease的key 第二個沒有key可以用 整個transaction rollback
+			_, err := urlDAO.Import(context.Background(), []URL{{Original: "http://example.com/a"}, {Original: "http://example.com/b"}})
+			Expect(err).NotTo(BeNil())
+			Expect(err.BusinessCode).To(Equal(business.CapacityExhausted))
+			Expect(source.keys).To(Equal([]string{leased}))
+			Ω(testPGClient.Model((*URL)(nil)).Where("id = ?", leased).Count()).To(Equal(0))
+		})
+	})
+
+	Context("NewKeyStrategy", func() {
+		It("build every strategy", func() {
+			for _, name := range KeyStrategies() {
+				strategy, err := NewKeyStrategy(KeyStrategyConfig{Strategy: name, Charset: shortid.DefaultCharset, Length: shortid.DefaultLength, Secret: "secret"})
+				Expect(err).To(BeNil())
+				Expect(strategy).NotTo(BeNil())
+			}
+		})
+
+		It("fail with bad config", func() {
+			_, err := NewKeyStrategy(KeyStrategyConfig{Strategy: "uuid"})
+			Expect(err).NotTo(BeNil())
+			_, err = NewKeyStrategy(KeyStrategyConfig{Strategy: KeyStrategySequence, Charset: shortid.DefaultCharset, Length: shortid.DefaultLength})
+			Expect(err).NotTo(BeNil())
+			_, err = NewKeyStrategy(KeyStrategyConfig{Strategy: KeyStrategyHash, Charset: shortid.CharsetBase62, Length: shortid.MaxLength})
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
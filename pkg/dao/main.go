@@ -5,7 +5,6 @@ import (
 	"time"
 )
 
-const randStrLength = 6
 const expiredDuration = 1 * time.Hour
 
 const prefixHotOriginalURL = "ORIGINAL-URL-ID"
@@ -18,4 +17,20 @@ func getRandomOriginalURLTTLSecond() time.Duration {
 	return time.Duration(randomFunc(int64(randomOriginalURLTTLNumber))+1) * time.Second
 }
 
+// 查不到的id也放進cache 值是這個sentinel 正常的網址不可能以NUL開頭
+const originalURLNotFoundSentinel = "\x00NOT-FOUND"
+
 const originalURLIDsFilterName = "FILTER-ORIGINAL-URL-IDs"
+
+// 重建filter時先寫到這個key 建好再RENAME成originalURLIDsFilterName
+const rebuildOriginalURLIDsFilterName = "FILTER-ORIGINAL-URL-IDs-REBUILD"
+
+// 重建期間這個key存在 加進filter的id同時記在rebuildAddedOriginalURLIDsName 換上新的filter之後補進去
+const rebuildingOriginalURLIDsFilterName = "FILTER-ORIGINAL-URL-IDs-REBUILDING"
+const rebuildAddedOriginalURLIDsName = "FILTER-ORIGINAL-URL-IDs-REBUILD-ADDED"
+
+// 刪除originalURL cache時通知所有instance清掉自己的L1 cache
+const originalURLInvalidationChannel = "ORIGINAL-URL-INVALIDATION"
+
+// filter backend是local時 通知所有instance新增或刪除的url id
+const originalURLIDFilterChannel = "ORIGINAL-URL-ID-FILTER"
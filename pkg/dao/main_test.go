@@ -1,7 +1,10 @@
 package dao
 
 import (
+	"database/sql"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -21,21 +24,44 @@ func TestDao(t *testing.T) {
 
 var testRedisClient *redislib.GORedisClient
 var testPGClient *pglib.GOPGClient
+var testSQLiteDB *sql.DB
+var testSQLiteDir string
 
 var _ = BeforeSuite(func() {
 	testPGClient = setupTestPG()
 	testRedisClient = setupTestRedis()
+	testSQLiteDB = setupTestSQLite()
 })
 
 var _ = AfterSuite(func() {
-	testPGClient.Close()
-	testRedisClient.Close()
+	if testPGClient != nil {
+		testPGClient.Close()
+	}
+	if testRedisClient != nil {
+		testRedisClient.Close()
+	}
+	testSQLiteDB.Close()
+	os.RemoveAll(testSQLiteDir)
 })
 
+// requirePG 沒有設定POSTGRES_URL時skip memory的測試不需要任何外部服務
+func requirePG() {
+	if testPGClient == nil {
+		Skip("POSTGRES_URL is not set")
+	}
+}
+
+// requireRedis 沒有設定REDIS_URL時skip
+func requireRedis() {
+	if testRedisClient == nil {
+		Skip("REDIS_URL is not set")
+	}
+}
+
 func setupTestPG() *pglib.GOPGClient {
 	pgURL := os.Getenv("POSTGRES_URL")
 	if pgURL == "" {
-		panic("should setup postgres url")
+		return nil
 	}
 
 	pgDebugMode, _ := strconv.ParseBool(os.Getenv("pgDebugMode"))
@@ -70,7 +96,7 @@ func setupTestPG() *pglib.GOPGClient {
 func setupTestRedis() *redislib.GORedisClient {
 	redisURL := os.Getenv("REDIS_URL")
 	if redisURL == "" {
-		panic("should setup redis url")
+		return nil
 	}
 
 	redisClient, err := redislib.NewGORedisClient(redislib.GORedisConfig{URL: redisURL}, nil)
@@ -79,3 +105,20 @@ func setupTestRedis() *redislib.GORedisClient {
 
 	return redisClient
 }
+
+// setupTestSQLite sqlite不需要外部服務 每次在暫存目錄建一個新的檔案跑migration
+func setupTestSQLite() *sql.DB {
+	var err error
+	testSQLiteDir, err = ioutil.TempDir("", "shortening-url-dao")
+	Expect(err).To(BeNil())
+
+	db, err := NewSQLiteClient(filepath.Join(testSQLiteDir, "test.db"))
+	Expect(err).To(BeNil())
+
+	migrationFileDir := os.Getenv("SQLITE_MIGRATION_FILE_DIR")
+	if migrationFileDir == "" {
+		migrationFileDir = "file://../../migrations/sqlite"
+	}
+	Expect(MigrateSQLite(db, migrationFileDir)).To(BeNil())
+	return db
+}
@@ -0,0 +1,63 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+)
+
+var (
+	MemoryErrNoRowsFound    = errors.New("memory: no rows in result set")
+	MemoryErrNoKeyAvailable = errors.New("memory: no key available in keys")
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		urls:            make(map[string]URL),
+		keys:            make(map[string]Key),
+		quarantinedKeys: make(map[string]QuarantinedKey),
+		healthChecks:    make(map[string]HealthCheck),
+	}
+}
+
+// MemoryStore 對應postgres裡的urls、keys、quarantined_keys以及url_health_checks 放在process的記憶體裡
+// 同一個store的DAO共用資料 process結束就不見了 只適合開發跟測試
+type MemoryStore struct {
+	mu              sync.Mutex
+	urls            map[string]URL
+	keys            map[string]Key
+	quarantinedKeys map[string]QuarantinedKey
+	healthChecks    map[string]HealthCheck
+}
+
+// lock ctx已經結束就不拿lock 跟postgres一樣回傳context error
+func (s *MemoryStore) lock(ctx context.Context) *business.Error {
+	if err := ctx.Err(); err != nil {
+		return business.NewContextError(err)
+	}
+	s.mu.Lock()
+	return nil
+}
+
+// used id已經在urls、keys或冷卻中
+func (s *MemoryStore) used(id string) bool {
+	if _, ok := s.urls[id]; ok {
+		return true
+	}
+	if _, ok := s.keys[id]; ok {
+		return true
+	}
+	_, ok := s.quarantinedKeys[id]
+	return ok
+}
+
+func memoryNotFoundError() *business.Error {
+	return business.NewError(business.NotFound, http.StatusNotFound, "record not found", MemoryErrNoRowsFound)
+}
+
+func memoryNoKeyAvailableError() *business.Error {
+	return business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", MemoryErrNoKeyAvailable)
+}
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
@@ -14,8 +15,29 @@ type URL struct {
 }
 
 type UrlDAO interface {
-	Create(originalURL string) (*URL, *business.Error)
-	Get(id string) (*URL, *business.Error)
-	Delete(id string) *business.Error
-	Expire(num int) ([]string, *business.Error)
+	Create(ctx context.Context, originalURL string) (*URL, *business.Error)
+	Get(ctx context.Context, id string) (*URL, *business.Error)
+	// Find 跟Get一樣 但是過期的也會找出來
+	Find(ctx context.Context, id string) (*URL, *business.Error)
+	// ForceExpire 把還沒過期的url的expired_at設成現在
+	ForceExpire(ctx context.Context, id string) *business.Error
+	Delete(ctx context.Context, id string) *business.Error
+	Expire(ctx context.Context, num int) ([]string, *business.Error)
+	// List 以id排序 透過afterID往下一頁拿
+	List(ctx context.Context, afterID string, limit int, includeExpired bool) ([]URL, *business.Error)
+	// ListRecent 還沒過期的url由新到舊 before是上一頁最後一筆 第一頁傳nil
+	ListRecent(ctx context.Context, before *URL, limit int) ([]URL, *business.Error)
+	// Import 原本的id沒被用過就沿用 否則重新分配
+	Import(ctx context.Context, urls []URL) ([]ImportResult, *business.Error)
+}
+
+// ImportResult 匯入一筆url的結果 OriginalID是匯入檔案裡的id ID是實際寫入的id
+type ImportResult struct {
+	OriginalID string `json:"originalId"`
+	ID         string `json:"id"`
+	URL        URL    `json:"-"`
+}
+
+func (r ImportResult) Preserved() bool {
+	return r.OriginalID == r.ID
 }
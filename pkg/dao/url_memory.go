@@ -0,0 +1,233 @@
+package dao
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+)
+
+// NewMemoryUrlDAO 新的id一律從store的keys拿 等同pool strategy
+func NewMemoryUrlDAO(store *MemoryStore, recycleCooldown time.Duration) *MemoryUrlDAO {
+	return &MemoryUrlDAO{store: store, recycleCooldown: recycleCooldown}
+}
+
+type MemoryUrlDAO struct {
+	store *MemoryStore
+	// 大於0時 刪除的url id會先放進quarantinedKeys 冷卻期過後才會回到keys 0則直接丟掉
+	recycleCooldown time.Duration
+}
+
+func (m *MemoryUrlDAO) Create(ctx context.Context, originalURL string) (*URL, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+	url := URL{Original: originalURL, CreatedAt: now, ExpiredAt: now.Add(expiredDuration)}
+	if _, ok := m.claimKey(&url); !ok {
+		return nil, memoryNoKeyAvailableError()
+	}
+	return &url, nil
+}
+
+// claimKey 從keys拿一個key當作url的id並寫入urls 回傳被拿走的key
+func (m *MemoryUrlDAO) claimKey(url *URL) (Key, bool) {
+	for id, key := range m.store.keys {
+		delete(m.store.keys, id)
+		url.ID = id
+		m.store.urls[id] = *url
+		return key, true
+	}
+	return Key{}, false
+}
+
+// Import 全部成功才寫入 中途沒有key可以用時 已經寫入的url以及拿走的key都會還原
+func (m *MemoryUrlDAO) Import(ctx context.Context, urls []URL) ([]ImportResult, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	results := make([]ImportResult, 0, len(urls))
+	claimed := make(map[string]Key)
+	now := time.Now()
+	for _, u := range urls {
+		url := u
+		if url.CreatedAt.IsZero() {
+			url.CreatedAt = now
+		}
+		if url.ExpiredAt.IsZero() {
+			url.ExpiredAt = now.Add(expiredDuration)
+		}
+
+		if shortid.Valid(url.ID) && m.free(url.ID) {
+			if key, ok := m.store.keys[url.ID]; ok {
+				claimed[url.ID] = key
+				delete(m.store.keys, url.ID)
+			}
+			m.store.urls[url.ID] = url
+			results = append(results, ImportResult{OriginalID: u.ID, ID: url.ID, URL: url})
+			continue
+		}
+
+		key, ok := m.claimKey(&url)
+		if !ok {
+			for _, result := range results {
+				delete(m.store.urls, result.ID)
+			}
+			for id, key := range claimed {
+				m.store.keys[id] = key
+			}
+			return nil, memoryNoKeyAvailableError()
+		}
+		claimed[url.ID] = key
+		results = append(results, ImportResult{OriginalID: u.ID, ID: url.ID, URL: url})
+	}
+	return results, nil
+}
+
+// free 冷卻中的id也算被用過 避免舊的連結指到別的網址
+func (m *MemoryUrlDAO) free(id string) bool {
+	if _, ok := m.store.urls[id]; ok {
+		return false
+	}
+	_, ok := m.store.quarantinedKeys[id]
+	return !ok
+}
+
+func (m *MemoryUrlDAO) Get(ctx context.Context, id string) (*URL, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	url, ok := m.store.urls[id]
+	if !ok || !url.ExpiredAt.After(time.Now()) {
+		return nil, memoryNotFoundError()
+	}
+	return &url, nil
+}
+
+func (m *MemoryUrlDAO) Find(ctx context.Context, id string) (*URL, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	url, ok := m.store.urls[id]
+	if !ok {
+		return nil, memoryNotFoundError()
+	}
+	return &url, nil
+}
+
+func (m *MemoryUrlDAO) ForceExpire(ctx context.Context, id string) *business.Error {
+	if err := m.store.lock(ctx); err != nil {
+		return err
+	}
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+	url, ok := m.store.urls[id]
+	if !ok || !url.ExpiredAt.After(now) {
+		return memoryNotFoundError()
+	}
+	url.ExpiredAt = now
+	m.store.urls[id] = url
+	return nil
+}
+
+func (m *MemoryUrlDAO) Delete(ctx context.Context, id string) *business.Error {
+	if err := m.store.lock(ctx); err != nil {
+		return err
+	}
+	defer m.store.mu.Unlock()
+
+	if _, ok := m.store.urls[id]; ok {
+		m.remove([]string{id})
+	}
+	return nil
+}
+
+func (m *MemoryUrlDAO) Expire(ctx context.Context, num int) ([]string, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	var ids []string
+	now := time.Now()
+	for id, url := range m.store.urls {
+		if len(ids) >= num {
+			break
+		}
+		if url.ExpiredAt.Before(now) {
+			ids = append(ids, id)
+		}
+	}
+	m.remove(ids)
+	return ids, nil
+}
+
+// remove 跟postgres的ON DELETE CASCADE一樣 health check一起刪掉 有開啟回收時id進入冷卻
+func (m *MemoryUrlDAO) remove(ids []string) {
+	for _, id := range ids {
+		delete(m.store.urls, id)
+		delete(m.store.healthChecks, id)
+	}
+	if m.recycleCooldown <= 0 {
+		return
+	}
+	now := time.Now()
+	for _, id := range ids {
+		m.store.quarantinedKeys[id] = QuarantinedKey{ID: id, QuarantinedAt: now, ReleaseAt: now.Add(m.recycleCooldown)}
+	}
+}
+
+func (m *MemoryUrlDAO) List(ctx context.Context, afterID string, limit int, includeExpired bool) ([]URL, *business.Error) {
+	now := time.Now()
+	return m.list(ctx, limit, func(u URL) bool {
+		return u.ID > afterID && (includeExpired || u.ExpiredAt.After(now))
+	}, func(a, b URL) bool {
+		return a.ID < b.ID
+	})
+}
+
+func (m *MemoryUrlDAO) ListRecent(ctx context.Context, before *URL, limit int) ([]URL, *business.Error) {
+	now := time.Now()
+	newer := func(a, b URL) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+	return m.list(ctx, limit, func(u URL) bool {
+		return u.ExpiredAt.After(now) && (before == nil || newer(*before, u))
+	}, newer)
+}
+
+// list 跟SQL的WHERE ORDER BY LIMIT一樣 沒有符合的回傳nil
+func (m *MemoryUrlDAO) list(ctx context.Context, limit int, match func(u URL) bool, less func(a, b URL) bool) ([]URL, *business.Error) {
+	if err := m.store.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.store.mu.Unlock()
+
+	var urls []URL
+	for _, url := range m.store.urls {
+		if match(url) {
+			urls = append(urls, url)
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return less(urls[i], urls[j])
+	})
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
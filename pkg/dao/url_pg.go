@@ -6,51 +6,110 @@ import (
 	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/KennyChenFight/golib/pglib"
 	"github.com/go-pg/pg/v10"
 )
 
-func NewPGUrlDAO(logger *loglib.Logger, client *pglib.GOPGClient) *PGUrlDAO {
-	return &PGUrlDAO{logger: logger, client: client}
+func NewPGUrlDAO(logger *loglib.Logger, client *pglib.GOPGClient, recycleCooldown time.Duration, keyStrategy KeyStrategy) *PGUrlDAO {
+	return &PGUrlDAO{logger: logger, client: client, recycleCooldown: recycleCooldown, keyStrategy: keyStrategy}
 }
 
 type PGUrlDAO struct {
 	logger *loglib.Logger
 	client *pglib.GOPGClient
+	// 大於0時 刪除的url id會先放進quarantined_keys 冷卻期過後才會回到keys 0則直接丟掉
+	recycleCooldown time.Duration
+	keyStrategy     KeyStrategy
 }
 
-func (p *PGUrlDAO) Create(originalURL string) (*URL, *business.Error) {
-	var url URL
-	var key Key
+func (p *PGUrlDAO) Create(ctx context.Context, originalURL string) (*URL, *business.Error) {
 	now := time.Now()
-	err := p.client.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
-		res, err := tx.Model((*URL)(nil)).Query(pg.Scan(&url.ID, &url.Original, &url.CreatedAt, &url.ExpiredAt), "INSERT INTO urls SELECT id, ?, ?, ? FROM keys FOR UPDATE SKIP LOCKED LIMIT 1 RETURNING id, original", originalURL, now, now.Add(expiredDuration))
-		if err != nil {
-			return err
-		}
-		if res.RowsReturned() == 0 {
-			return errors.New(PGErrMsgNoRowsFound)
-		}
+	url := URL{Original: originalURL, CreatedAt: now, ExpiredAt: now.Add(expiredDuration)}
+	err := p.runInAssignTx(ctx, func(tx *pg.Tx) error {
+		return p.keyStrategy.Assign(tx, &url)
+	})
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return &url, nil
+}
 
-		key.ID = url.ID
-		_, err = tx.Model(&key).WherePK().Delete()
-		if err != nil {
-			return err
+// Import 在同一個transaction裡寫入一批url 原本的id沒被用過就沿用 否則由keyStrategy重新分配
+func (p *PGUrlDAO) Import(ctx context.Context, urls []URL) ([]ImportResult, *business.Error) {
+	results := make([]ImportResult, 0, len(urls))
+	now := time.Now()
+	err := p.runInAssignTx(ctx, func(tx *pg.Tx) error {
+		results = results[:0]
+		for _, u := range urls {
+			url := u
+			if url.CreatedAt.IsZero() {
+				url.CreatedAt = now
+			}
+			if url.ExpiredAt.IsZero() {
+				url.ExpiredAt = now.Add(expiredDuration)
+			}
+
+			if shortid.Valid(url.ID) {
+				preserved, err := insertIfFree(tx, &url)
+				if err != nil {
+					return err
+				}
+				if preserved {
+					results = append(results, ImportResult{OriginalID: u.ID, ID: url.ID, URL: url})
+					continue
+				}
+			}
+
+			if err := p.keyStrategy.Assign(tx, &url); err != nil {
+				return err
+			}
+			results = append(results, ImportResult{OriginalID: u.ID, ID: url.ID, URL: url})
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, pgErrorHandle(p.logger, err)
 	}
-	return &url, nil
+	return results, nil
 }
 
-func (p *PGUrlDAO) Get(id string) (*URL, *business.Error) {
+// runInAssignTx 跟RunInTransaction一樣 結束之後告訴keyStrategy有沒有commit
+func (p *PGUrlDAO) runInAssignTx(ctx context.Context, fn func(tx *pg.Tx) error) error {
+	var assignTx *pg.Tx
+	err := p.client.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		assignTx = tx
+		return fn(tx)
+	})
+	if finisher, ok := p.keyStrategy.(KeyStrategyFinisher); ok && assignTx != nil {
+		finisher.Finish(assignTx, err == nil)
+	}
+	return err
+}
+
+// insertIfFree 用url現在的id寫入 id已經被用過或是還在冷卻中就回傳false
+func insertIfFree(tx *pg.Tx, url *URL) (bool, error) {
+	// 冷卻中的id也算被用過 避免舊的連結指到別的網址
+	quarantined, err := tx.Model((*QuarantinedKey)(nil)).Where("id = ?", url.ID).Exists()
+	if err != nil || quarantined {
+		return false, err
+	}
+
+	res, err := tx.Model(url).OnConflict("DO NOTHING").Insert()
+	if err != nil || res.RowsAffected() == 0 {
+		return false, err
+	}
+	// 用掉的id不能再被key pool發出去
+	_, err = tx.Model(&Key{ID: url.ID}).WherePK().Delete()
+	return err == nil, err
+}
+
+func (p *PGUrlDAO) Get(ctx context.Context, id string) (*URL, *business.Error) {
 	url := &URL{
 		ID: id,
 	}
-	err := p.client.Model(url).
+	err := p.client.ModelContext(ctx, url).
 		WherePK().
 		Where("expired_at > ?", time.Now()).Select()
 	if err != nil {
@@ -59,26 +118,116 @@ func (p *PGUrlDAO) Get(id string) (*URL, *business.Error) {
 	return url, nil
 }
 
-func (p *PGUrlDAO) Delete(id string) *business.Error {
+func (p *PGUrlDAO) Find(ctx context.Context, id string) (*URL, *business.Error) {
 	url := &URL{
 		ID: id,
 	}
-	_, err := p.client.Model(url).WherePK().Delete()
+	err := p.client.ModelContext(ctx, url).WherePK().Select()
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return url, nil
+}
+
+func (p *PGUrlDAO) ForceExpire(ctx context.Context, id string) *business.Error {
+	now := time.Now()
+	res, err := p.client.ModelContext(ctx, (*URL)(nil)).
+		Set("expired_at = ?", now).
+		Where("id = ?", id).
+		Where("expired_at > ?", now).
+		Update()
 	if err != nil {
 		return pgErrorHandle(p.logger, err)
 	}
+	if res.RowsAffected() == 0 {
+		return pgErrorHandle(p.logger, errors.New(PGErrMsgNoRowsFound))
+	}
 	return nil
 }
 
-func (p *PGUrlDAO) Expire(num int) ([]string, *business.Error) {
+func (p *PGUrlDAO) Delete(ctx context.Context, id string) *business.Error {
+	err := p.client.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		var ids []string
+		_, err := tx.Model((*URL)(nil)).
+			Where("id = ?", id).
+			Returning("id").
+			Delete(&ids)
+		if err != nil {
+			return err
+		}
+		return p.quarantine(tx, ids)
+	})
+	if err != nil {
+		return pgErrorHandle(p.logger, err)
+	}
+	return nil
+}
+
+func (p *PGUrlDAO) Expire(ctx context.Context, num int) ([]string, *business.Error) {
 	var ids []string
-	subQuery := p.client.Model((*URL)(nil)).Column("id").Where("expired_at < ?", time.Now()).Limit(num)
-	_, err := p.client.Model((*URL)(nil)).
-		Where("id in (?)", subQuery).
-		Returning("id").
-		Delete(&ids)
+	err := p.client.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		ids = ids[:0]
+		subQuery := tx.Model((*URL)(nil)).Column("id").Where("expired_at < ?", time.Now()).Limit(num)
+		_, err := tx.Model((*URL)(nil)).
+			Where("id in (?)", subQuery).
+			Returning("id").
+			Delete(&ids)
+		if err != nil {
+			return err
+		}
+		return p.quarantine(tx, ids)
+	})
 	if err != nil {
 		return ids, pgErrorHandle(p.logger, err)
 	}
 	return ids, nil
 }
+
+// quarantine 沒有開啟回收時什麼都不做
+func (p *PGUrlDAO) quarantine(tx *pg.Tx, ids []string) error {
+	if p.recycleCooldown <= 0 || len(ids) == 0 {
+		return nil
+	}
+	now := time.Now()
+	keys := make([]QuarantinedKey, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, QuarantinedKey{ID: id, QuarantinedAt: now, ReleaseAt: now.Add(p.recycleCooldown)})
+	}
+	_, err := tx.Model(&keys).
+		OnConflict("(id) DO UPDATE").
+		Set("quarantined_at = EXCLUDED.quarantined_at, release_at = EXCLUDED.release_at").
+		Insert()
+	return err
+}
+
+func (p *PGUrlDAO) List(ctx context.Context, afterID string, limit int, includeExpired bool) ([]URL, *business.Error) {
+	var urls []URL
+	query := p.client.ModelContext(ctx, &urls).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit)
+	if !includeExpired {
+		query = query.Where("expired_at > ?", time.Now())
+	}
+	err := query.Select()
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return urls, nil
+}
+
+func (p *PGUrlDAO) ListRecent(ctx context.Context, before *URL, limit int) ([]URL, *business.Error) {
+	var urls []URL
+	query := p.client.ModelContext(ctx, &urls).
+		Where("expired_at > ?", time.Now()).
+		Order("created_at DESC", "id DESC").
+		Limit(limit)
+	if before != nil {
+		query = query.Where("(created_at, id) < (?, ?)", before.CreatedAt, before.ID)
+	}
+	err := query.Select()
+	if err != nil {
+		return nil, pgErrorHandle(p.logger, err)
+	}
+	return urls, nil
+}
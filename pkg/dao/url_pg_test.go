@@ -1,23 +1,28 @@
 package dao
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/golib/loglib"
+	"github.com/go-pg/pg/v10"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("PGUrlDAO", func() {
+	BeforeEach(requirePG)
+
+	ctx := context.Background()
 	var pgUrlDAO *PGUrlDAO
 	var logger *loglib.Logger
 
 	BeforeEach(func() {
 		logger = loglib.NewNopLogger()
-		pgUrlDAO = &PGUrlDAO{logger, testPGClient}
+		pgUrlDAO = &PGUrlDAO{logger, testPGClient, 0, NewPoolKeyStrategy()}
 	})
 
 	var _ = Describe("Create", func() {
@@ -39,7 +44,7 @@ var _ = Describe("PGUrlDAO", func() {
 		}
 
 		JustBeforeEach(func() {
-			expectURL, createErr = pgUrlDAO.Create(actualOriginalURL)
+			expectURL, createErr = pgUrlDAO.Create(ctx, actualOriginalURL)
 		})
 
 		Context("success", func() {
@@ -62,9 +67,9 @@ var _ = Describe("PGUrlDAO", func() {
 			})
 		})
 
-		Context("key not found", func() {
+		Context("key pool exhausted", func() {
 			It("result", func() {
-				Expect(createErr).To(Equal(business.NewError(business.NotFound, http.StatusNotFound, "record not found", errors.New(PGErrMsgNoRowsFound))))
+				Expect(createErr).To(Equal(business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", errors.New(PGErrMsgNoKeyAvailable))))
 			})
 		})
 	})
@@ -84,7 +89,7 @@ var _ = Describe("PGUrlDAO", func() {
 		}
 
 		JustBeforeEach(func() {
-			expectURL, getErr = pgUrlDAO.Get(actualURL.ID)
+			expectURL, getErr = pgUrlDAO.Get(ctx, actualURL.ID)
 		})
 
 		Context("success", func() {
@@ -130,6 +135,88 @@ var _ = Describe("PGUrlDAO", func() {
 		})
 	})
 
+	var _ = Describe("Find", func() {
+		var (
+			expectURL *URL
+			findErr   *business.Error
+		)
+
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		actualURL := &URL{
+			ID:        "random",
+			Original:  "http://example.com",
+			CreatedAt: now,
+			ExpiredAt: now.Add(-time.Minute),
+		}
+
+		JustBeforeEach(func() {
+			expectURL, findErr = pgUrlDAO.Find(ctx, actualURL.ID)
+		})
+
+		Context("success even expired", func() {
+			BeforeEach(func() {
+				_, err := testPGClient.Model(actualURL).Insert()
+				Expect(err).To(BeNil())
+			})
+
+			AfterEach(func() {
+				_, err := testPGClient.Model(actualURL).WherePK().Delete()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(findErr).To(BeNil())
+				Expect(expectURL).To(Equal(actualURL))
+			})
+		})
+
+		Context("url not found", func() {
+			It("result", func() {
+				Expect(findErr).To(Equal(business.NewError(business.NotFound, http.StatusNotFound, "record not found", findErr.Reason)))
+				Expect(expectURL).To(BeNil())
+			})
+		})
+	})
+
+	var _ = Describe("ForceExpire", func() {
+		var expireErr *business.Error
+
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		actualURL := &URL{
+			ID:        "random",
+			Original:  "http://example.com",
+			CreatedAt: now,
+			ExpiredAt: now.Add(time.Hour),
+		}
+
+		JustBeforeEach(func() {
+			expireErr = pgUrlDAO.ForceExpire(ctx, actualURL.ID)
+		})
+
+		Context("success", func() {
+			BeforeEach(func() {
+				_, err := testPGClient.Model(actualURL).Insert()
+				Expect(err).To(BeNil())
+			})
+
+			AfterEach(func() {
+				_, err := testPGClient.Model(actualURL).WherePK().Delete()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(expireErr).To(BeNil())
+				Ω(testPGClient.Model(&URL{}).Where("id = ?", actualURL.ID).Where("expired_at > ?", time.Now()).Count()).To(Equal(0))
+			})
+		})
+
+		Context("url not found or already expired", func() {
+			It("result", func() {
+				Expect(expireErr).To(Equal(business.NewError(business.NotFound, http.StatusNotFound, "record not found", errors.New(PGErrMsgNoRowsFound))))
+			})
+		})
+	})
+
 	var _ = Describe("Delete", func() {
 		var (
 			deleteErr *business.Error
@@ -144,7 +231,7 @@ var _ = Describe("PGUrlDAO", func() {
 		}
 
 		JustBeforeEach(func() {
-			deleteErr = pgUrlDAO.Delete(actualURL.ID)
+			deleteErr = pgUrlDAO.Delete(ctx, actualURL.ID)
 		})
 
 		Context("success", func() {
@@ -156,6 +243,27 @@ var _ = Describe("PGUrlDAO", func() {
 			It("result", func() {
 				Expect(deleteErr).To(BeNil())
 				Expect(testPGClient.Model((*URL)(nil)).Where("id = ?", actualURL.ID).Select()).To(HaveOccurred())
+				Ω(testPGClient.Model(&QuarantinedKey{}).Where("id = ?", actualURL.ID).Count()).To(Equal(0))
+			})
+		})
+
+		Context("quarantine id when recycling enabled", func() {
+			BeforeEach(func() {
+				pgUrlDAO.recycleCooldown = time.Hour
+				_, err := testPGClient.Model(actualURL).Insert()
+				Expect(err).To(BeNil())
+			})
+
+			AfterEach(func() {
+				_, err := testPGClient.Model(&QuarantinedKey{ID: actualURL.ID}).WherePK().Delete()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(deleteErr).To(BeNil())
+				var quarantined QuarantinedKey
+				Expect(testPGClient.Model(&quarantined).Where("id = ?", actualURL.ID).Select()).To(BeNil())
+				Expect(quarantined.ReleaseAt).To(BeTemporally("~", quarantined.QuarantinedAt.Add(time.Hour), time.Second))
 			})
 		})
 	})
@@ -194,7 +302,7 @@ var _ = Describe("PGUrlDAO", func() {
 		}
 
 		JustBeforeEach(func() {
-			expectExpireIDs, expireErr = pgUrlDAO.Expire(actualLimitNum)
+			expectExpireIDs, expireErr = pgUrlDAO.Expire(ctx, actualLimitNum)
 		})
 
 		Context("success", func() {
@@ -217,6 +325,231 @@ var _ = Describe("PGUrlDAO", func() {
 				Expect(expectExpireIDs).To(Equal(actualExpireIDs))
 			})
 		})
+
+		Context("quarantine ids when recycling enabled", func() {
+			BeforeEach(func() {
+				pgUrlDAO.recycleCooldown = time.Hour
+				_, err := testPGClient.Model(&actualURLs).Insert()
+				Expect(err).To(BeNil())
+			})
+
+			AfterEach(func() {
+				_, err := testPGClient.Model((*URL)(nil)).WhereIn("id in (?)", []string{actualURLs[actualLimitNum].ID}).Delete()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model((*QuarantinedKey)(nil)).WhereIn("id in (?)", actualExpireIDs).Delete()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(expireErr).To(BeNil())
+				Expect(expectExpireIDs).To(Equal(actualExpireIDs))
+				Ω(testPGClient.Model(&QuarantinedKey{}).WhereIn("id in (?)", actualExpireIDs).Count()).To(Equal(len(actualExpireIDs)))
+			})
+		})
+	})
+
+	var _ = Describe("List", func() {
+		var (
+			expectURLs []URL
+			listErr    *business.Error
+		)
+
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		actualURLs := []URL{
+			{
+				ID:        "aaaaaa",
+				Original:  "http://example.com",
+				CreatedAt: now,
+				ExpiredAt: now.Add(time.Minute),
+			},
+			{
+				ID:        "bbbbbb",
+				Original:  "http://example.com",
+				CreatedAt: now,
+				ExpiredAt: now.Add(-time.Minute),
+			},
+			{
+				ID:        "cccccc",
+				Original:  "http://example.com",
+				CreatedAt: now,
+				ExpiredAt: now.Add(time.Minute),
+			},
+		}
+		var includeExpired bool
+
+		BeforeEach(func() {
+			_, err := testPGClient.Model(&actualURLs).Insert()
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model(&actualURLs).WherePK().Delete()
+			Expect(err).To(BeNil())
+		})
+
+		JustBeforeEach(func() {
+			expectURLs, listErr = pgUrlDAO.List(ctx, "aaaaaa", 10, includeExpired)
+		})
+
+		Context("only active", func() {
+			BeforeEach(func() {
+				includeExpired = false
+			})
+
+			It("result", func() {
+				Expect(listErr).To(BeNil())
+				Expect(expectURLs).To(Equal(actualURLs[2:]))
+			})
+		})
+
+		Context("include expired", func() {
+			BeforeEach(func() {
+				includeExpired = true
+			})
+
+			It("result", func() {
+				Expect(listErr).To(BeNil())
+				Expect(expectURLs).To(Equal(actualURLs[1:]))
+			})
+		})
 	})
 
+	var _ = Describe("ListRecent", func() {
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		actualURLs := []URL{
+			{ID: "aaaaaa", Original: "http://example.com", CreatedAt: now, ExpiredAt: now.Add(time.Minute)},
+			{ID: "bbbbbb", Original: "http://example.com", CreatedAt: now, ExpiredAt: now.Add(time.Minute)},
+			{ID: "cccccc", Original: "http://example.com", CreatedAt: now.Add(time.Second), ExpiredAt: now.Add(-time.Minute)},
+			{ID: "dddddd", Original: "http://example.com", CreatedAt: now.Add(-time.Second), ExpiredAt: now.Add(time.Minute)},
+		}
+
+		BeforeEach(func() {
+			_, err := testPGClient.Model(&actualURLs).Insert()
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model(&actualURLs).WherePK().Delete()
+			Expect(err).To(BeNil())
+		})
+
+		It("active urls from newest", func() {
+			urls, err := pgUrlDAO.ListRecent(ctx, nil, 2)
+			Expect(err).To(BeNil())
+			Expect(urls).To(Equal([]URL{actualURLs[1], actualURLs[0]}))
+			urls, err = pgUrlDAO.ListRecent(ctx, &urls[1], 2)
+			Expect(err).To(BeNil())
+			Expect(urls).To(Equal([]URL{actualURLs[3]}))
+		})
+	})
+
+	var _ = Describe("Import", func() {
+		var (
+			expectResults []ImportResult
+			importErr     *business.Error
+		)
+
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		existURL := URL{
+			ID:        "taken1",
+			Original:  "http://exist.com",
+			CreatedAt: now,
+			ExpiredAt: now.Add(time.Minute),
+		}
+		freeKey := Key{ID: "free01", CreatedAt: now}
+		poolKey := Key{ID: "pool01", CreatedAt: now}
+		actualURLs := []URL{
+			{
+				ID:        freeKey.ID,
+				Original:  "http://example.com/a",
+				CreatedAt: now,
+				ExpiredAt: now.Add(time.Minute),
+			},
+			{
+				ID:        existURL.ID,
+				Original:  "http://example.com/b",
+				CreatedAt: now,
+				ExpiredAt: now.Add(time.Minute),
+			},
+		}
+
+		JustBeforeEach(func() {
+			expectResults, importErr = pgUrlDAO.Import(ctx, actualURLs)
+		})
+
+		AfterEach(func() {
+			_, err := testPGClient.Model((*URL)(nil)).Where("id IN (?)", pg.In([]string{existURL.ID, freeKey.ID, poolKey.ID})).Delete()
+			Expect(err).To(BeNil())
+			_, err = testPGClient.Model((*Key)(nil)).Where("id IN (?)", pg.In([]string{freeKey.ID, poolKey.ID})).Delete()
+			Expect(err).To(BeNil())
+		})
+
+		Context("preserve free id and reassign taken id", func() {
+			BeforeEach(func() {
+				_, err := testPGClient.Model(&existURL).Insert()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model(&[]Key{freeKey, poolKey}).Insert()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(importErr).To(BeNil())
+				Expect(expectResults).To(HaveLen(2))
+				Expect(expectResults[0].ID).To(Equal(freeKey.ID))
+				Expect(expectResults[0].Preserved()).To(BeTrue())
+				Expect(expectResults[1].OriginalID).To(Equal(existURL.ID))
+				Expect(expectResults[1].ID).To(Equal(poolKey.ID))
+				Expect(expectResults[1].Preserved()).To(BeFalse())
+				Ω(testPGClient.Model(&Key{}).Where("id IN (?)", pg.In([]string{freeKey.ID, poolKey.ID})).Count()).To(Equal(0))
+
+				var reassigned URL
+				Expect(testPGClient.Model(&reassigned).Where("id = ?", poolKey.ID).Select()).To(BeNil())
+				Expect(reassigned.Original).To(Equal(actualURLs[1].Original))
+				Expect(reassigned.ExpiredAt).To(Equal(actualURLs[1].ExpiredAt))
+			})
+		})
+
+		Context("reassign quarantined id", func() {
+			quarantinedKey := QuarantinedKey{ID: freeKey.ID, QuarantinedAt: now, ReleaseAt: now.Add(time.Hour)}
+
+			BeforeEach(func() {
+				_, err := testPGClient.Model(&existURL).Insert()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model(&quarantinedKey).Insert()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model(&[]Key{poolKey, {ID: "pool02", CreatedAt: now}}).Insert()
+				Expect(err).To(BeNil())
+			})
+
+			AfterEach(func() {
+				_, err := testPGClient.Model(&quarantinedKey).WherePK().Delete()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model((*URL)(nil)).Where("id = ?", "pool02").Delete()
+				Expect(err).To(BeNil())
+				_, err = testPGClient.Model((*Key)(nil)).Where("id = ?", "pool02").Delete()
+				Expect(err).To(BeNil())
+			})
+
+			It("result", func() {
+				Expect(importErr).To(BeNil())
+				Expect(expectResults).To(HaveLen(2))
+				Expect(expectResults[0].OriginalID).To(Equal(freeKey.ID))
+				Expect(expectResults[0].Preserved()).To(BeFalse())
+				Expect(expectResults[1].Preserved()).To(BeFalse())
+				Ω(testPGClient.Model(&URL{}).Where("id = ?", freeKey.ID).Count()).To(Equal(0))
+			})
+		})
+
+		Context("no key left for taken id", func() {
+			BeforeEach(func() {
+				_, err := testPGClient.Model(&existURL).Insert()
+				Expect(err).To(BeNil())
+			})
+
+			It("rollback whole batch", func() {
+				Expect(importErr).To(Equal(business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", errors.New(PGErrMsgNoKeyAvailable))))
+				Ω(testPGClient.Model(&URL{}).Where("id = ?", freeKey.ID).Count()).To(Equal(0))
+			})
+		})
+	})
 })
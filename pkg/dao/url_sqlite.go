@@ -0,0 +1,310 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+	"github.com/KennyChenFight/golib/loglib"
+)
+
+const sqliteURLColumns = "id, original, created_at, expired_at"
+
+// NewSQLiteUrlDAO 新的id從keys拿 等同pool strategy keySource不是nil時優先用已經lease到記憶體的key
+func NewSQLiteUrlDAO(logger *loglib.Logger, db *sql.DB, recycleCooldown time.Duration, keySource KeySource) *SQLiteUrlDAO {
+	return &SQLiteUrlDAO{logger: logger, db: db, recycleCooldown: recycleCooldown, keySource: keySource}
+}
+
+type SQLiteUrlDAO struct {
+	logger *loglib.Logger
+	db     *sql.DB
+	// 大於0時 刪除的url id會先放進quarantined_keys 冷卻期過後才會回到keys 0則直接丟掉
+	recycleCooldown time.Duration
+	keySource       KeySource
+}
+
+func (s *SQLiteUrlDAO) Create(ctx context.Context, originalURL string) (*URL, *business.Error) {
+	now := time.Now()
+	url := URL{Original: originalURL, CreatedAt: now, ExpiredAt: now.Add(expiredDuration)}
+	var leased []string
+	err := runInSQLiteTx(ctx, s.db, func(tx *sql.Tx) error {
+		return s.assign(ctx, tx, &url, &leased)
+	})
+	if err != nil {
+		s.releaseLeased(leased)
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return &url, nil
+}
+
+// Import 在同一個transaction裡寫入一批url 原本的id沒被用過就沿用 否則從keys重新分配
+func (s *SQLiteUrlDAO) Import(ctx context.Context, urls []URL) ([]ImportResult, *business.Error) {
+	results := make([]ImportResult, 0, len(urls))
+	now := time.Now()
+	var leased []string
+	err := runInSQLiteTx(ctx, s.db, func(tx *sql.Tx) error {
+		for _, u := range urls {
+			url := u
+			if url.CreatedAt.IsZero() {
+				url.CreatedAt = now
+			}
+			if url.ExpiredAt.IsZero() {
+				url.ExpiredAt = now.Add(expiredDuration)
+			}
+
+			if shortid.Valid(url.ID) {
+				preserved, err := sqliteInsertIfFree(ctx, tx, &url)
+				if err != nil {
+					return err
+				}
+				if preserved {
+					results = append(results, ImportResult{OriginalID: u.ID, ID: url.ID, URL: url})
+					continue
+				}
+			}
+
+			if err := s.assign(ctx, tx, &url, &leased); err != nil {
+				return err
+			}
+			results = append(results, ImportResult{OriginalID: u.ID, ID: url.ID, URL: url})
+		}
+		return nil
+	})
+	if err != nil {
+		s.releaseLeased(leased)
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return results, nil
+}
+
+// assign 跟LeaseKeyStrategy一樣 先用lease到記憶體的key 沒有的時候才去keys拿 寫入的lease key記在leased
+func (s *SQLiteUrlDAO) assign(ctx context.Context, tx *sql.Tx, url *URL, leased *[]string) error {
+	for s.keySource != nil {
+		id, ok := s.keySource.Take()
+		if !ok {
+			break
+		}
+		// lease出來的key已經不在keys裡 也不會在冷卻中 只要確認沒被import之類的用掉
+		url.ID = id
+		res, err := tx.ExecContext(ctx, "INSERT INTO urls ("+sqliteURLColumns+") VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			url.ID, url.Original, toSQLiteTime(url.CreatedAt), toSQLiteTime(url.ExpiredAt))
+		if err != nil {
+			s.keySource.Release([]string{id})
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			s.keySource.Release([]string{id})
+			return err
+		}
+		if affected == 1 {
+			*leased = append(*leased, id)
+			return nil
+		}
+	}
+	return sqliteClaimKey(ctx, tx, url)
+}
+
+// releaseLeased transaction沒有commit 用掉的lease key已經不在keys也不在urls 要放回記憶體
+func (s *SQLiteUrlDAO) releaseLeased(leased []string) {
+	if s.keySource != nil && len(leased) > 0 {
+		s.keySource.Release(leased)
+	}
+}
+
+// sqliteClaimKey 從keys拿一個key當作url的id 並把key刪掉
+// sqlite同時只有一個人能寫 transaction又是BEGIN IMMEDIATE 拿到write lock之後才會選key
+// 所以兩個transaction不可能拿到同一個key 效果跟postgres的FOR UPDATE SKIP LOCKED一樣 只是後面的人會等前面的commit而不是跳過
+func sqliteClaimKey(ctx context.Context, tx *sql.Tx, url *URL) error {
+	err := tx.QueryRowContext(ctx, "DELETE FROM keys WHERE id = (SELECT id FROM keys LIMIT 1) RETURNING id").Scan(&url.ID)
+	if err == sql.ErrNoRows {
+		return errors.New(SQLiteErrMsgNoKeyAvailable)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO urls ("+sqliteURLColumns+") VALUES (?, ?, ?, ?)",
+		url.ID, url.Original, toSQLiteTime(url.CreatedAt), toSQLiteTime(url.ExpiredAt))
+	return err
+}
+
+// sqliteInsertIfFree 用url現在的id寫入 id已經被用過或是還在冷卻中就回傳false
+func sqliteInsertIfFree(ctx context.Context, tx *sql.Tx, url *URL) (bool, error) {
+	// 冷卻中的id也算被用過 避免舊的連結指到別的網址
+	res, err := tx.ExecContext(ctx, `INSERT INTO urls (`+sqliteURLColumns+`)
+		SELECT ?, ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM quarantined_keys WHERE id = ?)
+		ON CONFLICT DO NOTHING`,
+		url.ID, url.Original, toSQLiteTime(url.CreatedAt), toSQLiteTime(url.ExpiredAt), url.ID)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+	// 用掉的id不能再被key pool發出去
+	_, err = tx.ExecContext(ctx, "DELETE FROM keys WHERE id = ?", url.ID)
+	return err == nil, err
+}
+
+func (s *SQLiteUrlDAO) Get(ctx context.Context, id string) (*URL, *business.Error) {
+	url, err := scanSQLiteURL(s.db.QueryRowContext(ctx, "SELECT "+sqliteURLColumns+" FROM urls WHERE id = ? AND expired_at > ?", id, toSQLiteTime(time.Now())))
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return url, nil
+}
+
+func (s *SQLiteUrlDAO) Find(ctx context.Context, id string) (*URL, *business.Error) {
+	url, err := scanSQLiteURL(s.db.QueryRowContext(ctx, "SELECT "+sqliteURLColumns+" FROM urls WHERE id = ?", id))
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return url, nil
+}
+
+func (s *SQLiteUrlDAO) ForceExpire(ctx context.Context, id string) *business.Error {
+	now := toSQLiteTime(time.Now())
+	res, err := s.db.ExecContext(ctx, "UPDATE urls SET expired_at = ? WHERE id = ? AND expired_at > ?", now, id, now)
+	if err != nil {
+		return sqliteErrorHandle(s.logger, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return sqliteErrorHandle(s.logger, err)
+	}
+	if affected == 0 {
+		return sqliteErrorHandle(s.logger, sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (s *SQLiteUrlDAO) Delete(ctx context.Context, id string) *business.Error {
+	err := runInSQLiteTx(ctx, s.db, func(tx *sql.Tx) error {
+		ids, err := sqliteDeleteReturningIDs(ctx, tx, "DELETE FROM urls WHERE id = ? RETURNING id", id)
+		if err != nil {
+			return err
+		}
+		return s.quarantine(ctx, tx, ids)
+	})
+	if err != nil {
+		return sqliteErrorHandle(s.logger, err)
+	}
+	return nil
+}
+
+func (s *SQLiteUrlDAO) Expire(ctx context.Context, num int) ([]string, *business.Error) {
+	var ids []string
+	err := runInSQLiteTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		ids, err = sqliteDeleteReturningIDs(ctx, tx, `DELETE FROM urls WHERE id IN (
+				SELECT id FROM urls WHERE expired_at < ? LIMIT ?
+			) RETURNING id`, toSQLiteTime(time.Now()), num)
+		if err != nil {
+			return err
+		}
+		return s.quarantine(ctx, tx, ids)
+	})
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return ids, nil
+}
+
+// quarantine 沒有開啟回收時什麼都不做
+func (s *SQLiteUrlDAO) quarantine(ctx context.Context, tx *sql.Tx, ids []string) error {
+	if s.recycleCooldown <= 0 || len(ids) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `INSERT INTO quarantined_keys (id, quarantined_at, release_at)
+		SELECT value, ?, ? FROM json_each(?) WHERE true
+		ON CONFLICT (id) DO UPDATE SET quarantined_at = excluded.quarantined_at, release_at = excluded.release_at`,
+		toSQLiteTime(now), toSQLiteTime(now.Add(s.recycleCooldown)), string(encoded))
+	return err
+}
+
+func (s *SQLiteUrlDAO) List(ctx context.Context, afterID string, limit int, includeExpired bool) ([]URL, *business.Error) {
+	conditions := []string{"id > ?"}
+	args := []interface{}{afterID}
+	if !includeExpired {
+		conditions = append(conditions, "expired_at > ?")
+		args = append(args, toSQLiteTime(time.Now()))
+	}
+	return s.list(ctx, conditions, "id ASC", limit, args...)
+}
+
+func (s *SQLiteUrlDAO) ListRecent(ctx context.Context, before *URL, limit int) ([]URL, *business.Error) {
+	conditions := []string{"expired_at > ?"}
+	args := []interface{}{toSQLiteTime(time.Now())}
+	if before != nil {
+		conditions = append(conditions, "(created_at, id) < (?, ?)")
+		args = append(args, toSQLiteTime(before.CreatedAt), before.ID)
+	}
+	return s.list(ctx, conditions, "created_at DESC, id DESC", limit, args...)
+}
+
+func (s *SQLiteUrlDAO) list(ctx context.Context, conditions []string, order string, limit int, args ...interface{}) ([]URL, *business.Error) {
+	query := "SELECT " + sqliteURLColumns + " FROM urls WHERE " + strings.Join(conditions, " AND ") + " ORDER BY " + order + " LIMIT ?"
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	defer rows.Close()
+
+	var urls []URL
+	for rows.Next() {
+		url, err := scanSQLiteURL(rows)
+		if err != nil {
+			return nil, sqliteErrorHandle(s.logger, err)
+		}
+		urls = append(urls, *url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqliteErrorHandle(s.logger, err)
+	}
+	return urls, nil
+}
+
+// sqliteScanner *sql.Row跟*sql.Rows共用的Scan
+type sqliteScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteURL(row sqliteScanner) (*URL, error) {
+	var url URL
+	var createdAt, expiredAt int64
+	if err := row.Scan(&url.ID, &url.Original, &createdAt, &expiredAt); err != nil {
+		return nil, err
+	}
+	url.CreatedAt = fromSQLiteTime(createdAt)
+	url.ExpiredAt = fromSQLiteTime(expiredAt)
+	return &url, nil
+}
+
+// sqliteDeleteReturningIDs 執行DELETE ... RETURNING id 回傳被刪掉的id
+func sqliteDeleteReturningIDs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SQLiteUrlDAO", func() {
+	ctx := context.Background()
+	var urlDAO *SQLiteUrlDAO
+	var keyDAO *SQLiteKeyDAO
+	var source *sliceKeySource
+
+	truncate := func() {
+		for _, table := range []string{"urls", "keys"} {
+			_, err := testSQLiteDB.Exec("DELETE FROM " + table)
+			Expect(err).To(BeNil())
+		}
+	}
+
+	BeforeEach(func() {
+		truncate()
+		source = &sliceKeySource{}
+		urlDAO = NewSQLiteUrlDAO(loglib.NewNopLogger(), testSQLiteDB, 0, source)
+		keyDAO = NewSQLiteKeyDAO(loglib.NewNopLogger(), testSQLiteDB, nil, 6)
+	})
+
+	AfterEach(truncate)
+
+	Describe("Create with leased keys", func() {
+		It("use leased keys first and skip the used ones", func() {
+			Expect(keyDAO.Return(ctx, []string{"kkkkkk"})).To(Equal(1))
+			mustImportURL := func(id string) {
+				results, err := urlDAO.Import(ctx, []URL{{ID: id, Original: "http://example.com/" + id}})
+				Expect(err).To(BeNil())
+				Expect(results[0].Preserved()).To(BeTrue())
+			}
+			mustImportURL("bbbbbb")
+			source.keys = []string{"aaaaaa", "bbbbbb"}
+
+			url, err := urlDAO.Create(ctx, "http://example.com")
+			Expect(err).To(BeNil())
+			Expect(url.ID).To(Equal("aaaaaa"))
+
+			// bbbbbb已經被import用掉 lease的key用完之後才從keys拿
+			url, err = urlDAO.Create(ctx, "http://example.com")
+			Expect(err).To(BeNil())
+			Expect(url.ID).To(Equal("kkkkkk"))
+			Expect(source.keys).To(BeEmpty())
+			Expect(keyDAO.Count(ctx)).To(Equal(0))
+		})
+
+		It("put leased keys back when the transaction rolls back", func() {
+			source.keys = []string{"aaaaaa"}
+			// 第一個用掉lease的key 第二個keys裡也沒有 整個transaction rollback
+			_, err := urlDAO.Import(ctx, []URL{{Original: "http://example.com/a"}, {Original: "http://example.com/b"}})
+			Expect(err).NotTo(BeNil())
+			Expect(err.BusinessCode).To(Equal(business.CapacityExhausted))
+			Expect(source.keys).To(Equal([]string{"aaaaaa"}))
+			_, err = urlDAO.Find(ctx, "aaaaaa")
+			Expect(err.BusinessCode).To(Equal(business.NotFound))
+		})
+	})
+})
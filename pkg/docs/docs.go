@@ -0,0 +1,25 @@
+package docs
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// OpenAPISpec 為 OpenAPI 3 文件 新增或修改 route 時需要一併更新
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+//go:embed index.html
+var UIPage []byte
+
+// swagger-ui 5.18.2 的 dist(Apache-2.0 見 swagger-ui/LICENSE) 一起編進 binary 離線或有 CSP 限制時也能看文件
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css swagger-ui/swagger-initializer.js
+var uiAssets embed.FS
+
+// UIAsset 回傳 UIPage 引用的檔案 不存在時回傳 false
+func UIAsset(name string) ([]byte, bool) {
+	data, err := fs.ReadFile(uiAssets, "swagger-ui/"+name)
+	return data, err == nil
+}
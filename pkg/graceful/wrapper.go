@@ -11,7 +11,8 @@ import (
 	"go.uber.org/zap"
 )
 
-func Wrapper(logger *loglib.Logger, fn func(ctx context.Context) error) {
+// Wrapper 執行fn直到收到SIGINT/SIGTERM fn提早結束時回傳它的error
+func Wrapper(logger *loglib.Logger, fn func(ctx context.Context) error) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -25,17 +26,18 @@ func Wrapper(logger *loglib.Logger, fn func(ctx context.Context) error) {
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
-	case <-done:
-		return
+	case err := <-done:
+		return err
 	case <-shutdown:
 		cancel()
-		timeoutCtx, _ := context.WithTimeout(context.Background(), 5*time.Second)
+		timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer timeoutCancel()
 		select {
-		case <-done:
-			return
+		case err := <-done:
+			return err
 		case <-timeoutCtx.Done():
 			logger.Error("shutdown timeout", zap.Error(timeoutCtx.Err()))
-			return
+			return timeoutCtx.Err()
 		}
 	}
 }
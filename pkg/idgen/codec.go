@@ -0,0 +1,83 @@
+package idgen
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Codec 把[0, Size())的整數轉成固定長度的id 不足長度時用alphabet的第一個字元補在前面
+type Codec struct {
+	alphabet string
+	length   int
+	size     uint64
+	index    [256]int
+}
+
+func NewCodec(alphabet string, length int) (*Codec, error) {
+	if len(alphabet) < 2 {
+		return nil, fmt.Errorf("alphabet should have at least 2 characters")
+	}
+	if length < 1 {
+		return nil, fmt.Errorf("length should be positive")
+	}
+	c := &Codec{alphabet: alphabet, length: length, size: 1}
+	for i := range c.index {
+		c.index[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		if c.index[alphabet[i]] != -1 {
+			return nil, fmt.Errorf("duplicated character %q in alphabet", alphabet[i])
+		}
+		c.index[alphabet[i]] = i
+	}
+
+	base := uint64(len(alphabet))
+	for i := 0; i < length; i++ {
+		if c.size > math.MaxUint64/base {
+			return nil, fmt.Errorf("%d characters of a %d letter alphabet overflow uint64", length, base)
+		}
+		c.size *= base
+	}
+	return c, nil
+}
+
+// Size 這個長度以及alphabet總共能表示幾個id
+func (c *Codec) Size() uint64 {
+	return c.size
+}
+
+func (c *Codec) Length() int {
+	return c.length
+}
+
+// Encode n必須小於Size()
+func (c *Codec) Encode(n uint64) string {
+	base := uint64(len(c.alphabet))
+	b := make([]byte, c.length)
+	for i := c.length - 1; i >= 0; i-- {
+		b[i] = c.alphabet[n%base]
+		n /= base
+	}
+	return string(b)
+}
+
+func (c *Codec) Decode(id string) (uint64, error) {
+	if len(id) != c.length {
+		return 0, fmt.Errorf("id %q should have %d characters", id, c.length)
+	}
+	base := uint64(len(c.alphabet))
+	var n uint64
+	for i := 0; i < len(id); i++ {
+		digit := c.index[id[i]]
+		if digit < 0 {
+			return 0, fmt.Errorf("character %q of id %q is not in alphabet", id[i], id)
+		}
+		hi, lo := bits.Mul64(n, base)
+		if hi != 0 {
+			return 0, fmt.Errorf("id %q overflows uint64", id)
+		}
+		n = lo + uint64(digit)
+	}
+	return n, nil
+}
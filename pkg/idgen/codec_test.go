@@ -0,0 +1,46 @@
+package idgen
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Codec", func() {
+	It("encode with fixed length and decode back", func() {
+		codec, err := NewCodec("abc", 4)
+		Expect(err).To(BeNil())
+		Expect(codec.Size()).To(Equal(uint64(81)))
+		Expect(codec.Encode(0)).To(Equal("aaaa"))
+		Expect(codec.Encode(5)).To(Equal("aabc"))
+		Expect(codec.Encode(80)).To(Equal("cccc"))
+		for n := uint64(0); n < codec.Size(); n++ {
+			Expect(codec.Decode(codec.Encode(n))).To(Equal(n))
+		}
+	})
+
+	It("fail to decode foreign ids", func() {
+		codec, err := NewCodec("abc", 4)
+		Expect(err).To(BeNil())
+		_, err = codec.Decode("abcd")
+		Expect(err).NotTo(BeNil())
+		_, err = codec.Decode("abc")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("fail with bad alphabet or length", func() {
+		_, err := NewCodec("a", 4)
+		Expect(err).NotTo(BeNil())
+		_, err = NewCodec("aba", 4)
+		Expect(err).NotTo(BeNil())
+		_, err = NewCodec("abc", 0)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("fail when size overflows uint64", func() {
+		_, err := NewCodec("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", 11)
+		Expect(err).NotTo(BeNil())
+		codec, err := NewCodec("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", 10)
+		Expect(err).To(BeNil())
+		Expect(codec.Decode(codec.Encode(codec.Size() - 1))).To(Equal(codec.Size() - 1))
+	})
+})
@@ -0,0 +1,17 @@
+package idgen
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+)
+
+// HashIndex 同一個原始網址第attempt次嘗試的位置 撞到已經被用過的id時attempt加一往下找
+func HashIndex(original string, attempt int, size uint64) uint64 {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(attempt)))
+	h.Write([]byte{0})
+	h.Write([]byte(original))
+	// size遠小於2^64 取餘數的偏差可以忽略
+	return binary.BigEndian.Uint64(h.Sum(nil)) % size
+}
@@ -0,0 +1,20 @@
+package idgen
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HashIndex", func() {
+	size := uint64(52 * 52 * 52 * 52 * 52 * 52)
+
+	It("same url and attempt give same index", func() {
+		Expect(HashIndex("http://example.com", 0, size)).To(Equal(HashIndex("http://example.com", 0, size)))
+		Expect(HashIndex("http://example.com", 0, size)).To(BeNumerically("<", size))
+	})
+
+	It("probe another index on next attempt", func() {
+		Expect(HashIndex("http://example.com", 1, size)).NotTo(Equal(HashIndex("http://example.com", 0, size)))
+		Expect(HashIndex("http://example.com/a", 0, size)).NotTo(Equal(HashIndex("http://example.com", 0, size)))
+	})
+})
@@ -0,0 +1,13 @@
+package idgen
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestIDGen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "IDGen Suite")
+}
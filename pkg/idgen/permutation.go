@@ -0,0 +1,76 @@
+package idgen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+const feistelRounds = 4
+
+// Permutation 用secret當key的Feistel network 把[0, size)一對一打散到[0, size)
+// 連續的流水號經過Forward之後看不出順序 也猜不到下一個 Backward可以還原
+type Permutation struct {
+	size     uint64
+	halfBits uint
+	mask     uint64
+	secret   []byte
+}
+
+func NewPermutation(size uint64, secret []byte) *Permutation {
+	// 兩半各halfBits 整個network的範圍2^(2*halfBits)要涵蓋size
+	halfBits := uint(bits.Len64(size-1)+1) / 2
+	if halfBits == 0 {
+		halfBits = 1
+	}
+	return &Permutation{size: size, halfBits: halfBits, mask: 1<<halfBits - 1, secret: secret}
+}
+
+func (p *Permutation) Size() uint64 {
+	return p.size
+}
+
+// Forward n必須小於Size() network的範圍比size大 超出範圍就再做一次(cycle walking)直到落在範圍內
+func (p *Permutation) Forward(n uint64) uint64 {
+	for {
+		n = p.encrypt(n)
+		if n < p.size {
+			return n
+		}
+	}
+}
+
+func (p *Permutation) Backward(n uint64) uint64 {
+	for {
+		n = p.decrypt(n)
+		if n < p.size {
+			return n
+		}
+	}
+}
+
+func (p *Permutation) encrypt(n uint64) uint64 {
+	left, right := n>>p.halfBits, n&p.mask
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^p.round(round, right)
+	}
+	return left<<p.halfBits | right
+}
+
+func (p *Permutation) decrypt(n uint64) uint64 {
+	left, right := n>>p.halfBits, n&p.mask
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^p.round(round, left), left
+	}
+	return left<<p.halfBits | right
+}
+
+func (p *Permutation) round(round int, half uint64) uint64 {
+	var b [9]byte
+	b[0] = byte(round)
+	binary.BigEndian.PutUint64(b[1:], half)
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(b[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & p.mask
+}
@@ -0,0 +1,47 @@
+package idgen
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Permutation", func() {
+	It("map every number in range to a distinct number in range", func() {
+		for _, size := range []uint64{2, 3, 1000, 1024, 4099} {
+			permutation := NewPermutation(size, []byte("secret"))
+			seen := make(map[uint64]bool, size)
+			for n := uint64(0); n < size; n++ {
+				m := permutation.Forward(n)
+				Expect(m).To(BeNumerically("<", size))
+				Expect(seen[m]).To(BeFalse())
+				seen[m] = true
+				Expect(permutation.Backward(m)).To(Equal(n))
+			}
+		}
+	})
+
+	It("do not keep sequence order", func() {
+		permutation := NewPermutation(52*52*52*52*52*52, []byte("secret"))
+		ascending := 0
+		for n := uint64(0); n < 100; n++ {
+			if permutation.Forward(n+1) > permutation.Forward(n) {
+				ascending++
+			}
+		}
+		Expect(ascending).To(BeNumerically("<", 80))
+		Expect(ascending).To(BeNumerically(">", 20))
+	})
+
+	It("depend on secret", func() {
+		size := uint64(52 * 52 * 52 * 52 * 52 * 52)
+		a := NewPermutation(size, []byte("secret-a"))
+		b := NewPermutation(size, []byte("secret-b"))
+		same := 0
+		for n := uint64(0); n < 100; n++ {
+			if a.Forward(n) == b.Forward(n) {
+				same++
+			}
+		}
+		Expect(same).To(BeNumerically("<", 5))
+	})
+})
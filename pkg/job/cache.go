@@ -0,0 +1,99 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+)
+
+func NewWarmCacheJob(cfg WarmCacheJobConfig, urlDAO dao.UrlDAO, cacheDAO dao.CacheDAO) *WarmCacheJob {
+	return &WarmCacheJob{cfg: cfg, urlDAO: urlDAO, cacheDAO: cacheDAO, sleep: sleepContext}
+}
+
+// WarmCacheJobConfig 由新到舊預先載入Number個還沒過期的url 每次讀BatchSize筆 每批之間停Interval 避免一次壓垮database
+// 目前沒有記錄點擊數 所以只能以建立時間當作熱門程度
+type WarmCacheJobConfig struct {
+	Name        string
+	TimerFormat string
+	Number      int
+	BatchSize   int
+	Interval    time.Duration
+}
+
+// WarmCacheJob redis被清空或是failover之後 所有縮網址同時miss 每個id都要搶lock去database拿
+// 先把比較可能被訪問的url放進cache跟filter 已經在cache裡的不會被覆蓋
+type WarmCacheJob struct {
+	cfg      WarmCacheJobConfig
+	urlDAO   dao.UrlDAO
+	cacheDAO dao.CacheDAO
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+func (w *WarmCacheJob) Name() string {
+	return w.cfg.Name
+}
+
+func (w *WarmCacheJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
+	var before *dao.URL
+	warmed, addedToFilter := 0, 0
+	for warmed < w.cfg.Number {
+		if before != nil && w.cfg.Interval > 0 {
+			if err := w.sleep(ctx, w.cfg.Interval); err != nil {
+				return nil, business.NewContextError(err)
+			}
+		}
+		limit := w.cfg.BatchSize
+		if rest := w.cfg.Number - warmed; rest < limit {
+			limit = rest
+		}
+		urls, err := w.urlDAO.ListRecent(ctx, before, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(urls) == 0 {
+			break
+		}
+
+		originalURLs := make(map[string]string, len(urls))
+		for _, url := range urls {
+			originalURLs[url.ID] = url.Original
+		}
+		if err := w.cacheDAO.SetMultiOriginalURL(ctx, originalURLs); err != nil {
+			return nil, err
+		}
+		added, err := w.cacheDAO.AddMultiOriginalURLIDInFilters(ctx, urlIDs(urls))
+		if err != nil {
+			return nil, err
+		}
+
+		warmed += len(urls)
+		addedToFilter += added
+		before = &urls[len(urls)-1]
+		if len(urls) < limit {
+			break
+		}
+	}
+
+	var result = make(map[string]interface{})
+	result["warmed"] = warmed
+	result["addedToFilter"] = addedToFilter
+	return result, nil
+}
+
+func (w *WarmCacheJob) TimerFormat() string {
+	return w.cfg.TimerFormat
+}
+
+// sleepContext 停d這麼久 ctx先結束的話提早回傳ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/daomock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WarmCacheJob", func() {
+	ctx := context.Background()
+	var mockCtrl *gomock.Controller
+	var mockUrlDAO *daomock.MockUrlDAO
+	var mockCacheDAO *daomock.MockCacheDAO
+	var warmCacheJob *WarmCacheJob
+	var (
+		result  map[string]interface{}
+		workErr *business.Error
+		slept   []time.Duration
+	)
+
+	now := time.Now()
+	urls := []dao.URL{
+		{ID: "cccccc", Original: "http://c.com", CreatedAt: now},
+		{ID: "bbbbbb", Original: "http://b.com", CreatedAt: now.Add(-time.Second)},
+		{ID: "aaaaaa", Original: "http://a.com", CreatedAt: now.Add(-2 * time.Second)},
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockUrlDAO = daomock.NewMockUrlDAO(mockCtrl)
+		mockCacheDAO = daomock.NewMockCacheDAO(mockCtrl)
+		warmCacheJob = NewWarmCacheJob(WarmCacheJobConfig{Name: "WarmCacheJob", Number: 3, BatchSize: 2, Interval: time.Second}, mockUrlDAO, mockCacheDAO)
+		slept = nil
+		warmCacheJob.sleep = func(_ context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		result, workErr = warmCacheJob.Work(ctx)
+	})
+
+	Context("warm up to number in batches", func() {
+		BeforeEach(func() {
+			gomock.InOrder(
+				mockUrlDAO.EXPECT().ListRecent(gomock.Any(), nil, 2).Return(urls[:2], nil),
+				mockCacheDAO.EXPECT().SetMultiOriginalURL(gomock.Any(), map[string]string{"cccccc": "http://c.com", "bbbbbb": "http://b.com"}).Return(nil),
+				mockCacheDAO.EXPECT().AddMultiOriginalURLIDInFilters(gomock.Any(), []string{"cccccc", "bbbbbb"}).Return(2, nil),
+				// 最後一批只拿剩下的數量
+				mockUrlDAO.EXPECT().ListRecent(gomock.Any(), &urls[1], 1).Return(urls[2:], nil),
+				mockCacheDAO.EXPECT().SetMultiOriginalURL(gomock.Any(), map[string]string{"aaaaaa": "http://a.com"}).Return(nil),
+				mockCacheDAO.EXPECT().AddMultiOriginalURLIDInFilters(gomock.Any(), []string{"aaaaaa"}).Return(0, nil),
+			)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result).To(Equal(map[string]interface{}{"warmed": 3, "addedToFilter": 2}))
+			Expect(slept).To(Equal([]time.Duration{time.Second}))
+		})
+	})
+
+	Context("fewer active urls than number", func() {
+		BeforeEach(func() {
+			warmCacheJob.cfg.Number = 10
+			gomock.InOrder(
+				mockUrlDAO.EXPECT().ListRecent(gomock.Any(), nil, 2).Return(urls[:2], nil),
+				mockCacheDAO.EXPECT().SetMultiOriginalURL(gomock.Any(), gomock.Any()).Return(nil),
+				mockCacheDAO.EXPECT().AddMultiOriginalURLIDInFilters(gomock.Any(), gomock.Any()).Return(2, nil),
+				mockUrlDAO.EXPECT().ListRecent(gomock.Any(), &urls[1], 2).Return(urls[2:], nil),
+				mockCacheDAO.EXPECT().SetMultiOriginalURL(gomock.Any(), gomock.Any()).Return(nil),
+				mockCacheDAO.EXPECT().AddMultiOriginalURLIDInFilters(gomock.Any(), gomock.Any()).Return(1, nil),
+			)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result).To(Equal(map[string]interface{}{"warmed": 3, "addedToFilter": 3}))
+		})
+	})
+
+	Context("stop waiting between batches when canceled", func() {
+		BeforeEach(func() {
+			canceledCtx, cancel := context.WithCancel(context.Background())
+			cancel()
+			warmCacheJob.cfg.Interval = time.Hour
+			// 用真的sleep 只是換成已經取消的ctx
+			warmCacheJob.sleep = func(_ context.Context, d time.Duration) error {
+				return sleepContext(canceledCtx, d)
+			}
+			mockUrlDAO.EXPECT().ListRecent(gomock.Any(), nil, 2).Return(urls[:2], nil)
+			mockCacheDAO.EXPECT().SetMultiOriginalURL(gomock.Any(), gomock.Any()).Return(nil)
+			mockCacheDAO.EXPECT().AddMultiOriginalURLIDInFilters(gomock.Any(), gomock.Any()).Return(2, nil)
+		})
+
+		It("result", func() {
+			Expect(workErr.BusinessCode).To(Equal(business.Canceled))
+			Expect(result).To(BeNil())
+		})
+	})
+
+	Context("fail with set cache", func() {
+		var setErr *business.Error
+		BeforeEach(func() {
+			setErr = business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", errors.New("internal error"))
+			mockUrlDAO.EXPECT().ListRecent(gomock.Any(), nil, 2).Return(urls[:2], nil)
+			mockCacheDAO.EXPECT().SetMultiOriginalURL(gomock.Any(), gomock.Any()).Return(setErr)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(Equal(setErr))
+			Expect(result).To(BeNil())
+		})
+	})
+})
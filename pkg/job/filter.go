@@ -0,0 +1,129 @@
+package job
+
+import (
+	"context"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+	"github.com/KennyChenFight/golib/loglib"
+	"go.uber.org/zap"
+)
+
+func NewRebuildFilterJob(cfg RebuildFilterJobConfig, urlDAO dao.UrlDAO, filter dao.OriginalURLIDFilterRebuilder) *RebuildFilterJob {
+	return &RebuildFilterJob{cfg: cfg, urlDAO: urlDAO, filter: filter}
+}
+
+// RebuildFilterJobConfig 每次從urls讀PageSize筆id
+// 已經過期但ExpiredURLJob還沒刪掉的也要讀 之後刪除時filter裡才有這個id 不然cuckoo filter會刪到fingerprint相同的其他id
+type RebuildFilterJobConfig struct {
+	Name        string
+	TimerFormat string
+	PageSize    int
+}
+
+// RebuildFilterJob 從urls table重建filter 修正建立url時沒加進去 或是刪除時沒刪掉的id
+type RebuildFilterJob struct {
+	cfg    RebuildFilterJobConfig
+	urlDAO dao.UrlDAO
+	filter dao.OriginalURLIDFilterRebuilder
+}
+
+func (r *RebuildFilterJob) Name() string {
+	return r.cfg.Name
+}
+
+func (r *RebuildFilterJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
+	// 重建期間建立或import的url可能落在已經掃過的範圍 由filter在換掉之後補回去
+	afterID := ""
+	rebuild, err := r.filter.Rebuild(ctx, func() ([]string, *business.Error) {
+		urls, err := r.urlDAO.List(ctx, afterID, r.cfg.PageSize, true)
+		if err != nil || len(urls) == 0 {
+			return nil, err
+		}
+		afterID = urls[len(urls)-1].ID
+		return urlIDs(urls), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.OriginalURLFilterMissingIDs.Set(float64(rebuild.Missing))
+	metrics.OriginalURLFilterExtraIDs.Set(float64(rebuild.Extra))
+
+	var result = make(map[string]interface{})
+	result["scanned"] = rebuild.Scanned
+	result["missing"] = rebuild.Missing
+	result["extra"] = rebuild.Extra
+	result["caughtUp"] = rebuild.Replayed
+	return result, nil
+}
+
+func (r *RebuildFilterJob) TimerFormat() string {
+	return r.cfg.TimerFormat
+}
+
+func urlIDs(urls []dao.URL) []string {
+	ids := make([]string, 0, len(urls))
+	for _, url := range urls {
+		ids = append(ids, url.ID)
+	}
+	return ids
+}
+
+func NewFilterStatsJob(cfg FilterStatsJobConfig, logger *loglib.Logger, filter dao.OriginalURLIDFilterInspector) *FilterStatsJob {
+	return &FilterStatsJob{cfg: cfg, logger: logger, filter: filter}
+}
+
+// FilterStatsJobConfig fill ratio或是估計的false positive rate超過門檻就發warning
+// cuckoo filter快滿時insert會開始失敗或是擴充新的sub filter 每多一個sub filter false positive rate就多一倍
+type FilterStatsJobConfig struct {
+	Name          string
+	TimerFormat   string
+	WarnFillRatio float64
+	WarnErrorRate float64
+}
+
+type FilterStatsJob struct {
+	cfg    FilterStatsJobConfig
+	logger *loglib.Logger
+	filter dao.OriginalURLIDFilterInspector
+}
+
+func (f *FilterStatsJob) Name() string {
+	return f.cfg.Name
+}
+
+func (f *FilterStatsJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
+	info, err := f.filter.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.OriginalURLFilterItems.Set(float64(info.Items))
+	metrics.OriginalURLFilterCapacity.Set(float64(info.Capacity))
+	metrics.OriginalURLFilterFillRatio.Set(info.FillRatio)
+	metrics.OriginalURLFilterSubFilters.Set(float64(info.Filters))
+	metrics.OriginalURLFilterErrorRate.Set(info.ErrorRate)
+
+	warn := info.FillRatio >= f.cfg.WarnFillRatio || info.ErrorRate >= f.cfg.WarnErrorRate
+	if warn {
+		f.logger.Warn("filter is close to full or false positive rate degraded, raise capacity and rebuild",
+			zap.Int("items", info.Items),
+			zap.Int("capacity", info.Capacity),
+			zap.Float64("fillRatio", info.FillRatio),
+			zap.Int("filters", info.Filters),
+			zap.Float64("errorRate", info.ErrorRate))
+	}
+
+	var result = make(map[string]interface{})
+	result["items"] = info.Items
+	result["capacity"] = info.Capacity
+	result["fillRatio"] = info.FillRatio
+	result["filters"] = info.Filters
+	result["errorRate"] = info.ErrorRate
+	result["warn"] = warn
+	return result, nil
+}
+
+func (f *FilterStatsJob) TimerFormat() string {
+	return f.cfg.TimerFormat
+}
@@ -0,0 +1,240 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/daomock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RebuildFilterJob", func() {
+	ctx := context.Background()
+	var mockCtrl *gomock.Controller
+	var mockUrlDAO *daomock.MockUrlDAO
+	var mockFilter *daomock.MockOriginalURLIDFilterRebuilder
+	var rebuildFilterJob *RebuildFilterJob
+	var (
+		result  map[string]interface{}
+		workErr *business.Error
+		scanned [][]string
+	)
+
+	// 模擬filter把next拿到的id全部讀完
+	drain := func(_ context.Context, next func() ([]string, *business.Error)) (dao.FilterRebuildResult, *business.Error) {
+		for {
+			ids, err := next()
+			if err != nil {
+				return dao.FilterRebuildResult{}, err
+			}
+			if len(ids) == 0 {
+				return dao.FilterRebuildResult{Scanned: 3, Missing: 1, Extra: 2, Replayed: 1}, nil
+			}
+			scanned = append(scanned, ids)
+		}
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockUrlDAO = daomock.NewMockUrlDAO(mockCtrl)
+		mockFilter = daomock.NewMockOriginalURLIDFilterRebuilder(mockCtrl)
+		rebuildFilterJob = NewRebuildFilterJob(RebuildFilterJobConfig{Name: "RebuildFilterJob", PageSize: 2}, mockUrlDAO, mockFilter)
+		scanned = nil
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		result, workErr = rebuildFilterJob.Work(ctx)
+	})
+
+	Context("rebuild and catch up", func() {
+		BeforeEach(func() {
+			gomock.InOrder(
+				mockUrlDAO.EXPECT().List(gomock.Any(), "", 2, true).Return([]dao.URL{{ID: "aaaaaa"}, {ID: "bbbbbb"}}, nil),
+				mockUrlDAO.EXPECT().List(gomock.Any(), "bbbbbb", 2, true).Return([]dao.URL{{ID: "cccccc"}}, nil),
+				mockUrlDAO.EXPECT().List(gomock.Any(), "cccccc", 2, true).Return(nil, nil),
+			)
+			mockFilter.EXPECT().Rebuild(gomock.Any(), gomock.Any()).DoAndReturn(drain)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(scanned).To(Equal([][]string{{"aaaaaa", "bbbbbb"}, {"cccccc"}}))
+			Expect(result).To(Equal(map[string]interface{}{"scanned": 3, "missing": 1, "extra": 2, "caughtUp": 1}))
+			Expect(testutil.ToFloat64(metrics.OriginalURLFilterMissingIDs)).To(Equal(float64(1)))
+			Expect(testutil.ToFloat64(metrics.OriginalURLFilterExtraIDs)).To(Equal(float64(2)))
+		})
+	})
+
+	Context("fail with list", func() {
+		var listErr *business.Error
+		BeforeEach(func() {
+			listErr = business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", errors.New("internal error"))
+			mockUrlDAO.EXPECT().List(gomock.Any(), "", 2, true).Return(nil, listErr)
+			mockFilter.EXPECT().Rebuild(gomock.Any(), gomock.Any()).DoAndReturn(drain)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(Equal(listErr))
+			Expect(result).To(BeNil())
+		})
+	})
+})
+
+// rebuildMemoryFilter Rebuild時換成一個只有next給的id的MemoryFilter
+type rebuildMemoryFilter struct {
+	*dao.MemoryFilter
+}
+
+func (r *rebuildMemoryFilter) Rebuild(ctx context.Context, next func() ([]string, *business.Error)) (dao.FilterRebuildResult, *business.Error) {
+	filter := dao.NewMemoryFilter()
+	var result dao.FilterRebuildResult
+	for {
+		ids, err := next()
+		if err != nil {
+			return dao.FilterRebuildResult{}, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			filter.Add(ctx, id)
+		}
+		result.Scanned += len(ids)
+	}
+	r.MemoryFilter = filter
+	return result, nil
+}
+
+var _ = Describe("RebuildFilterJob with expired urls", func() {
+	ctx := context.Background()
+	var urlDAO *dao.MemoryUrlDAO
+	var filter *rebuildMemoryFilter
+	var cacheDAO *dao.MemoryCacheDAO
+
+	BeforeEach(func() {
+		urlDAO = dao.NewMemoryUrlDAO(dao.NewMemoryStore(), 0)
+		filter = &rebuildMemoryFilter{MemoryFilter: dao.NewMemoryFilter()}
+		cacheDAO = dao.NewMemoryCacheDAO(time.Minute, filter)
+
+		// bbbbbb已經過期 但是ExpiredURLJob還沒把它刪掉
+		_, err := urlDAO.Import(ctx, []dao.URL{
+			{ID: "aaaaaa", Original: "https://example.com/a"},
+			{ID: "bbbbbb", Original: "https://example.com/b", ExpiredAt: time.Now().Add(-time.Minute)},
+		})
+		Expect(err).To(BeNil())
+	})
+
+	It("keep expired ids until the expire job deletes them", func() {
+		rebuildFilterJob := NewRebuildFilterJob(RebuildFilterJobConfig{Name: "RebuildFilterJob", PageSize: 1}, urlDAO, filter)
+		result, err := rebuildFilterJob.Work(ctx)
+		Expect(err).To(BeNil())
+		Expect(result["scanned"]).To(Equal(2))
+		for _, id := range []string{"aaaaaa", "bbbbbb"} {
+			exist, err := filter.Exist(ctx, id)
+			Expect(err).To(BeNil())
+			Expect(exist).To(BeTrue(), "%s is missing in rebuilt filter", id)
+		}
+
+		expiredURLJob := NewExpiredURLJob(ExpiredURLJobConfig{Name: "ExpiredURLJob", ExpireURLNumber: 10}, urlDAO, cacheDAO)
+		result, err = expiredURLJob.Work(ctx)
+		Expect(err).To(BeNil())
+		Expect(result["length"]).To(Equal(1))
+
+		exist, err := filter.Exist(ctx, "aaaaaa")
+		Expect(err).To(BeNil())
+		Expect(exist).To(BeTrue())
+		exist, err = filter.Exist(ctx, "bbbbbb")
+		Expect(err).To(BeNil())
+		Expect(exist).To(BeFalse())
+	})
+})
+
+var _ = Describe("FilterStatsJob", func() {
+	ctx := context.Background()
+	var mockCtrl *gomock.Controller
+	var mockFilter *daomock.MockOriginalURLIDFilterInspector
+	var filterStatsJob *FilterStatsJob
+	var (
+		result  map[string]interface{}
+		workErr *business.Error
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockFilter = daomock.NewMockOriginalURLIDFilterInspector(mockCtrl)
+		filterStatsJob = NewFilterStatsJob(FilterStatsJobConfig{Name: "FilterStatsJob", WarnFillRatio: 0.8, WarnErrorRate: 0.03}, loglib.NewNopLogger(), mockFilter)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		result, workErr = filterStatsJob.Work(ctx)
+	})
+
+	Context("healthy", func() {
+		BeforeEach(func() {
+			mockFilter.EXPECT().Info(gomock.Any()).Return(dao.FilterInfo{Items: 500, Capacity: 1000, FillRatio: 0.5, Filters: 1, BucketSize: 2, ErrorRate: 0.015625}, nil)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result).To(Equal(map[string]interface{}{"items": 500, "capacity": 1000, "fillRatio": 0.5, "filters": 1, "errorRate": 0.015625, "warn": false}))
+			Expect(testutil.ToFloat64(metrics.OriginalURLFilterItems)).To(Equal(float64(500)))
+			Expect(testutil.ToFloat64(metrics.OriginalURLFilterCapacity)).To(Equal(float64(1000)))
+			Expect(testutil.ToFloat64(metrics.OriginalURLFilterFillRatio)).To(Equal(0.5))
+			Expect(testutil.ToFloat64(metrics.OriginalURLFilterSubFilters)).To(Equal(float64(1)))
+			Expect(testutil.ToFloat64(metrics.OriginalURLFilterErrorRate)).To(Equal(0.015625))
+		})
+	})
+
+	Context("expanded", func() {
+		BeforeEach(func() {
+			mockFilter.EXPECT().Info(gomock.Any()).Return(dao.FilterInfo{Items: 1100, Capacity: 2000, FillRatio: 0.55, Filters: 2, BucketSize: 2, ErrorRate: 0.03125}, nil)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result["warn"]).To(BeTrue())
+		})
+	})
+
+	Context("nearly full", func() {
+		BeforeEach(func() {
+			mockFilter.EXPECT().Info(gomock.Any()).Return(dao.FilterInfo{Items: 900, Capacity: 1000, FillRatio: 0.9, Filters: 1, BucketSize: 2, ErrorRate: 0.015625}, nil)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result["warn"]).To(BeTrue())
+		})
+	})
+
+	Context("fail with info", func() {
+		var infoErr *business.Error
+		BeforeEach(func() {
+			infoErr = business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", errors.New("internal error"))
+			mockFilter.EXPECT().Info(gomock.Any()).Return(dao.FilterInfo{}, infoErr)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(Equal(infoErr))
+			Expect(result).To(BeNil())
+		})
+	})
+})
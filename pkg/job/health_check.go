@@ -0,0 +1,223 @@
+package job
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/golib/loglib"
+	"go.uber.org/zap"
+)
+
+const maxHealthCheckErrorLength = 512
+
+func NewHealthCheckJob(cfg HealthCheckJobConfig, logger *loglib.Logger, urlDAO dao.UrlDAO, healthCheckDAO dao.HealthCheckDAO, client *http.Client) *HealthCheckJob {
+	return &HealthCheckJob{cfg: cfg, logger: logger, urlDAO: urlDAO, healthCheckDAO: healthCheckDAO, client: client}
+}
+
+type HealthCheckJobConfig struct {
+	Name        string
+	TimerFormat string
+	// 每次從database拿多少筆url
+	BatchSize int
+	// 同時最多probe幾個url
+	Concurrency int
+	// 同一個host兩次request之間至少要間隔多久 避免把對方打爆
+	PerHostInterval time.Duration
+	Timeout         time.Duration
+	// 連續失敗幾次就標記為broken
+	BrokenThreshold int
+	UserAgent       string
+}
+
+type HealthCheckJob struct {
+	cfg            HealthCheckJobConfig
+	logger         *loglib.Logger
+	urlDAO         dao.UrlDAO
+	healthCheckDAO dao.HealthCheckDAO
+	client         *http.Client
+}
+
+func (h *HealthCheckJob) Name() string {
+	return h.cfg.Name
+}
+
+func (h *HealthCheckJob) TimerFormat() string {
+	return h.cfg.TimerFormat
+}
+
+func (h *HealthCheckJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
+	var (
+		mu                                             sync.Mutex
+		wg                                             sync.WaitGroup
+		checked, healthy, failed, broken, recordFailed int
+	)
+
+	// concurrency是0的話slots會永遠塞不進去 batch size是0會一直拿到空的page
+	if h.cfg.Concurrency <= 0 || h.cfg.BatchSize <= 0 {
+		return nil, business.NewError(business.Validation, http.StatusBadRequest, "concurrency and batch size must be greater than 0", nil)
+	}
+
+	slots := make(chan struct{}, h.cfg.Concurrency)
+	gates := newHostGates(h.cfg.PerHostInterval)
+
+	afterID := ""
+	for {
+		urls, err := h.urlDAO.List(ctx, afterID, h.cfg.BatchSize, false)
+		if err != nil {
+			wg.Wait()
+			return nil, err
+		}
+		if len(urls) == 0 {
+			break
+		}
+		afterID = urls[len(urls)-1].ID
+
+		for _, u := range urls {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(u dao.URL) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+
+				check := h.probe(ctx, gates, u)
+				recorded, err := h.healthCheckDAO.Record(ctx, check, h.cfg.BrokenThreshold)
+
+				mu.Lock()
+				defer mu.Unlock()
+				checked++
+				if check.ConsecutiveFailures == 0 {
+					healthy++
+				} else {
+					failed++
+				}
+				if err != nil {
+					h.logger.Error("fail to record health check", zap.String("id", u.ID), zap.Error(err))
+					recordFailed++
+					return
+				}
+				if recorded.Broken {
+					broken++
+				}
+			}(u)
+		}
+
+		if len(urls) < h.cfg.BatchSize {
+			break
+		}
+	}
+	wg.Wait()
+
+	var result = make(map[string]interface{})
+	result["checked"] = checked
+	result["healthy"] = healthy
+	result["failed"] = failed
+	result["broken"] = broken
+	result["recordFailed"] = recordFailed
+	return result, nil
+}
+
+func (h *HealthCheckJob) probe(ctx context.Context, gates *hostGates, u dao.URL) *dao.HealthCheck {
+	check := &dao.HealthCheck{URLID: u.ID}
+
+	destination, err := url.Parse(u.Original)
+	if err != nil || destination.Host == "" {
+		check.ConsecutiveFailures = 1
+		check.Error = "invalid destination url"
+		check.CheckedAt = time.Now()
+		return check
+	}
+
+	gates.wait(ctx, destination.Host)
+
+	start := time.Now()
+	statusCode, err := h.request(ctx, http.MethodHead, u.Original)
+	// 有些server不支援HEAD 改用GET再試一次
+	if err == nil && (statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented) {
+		gates.wait(ctx, destination.Host)
+		start = time.Now()
+		statusCode, err = h.request(ctx, http.MethodGet, u.Original)
+	}
+	check.LatencyMs = time.Since(start).Milliseconds()
+	check.StatusCode = statusCode
+	check.CheckedAt = time.Now()
+
+	if err != nil {
+		check.ConsecutiveFailures = 1
+		check.Error = truncateUTF8(err.Error(), maxHealthCheckErrorLength)
+	} else if statusCode >= http.StatusBadRequest {
+		check.ConsecutiveFailures = 1
+		check.Error = http.StatusText(statusCode)
+	}
+	return check
+}
+
+func (h *HealthCheckJob) request(ctx context.Context, method, destination string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, method, destination, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("User-Agent", h.cfg.UserAgent)
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// 只需要status code 讀一小段body讓connection可以被reuse
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 4096))
+	return response.StatusCode, nil
+}
+
+// truncateUTF8 最多保留maxBytes個byte 不會把一個字切成兩半
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
+
+func newHostGates(interval time.Duration) *hostGates {
+	return &hostGates{interval: interval, next: make(map[string]time.Time)}
+}
+
+// hostGates 讓同一個host的request之間至少間隔interval
+type hostGates struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+func (g *hostGates) wait(ctx context.Context, host string) {
+	g.mu.Lock()
+	now := time.Now()
+	slot := g.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	g.next[host] = slot.Add(g.interval)
+	g.mu.Unlock()
+
+	timer := time.NewTimer(slot.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
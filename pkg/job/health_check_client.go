@@ -0,0 +1,101 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const maxHealthCheckRedirects = 10
+
+// 縮網址的destination是使用者給的 不能讓health check被拿來打內網或是cloud metadata
+var blockedProbeNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// NewHealthCheckHTTPClient 回傳health check用的client 連到loopback、private、link-local(包含metadata)的位址都會失敗
+func NewHealthCheckHTTPClient() *http.Client {
+	return newGuardedHTTPClient(isBlockedProbeIP)
+}
+
+func newGuardedHTTPClient(blocked func(ip net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Control是在DNS解析完 真正connect之前呼叫 拿到的就是實際要連的ip 不會被DNS rebinding繞過
+		// redirect的每一個hop都會重新dial 一樣會經過這裡
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || blocked(ip) {
+				return fmt.Errorf("destination address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			// 走proxy的話dial的對象變成proxy 上面的檢查就沒有意義了
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxHealthCheckRedirects {
+				return errors.New("too many redirects")
+			}
+			if request.URL.Scheme != "http" && request.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", request.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+func isBlockedProbeIP(ip net.IP) bool {
+	for _, network := range blockedProbeNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,290 @@
+package job
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/daomock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HealthCheckJob", func() {
+	ctx := context.Background()
+	var mockCtrl *gomock.Controller
+	var mockUrlDAO *daomock.MockUrlDAO
+	var mockHealthCheckDAO *daomock.MockHealthCheckDAO
+	var cfg HealthCheckJobConfig
+	var recorded map[string]*dao.HealthCheck
+	var recordedMu sync.Mutex
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockUrlDAO = daomock.NewMockUrlDAO(mockCtrl)
+		mockHealthCheckDAO = daomock.NewMockHealthCheckDAO(mockCtrl)
+		cfg = HealthCheckJobConfig{
+			Name:            "HealthCheckJob",
+			BatchSize:       2,
+			Concurrency:     2,
+			PerHostInterval: 0,
+			Timeout:         time.Second,
+			BrokenThreshold: 1,
+			UserAgent:       "test",
+		}
+		recorded = make(map[string]*dao.HealthCheck)
+		mockHealthCheckDAO.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, check *dao.HealthCheck, brokenThreshold int) (*dao.HealthCheck, *business.Error) {
+			recordedMu.Lock()
+			defer recordedMu.Unlock()
+			check.Broken = check.ConsecutiveFailures >= brokenThreshold
+			recorded[check.URLID] = check
+			return check, nil
+		}).AnyTimes()
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	expectList := func(urls []dao.URL, batchSize int) {
+		afterID := ""
+		for i := 0; i < len(urls); i += batchSize {
+			end := i + batchSize
+			if end > len(urls) {
+				end = len(urls)
+			}
+			mockUrlDAO.EXPECT().List(gomock.Any(), afterID, batchSize, false).Return(urls[i:end], nil)
+			afterID = urls[end-1].ID
+		}
+		if len(urls)%batchSize == 0 {
+			mockUrlDAO.EXPECT().List(gomock.Any(), afterID, batchSize, false).Return(nil, nil)
+		}
+	}
+
+	Context("record status of every destination", func() {
+		var standIn *httptest.Server
+		BeforeEach(func() {
+			standIn = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/ok":
+					w.WriteHeader(http.StatusOK)
+				case "/no-head":
+					if r.Method == http.MethodHead {
+						w.WriteHeader(http.StatusMethodNotAllowed)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			expectList([]dao.URL{
+				{ID: "aaaaaa", Original: standIn.URL + "/ok"},
+				{ID: "bbbbbb", Original: standIn.URL + "/no-head"},
+				{ID: "cccccc", Original: standIn.URL + "/gone"},
+			}, cfg.BatchSize)
+		})
+
+		AfterEach(func() {
+			standIn.Close()
+		})
+
+		It("result", func() {
+			result, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, standIn.Client()).Work(ctx)
+			Expect(err).To(BeNil())
+			Expect(result["checked"]).To(Equal(3))
+			Expect(result["healthy"]).To(Equal(2))
+			Expect(result["failed"]).To(Equal(1))
+			Expect(result["broken"]).To(Equal(1))
+
+			Expect(recorded["aaaaaa"].StatusCode).To(Equal(http.StatusOK))
+			Expect(recorded["bbbbbb"].StatusCode).To(Equal(http.StatusOK))
+			Expect(recorded["cccccc"].StatusCode).To(Equal(http.StatusNotFound))
+			Expect(recorded["cccccc"].Broken).To(BeTrue())
+			Expect(recorded["aaaaaa"].CheckedAt).NotTo(BeZero())
+		})
+	})
+
+	Context("record failure when destination is unreachable", func() {
+		BeforeEach(func() {
+			standIn := httptest.NewServer(http.NotFoundHandler())
+			unreachable := standIn.URL
+			standIn.Close()
+			expectList([]dao.URL{{ID: "aaaaaa", Original: unreachable}, {ID: "bbbbbb", Original: "not a url"}}, cfg.BatchSize)
+		})
+
+		It("result", func() {
+			result, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, http.DefaultClient).Work(ctx)
+			Expect(err).To(BeNil())
+			Expect(result["failed"]).To(Equal(2))
+			Expect(recorded["aaaaaa"].StatusCode).To(Equal(0))
+			Expect(recorded["aaaaaa"].Error).NotTo(BeEmpty())
+			Expect(recorded["bbbbbb"].Error).To(Equal("invalid destination url"))
+		})
+	})
+
+	Context("bounded concurrency across hosts", func() {
+		var standIns []*httptest.Server
+		var inFlight, maxInFlight int32
+		BeforeEach(func() {
+			inFlight, maxInFlight = 0, 0
+			cfg.BatchSize = 6
+			var urls []dao.URL
+			for _, id := range []string{"aaaaaa", "bbbbbb", "cccccc", "dddddd", "eeeeee", "ffffff"} {
+				standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					current := atomic.AddInt32(&inFlight, 1)
+					defer atomic.AddInt32(&inFlight, -1)
+					for {
+						max := atomic.LoadInt32(&maxInFlight)
+						if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+							break
+						}
+					}
+					time.Sleep(50 * time.Millisecond)
+				}))
+				standIns = append(standIns, standIn)
+				urls = append(urls, dao.URL{ID: id, Original: standIn.URL})
+			}
+			expectList(urls, cfg.BatchSize)
+		})
+
+		AfterEach(func() {
+			for _, standIn := range standIns {
+				standIn.Close()
+			}
+			standIns = nil
+		})
+
+		It("result", func() {
+			result, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, http.DefaultClient).Work(ctx)
+			Expect(err).To(BeNil())
+			Expect(result["healthy"]).To(Equal(6))
+			Expect(atomic.LoadInt32(&maxInFlight)).To(BeNumerically("<=", cfg.Concurrency))
+			Expect(atomic.LoadInt32(&maxInFlight)).To(BeNumerically(">", 1))
+		})
+	})
+
+	Context("per host politeness", func() {
+		var standIn *httptest.Server
+		var requestTimes []time.Time
+		var mu sync.Mutex
+		BeforeEach(func() {
+			requestTimes = nil
+			cfg.Concurrency = 3
+			cfg.BatchSize = 3
+			cfg.PerHostInterval = 100 * time.Millisecond
+			standIn = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requestTimes = append(requestTimes, time.Now())
+				mu.Unlock()
+			}))
+			expectList([]dao.URL{
+				{ID: "aaaaaa", Original: standIn.URL + "/a"},
+				{ID: "bbbbbb", Original: standIn.URL + "/b"},
+				{ID: "cccccc", Original: standIn.URL + "/c"},
+			}, cfg.BatchSize)
+		})
+
+		AfterEach(func() {
+			standIn.Close()
+		})
+
+		It("result", func() {
+			_, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, standIn.Client()).Work(ctx)
+			Expect(err).To(BeNil())
+			Expect(requestTimes).To(HaveLen(3))
+			for i := 1; i < len(requestTimes); i++ {
+				// 容許一點點誤差
+				Expect(requestTimes[i].Sub(requestTimes[i-1])).To(BeNumerically(">=", cfg.PerHostInterval-10*time.Millisecond))
+			}
+		})
+	})
+
+	Context("refuse internal destinations", func() {
+		var standIn *httptest.Server
+		BeforeEach(func() {
+			standIn = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/redirect" {
+					http.Redirect(w, r, strings.Replace(standIn.URL, "127.0.0.1", "127.0.0.2", 1)+"/ok", http.StatusFound)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			standIn.Close()
+		})
+
+		It("loopback destination", func() {
+			expectList([]dao.URL{{ID: "aaaaaa", Original: standIn.URL}}, cfg.BatchSize)
+			result, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, NewHealthCheckHTTPClient()).Work(ctx)
+			Expect(err).To(BeNil())
+			Expect(result["failed"]).To(Equal(1))
+			Expect(recorded["aaaaaa"].Error).To(ContainSubstring("is not allowed"))
+		})
+
+		It("every redirect hop", func() {
+			// 第一個hop是允許的 redirect之後的hop不允許
+			client := newGuardedHTTPClient(func(ip net.IP) bool { return ip.Equal(net.ParseIP("127.0.0.2")) })
+			expectList([]dao.URL{{ID: "aaaaaa", Original: standIn.URL + "/redirect"}}, cfg.BatchSize)
+			result, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, client).Work(ctx)
+			Expect(err).To(BeNil())
+			Expect(result["failed"]).To(Equal(1))
+			Expect(recorded["aaaaaa"].Error).To(ContainSubstring("127.0.0.2 is not allowed"))
+		})
+
+		It("blocked address ranges", func() {
+			for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200", "0.0.0.0", "::1", "fe80::1", "fd00:ec2::254", "::ffff:127.0.0.1"} {
+				Expect(isBlockedProbeIP(net.ParseIP(ip))).To(BeTrue(), ip)
+			}
+			for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2001:4860:4860::8888"} {
+				Expect(isBlockedProbeIP(net.ParseIP(ip))).To(BeFalse(), ip)
+			}
+		})
+	})
+
+	Context("fail with invalid config", func() {
+		It("result", func() {
+			cfg.Concurrency = 0
+			_, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, http.DefaultClient).Work(ctx)
+			Expect(err.BusinessCode).To(Equal(business.Validation))
+
+			cfg.Concurrency, cfg.BatchSize = 1, 0
+			_, err = NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, http.DefaultClient).Work(ctx)
+			Expect(err.BusinessCode).To(Equal(business.Validation))
+		})
+	})
+
+	Context("truncate error on rune boundary", func() {
+		It("result", func() {
+			truncated := truncateUTF8(strings.Repeat("連線失敗", 100), maxHealthCheckErrorLength)
+			Expect(len(truncated)).To(BeNumerically("<=", maxHealthCheckErrorLength))
+			Expect(utf8.ValidString(truncated)).To(BeTrue())
+			Expect(truncateUTF8("short", maxHealthCheckErrorLength)).To(Equal("short"))
+		})
+	})
+
+	Context("fail with list urls", func() {
+		var listErr *business.Error
+		BeforeEach(func() {
+			listErr = business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", nil)
+			mockUrlDAO.EXPECT().List(gomock.Any(), "", cfg.BatchSize, false).Return(nil, listErr)
+		})
+
+		It("result", func() {
+			result, err := NewHealthCheckJob(cfg, loglib.NewNopLogger(), mockUrlDAO, mockHealthCheckDAO, http.DefaultClient).Work(ctx)
+			Expect(err).To(Equal(listErr))
+			Expect(result).To(BeNil())
+		})
+	})
+})
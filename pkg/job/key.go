@@ -1,18 +1,25 @@
 package job
 
 import (
+	"context"
+
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
 )
 
 func NewGenerateKeyJob(cfg GenerateKeyJobConfig, keyDAO dao.KeyDAO) *GenerateKeyJob {
 	return &GenerateKeyJob{cfg: cfg, keyDAO: keyDAO}
 }
 
+// GenerateKeyJobConfig key數量低於LowWatermark時補到HighWatermark
 type GenerateKeyJobConfig struct {
-	Name           string
-	EveryKeyNumber int
-	TimerFormat    string
+	Name          string
+	TimerFormat   string
+	LowWatermark  int
+	HighWatermark int
+	// 一次insert最多幾個key 避免一個transaction太大
+	MaxBatchSize int
 }
 
 type GenerateKeyJob struct {
@@ -24,13 +31,56 @@ func (g *GenerateKeyJob) Name() string {
 	return g.cfg.Name
 }
 
-func (g *GenerateKeyJob) Work() (map[string]interface{}, *business.Error) {
+func (g *GenerateKeyJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
+	poolSize, err := g.keyDAO.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.KeyPoolSize.Set(float64(poolSize))
+
 	var result = make(map[string]interface{})
-	length, err := g.keyDAO.BatchCreate(g.cfg.EveryKeyNumber)
-	if err == nil {
-		result["length"] = length
+	result["poolSize"] = poolSize
+	if poolSize >= g.cfg.LowWatermark {
+		result["refilled"] = false
+		result["requested"] = 0
+		result["inserted"] = 0
+		result["skipped"] = 0
+		return result, nil
 	}
-	return result, err
+
+	metrics.KeyPoolRefills.Inc()
+	requested, inserted := 0, 0
+	record := func() {
+		metrics.KeyPoolGeneratedKeys.Add(float64(inserted))
+		metrics.KeyPoolSkippedKeys.Add(float64(requested - inserted))
+		metrics.KeyPoolSize.Set(float64(poolSize + inserted))
+	}
+	for poolSize+inserted < g.cfg.HighWatermark {
+		num := g.cfg.HighWatermark - poolSize - inserted
+		if num > g.cfg.MaxBatchSize {
+			num = g.cfg.MaxBatchSize
+		}
+		batchInserted, err := g.keyDAO.BatchCreate(ctx, num)
+		if err != nil {
+			record()
+			return nil, err
+		}
+		requested += num
+		inserted += batchInserted
+		// 整批都撞到已存在的key或url 代表key space快滿了 不要無限迴圈
+		if batchInserted == 0 {
+			break
+		}
+	}
+	record()
+
+	result["refilled"] = true
+	result["requested"] = requested
+	result["inserted"] = inserted
+	// 跟keys或urls裡已有的id重複 或是同一批內重複而沒有insert的數量
+	result["skipped"] = requested - inserted
+	result["poolSizeAfter"] = poolSize + inserted
+	return result, nil
 }
 
 func (g *GenerateKeyJob) TimerFormat() string {
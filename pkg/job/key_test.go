@@ -0,0 +1,125 @@
+package job
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/daomock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GenerateKeyJob", func() {
+	ctx := context.Background()
+	var mockCtrl *gomock.Controller
+	var mockKeyDAO *daomock.MockKeyDAO
+	var generateKeyJob *GenerateKeyJob
+	var (
+		result    map[string]interface{}
+		workErr   *business.Error
+		refills   float64
+		generated float64
+		skipped   float64
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKeyDAO = daomock.NewMockKeyDAO(mockCtrl)
+		generateKeyJob = NewGenerateKeyJob(GenerateKeyJobConfig{Name: "GenerateKeyJob", LowWatermark: 100, HighWatermark: 300, MaxBatchSize: 150}, mockKeyDAO)
+		refills = testutil.ToFloat64(metrics.KeyPoolRefills)
+		generated = testutil.ToFloat64(metrics.KeyPoolGeneratedKeys)
+		skipped = testutil.ToFloat64(metrics.KeyPoolSkippedKeys)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		result, workErr = generateKeyJob.Work(ctx)
+	})
+
+	Context("pool above low watermark", func() {
+		BeforeEach(func() {
+			mockKeyDAO.EXPECT().Count(gomock.Any()).Return(100, nil)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result).To(Equal(map[string]interface{}{"poolSize": 100, "refilled": false, "requested": 0, "inserted": 0, "skipped": 0}))
+			Expect(testutil.ToFloat64(metrics.KeyPoolSize)).To(Equal(float64(100)))
+			Expect(testutil.ToFloat64(metrics.KeyPoolRefills)).To(Equal(refills))
+		})
+	})
+
+	Context("refill up to high watermark in batches", func() {
+		BeforeEach(func() {
+			mockKeyDAO.EXPECT().Count(gomock.Any()).Return(20, nil)
+			gomock.InOrder(
+				mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), 150).Return(150, nil),
+				// 有一部分key撞到已經存在的
+				mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), 130).Return(120, nil),
+				mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), 10).Return(10, nil),
+			)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result).To(Equal(map[string]interface{}{"poolSize": 20, "refilled": true, "requested": 290, "inserted": 280, "skipped": 10, "poolSizeAfter": 300}))
+			Expect(testutil.ToFloat64(metrics.KeyPoolSize)).To(Equal(float64(300)))
+			Expect(testutil.ToFloat64(metrics.KeyPoolRefills)).To(Equal(refills + 1))
+			Expect(testutil.ToFloat64(metrics.KeyPoolGeneratedKeys)).To(Equal(generated + 280))
+			Expect(testutil.ToFloat64(metrics.KeyPoolSkippedKeys)).To(Equal(skipped + 10))
+		})
+	})
+
+	Context("stop when a whole batch collides", func() {
+		BeforeEach(func() {
+			mockKeyDAO.EXPECT().Count(gomock.Any()).Return(50, nil)
+			gomock.InOrder(
+				mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), 150).Return(100, nil),
+				mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), 150).Return(0, nil),
+			)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result["requested"]).To(Equal(300))
+			Expect(result["inserted"]).To(Equal(100))
+			Expect(result["skipped"]).To(Equal(200))
+			Expect(result["poolSizeAfter"]).To(Equal(150))
+		})
+	})
+
+	Context("fail with count", func() {
+		var countErr *business.Error
+		BeforeEach(func() {
+			countErr = business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", nil)
+			mockKeyDAO.EXPECT().Count(gomock.Any()).Return(0, countErr)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(Equal(countErr))
+			Expect(result).To(BeNil())
+		})
+	})
+
+	Context("fail with batch create", func() {
+		var createErr *business.Error
+		BeforeEach(func() {
+			createErr = business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", nil)
+			mockKeyDAO.EXPECT().Count(gomock.Any()).Return(0, nil)
+			mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), 150).Return(0, createErr)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(Equal(createErr))
+			Expect(result).To(BeNil())
+		})
+	})
+})
@@ -1,7 +1,10 @@
 package job
 
 import (
+	"context"
 	"fmt"
+	"time"
+
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/robfig/cron/v3"
@@ -11,37 +14,37 @@ import (
 type Job interface {
 	Name() string
 	TimerFormat() string
-	Work() (map[string]interface{}, *business.Error)
+	Work(ctx context.Context) (map[string]interface{}, *business.Error)
 }
 
-func NewManager(jobs []Job, logger *loglib.Logger) *Manager {
+// NewManager timeout是每一輪job最多可以跑多久 0則只有Stop時才會取消
+func NewManager(jobs []Job, logger *loglib.Logger, timeout time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Manager{
-		cron:   cron.New(),
-		jobs:   jobs,
-		logger: logger,
+		cron:    cron.New(),
+		jobs:    jobs,
+		logger:  logger,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
 	}
 }
 
 type Manager struct {
-	cron   *cron.Cron
-	jobs   []Job
-	logger *loglib.Logger
+	cron    *cron.Cron
+	jobs    []Job
+	logger  *loglib.Logger
+	timeout time.Duration
+	// Stop時取消 讓正在跑的job不用做完整輪
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func (m *Manager) Start() {
 	for _, job := range m.jobs {
+		job := job
 		_, err := m.cron.AddFunc(job.TimerFormat(), func() {
-			m.logger.Info(fmt.Sprintf("job:%s start to run this round", job.Name()))
-			result, err := job.Work()
-			if err != nil {
-				m.logger.Error(fmt.Sprintf("job:%s fail this round", job.Name()), zap.Error(err))
-				return
-			}
-			var fields []zap.Field
-			for key, value := range result {
-				fields = append(fields, zap.Any(key, value))
-			}
-			m.logger.Info(fmt.Sprintf("job:%s success this round", job.Name()), fields...)
+			m.run(job)
 		})
 		if err != nil {
 			panic(err)
@@ -50,7 +53,31 @@ func (m *Manager) Start() {
 	m.cron.Start()
 }
 
+func (m *Manager) run(job Job) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if m.timeout > 0 {
+		ctx, cancel = context.WithTimeout(m.ctx, m.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(m.ctx)
+	}
+	defer cancel()
+
+	m.logger.Info(fmt.Sprintf("job:%s start to run this round", job.Name()))
+	result, err := job.Work(ctx)
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("job:%s fail this round", job.Name()), zap.Error(err))
+		return
+	}
+	var fields []zap.Field
+	for key, value := range result {
+		fields = append(fields, zap.Any(key, value))
+	}
+	m.logger.Info(fmt.Sprintf("job:%s success this round", job.Name()), fields...)
+}
+
 func (m *Manager) Stop() {
+	m.cancel()
 	ctx := m.cron.Stop()
 	select {
 	case <-ctx.Done():
@@ -0,0 +1,61 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestJob(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Job Suite")
+}
+
+type ctxRecordJob struct {
+	ctx context.Context
+}
+
+func (j *ctxRecordJob) Name() string {
+	return "ctxRecordJob"
+}
+
+func (j *ctxRecordJob) TimerFormat() string {
+	return "* * * * *"
+}
+
+func (j *ctxRecordJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
+	j.ctx = ctx
+	return nil, nil
+}
+
+var _ = Describe("Manager", func() {
+	var recorder *ctxRecordJob
+
+	BeforeEach(func() {
+		recorder = &ctxRecordJob{}
+	})
+
+	It("run each round with the timeout", func() {
+		manager := NewManager([]Job{recorder}, loglib.NewNopLogger(), time.Minute)
+		manager.run(recorder)
+
+		deadline, ok := recorder.ctx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+		// 這一輪跑完就釋放
+		Expect(recorder.ctx.Err()).To(Equal(context.Canceled))
+	})
+
+	It("run without deadline when timeout is 0", func() {
+		manager := NewManager([]Job{recorder}, loglib.NewNopLogger(), 0)
+		manager.run(recorder)
+
+		_, ok := recorder.ctx.Deadline()
+		Expect(ok).To(BeFalse())
+	})
+})
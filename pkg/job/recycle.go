@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+)
+
+func NewRecycleKeyJob(cfg RecycleKeyJobConfig, keyDAO dao.KeyDAO) *RecycleKeyJob {
+	return &RecycleKeyJob{cfg: cfg, keyDAO: keyDAO}
+}
+
+// RecycleKeyJobConfig 每次最多把ReleaseNumber個冷卻期已過的id放回keys
+type RecycleKeyJobConfig struct {
+	Name          string
+	TimerFormat   string
+	ReleaseNumber int
+}
+
+type RecycleKeyJob struct {
+	cfg    RecycleKeyJobConfig
+	keyDAO dao.KeyDAO
+}
+
+func (r *RecycleKeyJob) Name() string {
+	return r.cfg.Name
+}
+
+func (r *RecycleKeyJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
+	released, err := r.keyDAO.ReleaseQuarantined(ctx, r.cfg.ReleaseNumber)
+	if err != nil {
+		return nil, err
+	}
+	metrics.KeyPoolRecycledKeys.Add(float64(released))
+
+	quarantined, err := r.keyDAO.CountQuarantined(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.KeyQuarantineSize.Set(float64(quarantined))
+
+	var result = make(map[string]interface{})
+	result["released"] = released
+	result["quarantined"] = quarantined
+	return result, nil
+}
+
+func (r *RecycleKeyJob) TimerFormat() string {
+	return r.cfg.TimerFormat
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/daomock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/metrics"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecycleKeyJob", func() {
+	ctx := context.Background()
+	var mockCtrl *gomock.Controller
+	var mockKeyDAO *daomock.MockKeyDAO
+	var recycleKeyJob *RecycleKeyJob
+	var (
+		result   map[string]interface{}
+		workErr  *business.Error
+		recycled float64
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKeyDAO = daomock.NewMockKeyDAO(mockCtrl)
+		recycleKeyJob = NewRecycleKeyJob(RecycleKeyJobConfig{Name: "RecycleKeyJob", ReleaseNumber: 1000}, mockKeyDAO)
+		recycled = testutil.ToFloat64(metrics.KeyPoolRecycledKeys)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		result, workErr = recycleKeyJob.Work(ctx)
+	})
+
+	Context("success", func() {
+		BeforeEach(func() {
+			gomock.InOrder(
+				mockKeyDAO.EXPECT().ReleaseQuarantined(gomock.Any(), 1000).Return(30, nil),
+				mockKeyDAO.EXPECT().CountQuarantined(gomock.Any()).Return(70, nil),
+			)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(BeNil())
+			Expect(result).To(Equal(map[string]interface{}{"released": 30, "quarantined": 70}))
+			Expect(testutil.ToFloat64(metrics.KeyPoolRecycledKeys)).To(Equal(recycled + 30))
+			Expect(testutil.ToFloat64(metrics.KeyQuarantineSize)).To(Equal(float64(70)))
+		})
+	})
+
+	Context("fail with release", func() {
+		var releaseErr *business.Error
+		BeforeEach(func() {
+			releaseErr = business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", nil)
+			mockKeyDAO.EXPECT().ReleaseQuarantined(gomock.Any(), 1000).Return(0, releaseErr)
+		})
+
+		It("result", func() {
+			Expect(workErr).To(Equal(releaseErr))
+			Expect(result).To(BeNil())
+		})
+	})
+})
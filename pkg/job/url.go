@@ -1,6 +1,8 @@
 package job
 
 import (
+	"context"
+
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
 )
@@ -25,18 +27,18 @@ func (e *ExpiredURLJob) Name() string {
 	return e.cfg.Name
 }
 
-func (e *ExpiredURLJob) Work() (map[string]interface{}, *business.Error) {
+func (e *ExpiredURLJob) Work(ctx context.Context) (map[string]interface{}, *business.Error) {
 	var result = make(map[string]interface{})
-	ids, err := e.urlDAO.Expire(e.cfg.ExpireURLNumber)
+	ids, err := e.urlDAO.Expire(ctx, e.cfg.ExpireURLNumber)
 	if err != nil {
 		return nil, err
 	}
-	err = e.cacheDAO.DeleteMultiOriginalURL(ids)
+	err = e.cacheDAO.DeleteMultiOriginalURL(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = e.cacheDAO.DeleteMultiOriginalURLIDInFilters(ids)
+	_, err = e.cacheDAO.DeleteMultiOriginalURLIDInFilters(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
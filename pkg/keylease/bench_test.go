@@ -0,0 +1,148 @@
+package keylease
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/migrationlib"
+	"github.com/KennyChenFight/golib/pglib"
+	"github.com/KennyChenFight/randstr"
+	"github.com/go-pg/pg/v10"
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// benchmark只用自己產生的key 帶著這個prefix 跑在共用的database上也不會用掉真正的key
+const benchKeyPrefix = "KLBENCH"
+const benchKeyRandomLength = 9
+const benchOriginalURL = "http://example.com/keylease-benchmark"
+
+// BenchmarkCreateWithPool 原本每次建立都鎖keys table拿一個key
+func BenchmarkCreateWithPool(b *testing.B) {
+	benchmarkCreate(b, func(keyDAO dao.KeyDAO, pool dao.KeyStrategy) (dao.KeyStrategy, func()) {
+		return pool, func() {}
+	})
+}
+
+// BenchmarkCreateWithLease 從記憶體裡lease到的key拿
+func BenchmarkCreateWithLease(b *testing.B) {
+	benchmarkCreate(b, func(keyDAO dao.KeyDAO, pool dao.KeyStrategy) (dao.KeyStrategy, func()) {
+		manager := NewManager(Config{BlockSize: 1000, RefillThreshold: 200}, loglib.NewNopLogger(), keyDAO)
+		manager.Start()
+		return dao.NewLeaseKeyStrategy(manager, pool), func() { manager.Close() }
+	})
+}
+
+func benchmarkCreate(b *testing.B, newStrategy func(keyDAO dao.KeyDAO, pool dao.KeyStrategy) (dao.KeyStrategy, func())) {
+	client := setupBenchPG(b)
+	defer client.Close()
+	logger := loglib.NewNopLogger()
+	keyDAO := &benchKeyDAO{KeyDAO: dao.NewPGKeyDAO(logger, client, nil, 0), client: client}
+
+	generator := randstr.NewFastGenerator(randstr.CharSetBase62)
+	for filled := 0; filled < b.N; {
+		num := b.N - filled
+		if num > 5000 {
+			num = 5000
+		}
+		keys := make([]dao.Key, 0, num)
+		now := time.Now()
+		for i := 0; i < num; i++ {
+			keys = append(keys, dao.Key{ID: benchKeyPrefix + generator.GenerateRandomStr(benchKeyRandomLength), CreatedAt: now})
+		}
+		res, err := client.Model(&keys).OnConflict("DO NOTHING").Insert()
+		if err != nil {
+			b.Fatal(err)
+		}
+		filled += res.RowsAffected()
+	}
+
+	strategy, closeFunc := newStrategy(keyDAO, benchPoolKeyStrategy{})
+	urlDAO := dao.NewPGUrlDAO(logger, client, 0, strategy)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := urlDAO.Create(context.Background(), benchOriginalURL); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.StopTimer()
+
+	closeFunc()
+	if _, err := client.Exec("DELETE FROM urls WHERE id LIKE ?", benchKeyPrefix+"%"); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := client.Exec("DELETE FROM keys WHERE id LIKE ?", benchKeyPrefix+"%"); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// benchKeyDAO 只lease benchmark自己的key
+type benchKeyDAO struct {
+	dao.KeyDAO
+	client *pglib.GOPGClient
+}
+
+func (k *benchKeyDAO) Lease(ctx context.Context, num int) ([]string, *business.Error) {
+	var ids []string
+	_, err := k.client.QueryContext(ctx, &ids, `DELETE FROM keys WHERE id IN (
+			SELECT id FROM keys WHERE id LIKE ? LIMIT ? FOR UPDATE SKIP LOCKED
+		) RETURNING id`, benchKeyPrefix+"%", num)
+	if err != nil {
+		return nil, business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", err)
+	}
+	return ids, nil
+}
+
+// benchPoolKeyStrategy 跟pool strategy一樣的query 只是只拿benchmark自己的key
+type benchPoolKeyStrategy struct{}
+
+func (benchPoolKeyStrategy) Assign(tx *pg.Tx, url *dao.URL) error {
+	res, err := tx.Model((*dao.URL)(nil)).Query(pg.Scan(&url.ID), "INSERT INTO urls SELECT id, ?, ?, ? FROM keys WHERE id LIKE ? FOR UPDATE SKIP LOCKED LIMIT 1 RETURNING id",
+		url.Original, url.CreatedAt, url.ExpiredAt, benchKeyPrefix+"%")
+	if err != nil {
+		return err
+	}
+	if res.RowsReturned() == 0 {
+		return errors.New(dao.PGErrMsgNoKeyAvailable)
+	}
+	_, err = tx.Model(&dao.Key{ID: url.ID}).WherePK().Delete()
+	return err
+}
+
+func setupBenchPG(b *testing.B) *pglib.GOPGClient {
+	pgURL := os.Getenv("POSTGRES_URL")
+	if pgURL == "" {
+		b.Skip("should setup postgres url")
+	}
+
+	client, err := pglib.NewDefaultGOPGClient(pglib.GOPGConfig{URL: pgURL, PoolSize: 20})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	migrationFileDir := os.Getenv("POSTGRES_MIGRATION_FILE_DIR")
+	if migrationFileDir == "" {
+		migrationFileDir = "file://../../migrations"
+	}
+	migration := migrationlib.NewMigrateLib(migrationlib.Config{
+		DatabaseDriver: migrationlib.PostgresDriver,
+		DatabaseURL:    pgURL,
+		SourceDriver:   migrationlib.FileDriver,
+		SourceURL:      migrationFileDir,
+		TableName:      "migrate_version",
+	})
+	if err := migration.Up(); err != nil && err != migrate.ErrNoChange {
+		b.Fatal(err)
+	}
+	return client
+}
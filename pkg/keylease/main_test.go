@@ -0,0 +1,13 @@
+package keylease
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestKeyLease(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "KeyLease Suite")
+}
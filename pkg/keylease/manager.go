@@ -0,0 +1,141 @@
+package keylease
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/golib/loglib"
+	"go.uber.org/zap"
+)
+
+// Config 每次從keys lease BlockSize個key 記憶體裡剩不到RefillThreshold個時在背景再lease一批
+// Timeout是每次lease以及歸還最多花多久 0則不限制
+type Config struct {
+	BlockSize       int
+	RefillThreshold int
+	Timeout         time.Duration
+}
+
+func NewManager(cfg Config, logger *loglib.Logger, keyDAO dao.KeyDAO) *Manager {
+	return &Manager{cfg: cfg, logger: logger, keyDAO: keyDAO}
+}
+
+// Manager 每個server instance各自持有一批key 建立縮網址時不用每次都去鎖keys table
+// 沒有graceful shutdown的話 記憶體裡還沒用掉的key就不會回到keys 只是浪費掉那些id
+type Manager struct {
+	cfg    Config
+	logger *loglib.Logger
+	keyDAO dao.KeyDAO
+
+	mu        sync.Mutex
+	keys      []string
+	refilling bool
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Start 先同步lease第一批 失敗的話之後Take會再觸發refill
+func (m *Manager) Start() {
+	m.mu.Lock()
+	m.refilling = true
+	m.wg.Add(1)
+	m.mu.Unlock()
+	m.refill()
+}
+
+func (m *Manager) Take() (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return "", false
+	}
+
+	var id string
+	ok := len(m.keys) > 0
+	if ok {
+		id = m.keys[len(m.keys)-1]
+		m.keys = m.keys[:len(m.keys)-1]
+	}
+	if len(m.keys) < m.cfg.RefillThreshold && !m.refilling {
+		m.refilling = true
+		m.wg.Add(1)
+		go m.refill()
+	}
+	return id, ok
+}
+
+// Release 拿了但是沒用到的key放回記憶體 例如transaction rollback 已經Close的話直接還回keys
+func (m *Manager) Release(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	m.mu.Lock()
+	if !m.closed {
+		m.keys = append(m.keys, ids...)
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	ctx, cancel := m.withTimeout()
+	defer cancel()
+	if _, err := m.keyDAO.Return(ctx, ids); err != nil {
+		m.logger.Error("fail to return released keys", zap.Strings("ids", ids), zap.Error(err))
+	}
+}
+
+// Len 記憶體裡還剩幾個key
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.keys)
+}
+
+func (m *Manager) refill() {
+	defer m.wg.Done()
+	ctx, cancel := m.withTimeout()
+	defer cancel()
+	ids, err := m.keyDAO.Lease(ctx, m.cfg.BlockSize)
+	if err != nil {
+		m.logger.Error("fail to lease keys", zap.Error(err))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// 已經Close的話 Close會等refill結束再把這批一起還回去
+	m.keys = append(m.keys, ids...)
+	m.refilling = false
+}
+
+// Close 之後Take都會回傳false 等背景的refill結束後把沒用到的key還回keys
+func (m *Manager) Close() *business.Error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	keys := m.keys
+	m.keys = nil
+	m.mu.Unlock()
+
+	ctx, cancel := m.withTimeout()
+	defer cancel()
+	returned, err := m.keyDAO.Return(ctx, keys)
+	if err != nil {
+		return err
+	}
+	m.logger.Info("return leased keys", zap.Int("leased", len(keys)), zap.Int("returned", returned))
+	return nil
+}
+
+// withTimeout refill是在背景跑的 跟觸發它的request無關 所以從Background開始算
+func (m *Manager) withTimeout() (context.Context, context.CancelFunc) {
+	if m.cfg.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), m.cfg.Timeout)
+}
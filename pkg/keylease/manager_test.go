@@ -0,0 +1,159 @@
+package keylease
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/daomock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manager", func() {
+	var mockCtrl *gomock.Controller
+	var mockKeyDAO *daomock.MockKeyDAO
+	var manager *Manager
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKeyDAO = daomock.NewMockKeyDAO(mockCtrl)
+		manager = NewManager(Config{BlockSize: 3, RefillThreshold: 2}, loglib.NewNopLogger(), mockKeyDAO)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("serve leased keys and refill in background", func() {
+		BeforeEach(func() {
+			gomock.InOrder(
+				mockKeyDAO.EXPECT().Lease(gomock.Any(), 3).Return([]string{"aaaaaa", "bbbbbb", "cccccc"}, nil),
+				mockKeyDAO.EXPECT().Lease(gomock.Any(), 3).Return([]string{"dddddd", "eeeeee", "ffffff"}, nil),
+			)
+			mockKeyDAO.EXPECT().Return(gomock.Any(), gomock.Len(4)).Return(4, nil)
+		})
+
+		It("result", func() {
+			manager.Start()
+			Expect(manager.Len()).To(Equal(3))
+
+			id, ok := manager.Take()
+			Expect(ok).To(BeTrue())
+			Expect(id).To(Equal("cccccc"))
+			Expect(manager.Len()).To(Equal(2))
+
+			// 剩下不到2個 觸發背景refill
+			id, ok = manager.Take()
+			Expect(ok).To(BeTrue())
+			Expect(id).To(Equal("bbbbbb"))
+			Eventually(manager.Len).Should(Equal(4))
+
+			Expect(manager.Close()).To(BeNil())
+			Expect(manager.Len()).To(Equal(0))
+			_, ok = manager.Take()
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("fail to lease", func() {
+		BeforeEach(func() {
+			leaseErr := business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", nil)
+			mockKeyDAO.EXPECT().Lease(gomock.Any(), 3).Return(nil, leaseErr).Times(2)
+			mockKeyDAO.EXPECT().Return(gomock.Any(), gomock.Len(0)).Return(0, nil)
+		})
+
+		It("take nothing and retry on next take", func() {
+			manager.Start()
+			_, ok := manager.Take()
+			Expect(ok).To(BeFalse())
+			Expect(manager.Close()).To(BeNil())
+		})
+	})
+
+	Context("release unused keys", func() {
+		BeforeEach(func() {
+			mockKeyDAO.EXPECT().Lease(gomock.Any(), 3).Return([]string{"aaaaaa", "bbbbbb", "cccccc"}, nil)
+			mockKeyDAO.EXPECT().Return(gomock.Any(), gomock.Len(3)).Return(3, nil)
+			mockKeyDAO.EXPECT().Return(gomock.Any(), []string{"dddddd"}).Return(1, nil)
+		})
+
+		It("result", func() {
+			manager.Start()
+			id, ok := manager.Take()
+			Expect(ok).To(BeTrue())
+
+			// rollback的key放回記憶體 Close時一起還回keys
+			manager.Release([]string{id})
+			Expect(manager.Len()).To(Equal(3))
+			Expect(manager.Close()).To(BeNil())
+
+			// Close之後直接還回keys
+			manager.Release([]string{"dddddd"})
+			Expect(manager.Len()).To(Equal(0))
+		})
+	})
+
+	Context("return keys leased while closing", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			mockKeyDAO.EXPECT().Lease(gomock.Any(), 3).Return([]string{"aaaaaa"}, nil)
+			mockKeyDAO.EXPECT().Lease(gomock.Any(), 3).DoAndReturn(func(_ context.Context, num int) ([]string, *business.Error) {
+				<-release
+				return []string{"bbbbbb", "cccccc"}, nil
+			})
+			mockKeyDAO.EXPECT().Return(gomock.Any(), []string{"bbbbbb", "cccccc"}).Return(2, nil)
+		})
+
+		It("result", func() {
+			manager.Start()
+			_, ok := manager.Take()
+			Expect(ok).To(BeTrue())
+
+			closed := make(chan *business.Error)
+			go func() {
+				closed <- manager.Close()
+			}()
+			Consistently(closed).ShouldNot(Receive())
+			close(release)
+			Eventually(closed).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("lease and return with the timeout", func() {
+		var deadlines []time.Time
+
+		BeforeEach(func() {
+			deadlines = nil
+			manager = NewManager(Config{BlockSize: 3, RefillThreshold: 2, Timeout: time.Minute}, loglib.NewNopLogger(), mockKeyDAO)
+			record := func(ctx context.Context) {
+				deadline, ok := ctx.Deadline()
+				Expect(ok).To(BeTrue())
+				deadlines = append(deadlines, deadline)
+			}
+			mockKeyDAO.EXPECT().Lease(gomock.Any(), 3).DoAndReturn(func(ctx context.Context, num int) ([]string, *business.Error) {
+				record(ctx)
+				return []string{"aaaaaa", "bbbbbb", "cccccc"}, nil
+			})
+			mockKeyDAO.EXPECT().Return(gomock.Any(), gomock.Len(3)).DoAndReturn(func(ctx context.Context, ids []string) (int, *business.Error) {
+				record(ctx)
+				return len(ids), nil
+			})
+		})
+
+		It("result", func() {
+			manager.Start()
+			Expect(manager.Close()).To(BeNil())
+			Expect(deadlines).To(HaveLen(2))
+			for _, deadline := range deadlines {
+				Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+			}
+		})
+	})
+})
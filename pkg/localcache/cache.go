@@ -0,0 +1,83 @@
+package localcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache process內的LRU cache 超過size時淘汰最久沒用到的 超過ttl的視為不存在
+type Cache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key      string
+	value    string
+	expireAt time.Time
+}
+
+func New(size int, ttl time.Duration) *Cache {
+	return &Cache{size: size, ttl: ttl, now: time.Now, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *Cache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expireAt) {
+		c.remove(el)
+		return "", false
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *Cache) Set(key string, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expireAt := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value = value
+		e.expireAt = expireAt
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value, expireAt: expireAt})
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *Cache) Delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// Len 包含已經過期但還沒被清掉的
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
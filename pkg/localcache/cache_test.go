@@ -0,0 +1,67 @@
+package localcache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	var cache *Cache
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Now()
+		cache = New(2, time.Minute)
+		cache.now = func() time.Time { return now }
+	})
+
+	mustGet := func(key string) string {
+		value, ok := cache.Get(key)
+		Expect(ok).To(BeTrue())
+		return value
+	}
+
+	It("get what was set", func() {
+		cache.Set("a", "http://a.com")
+		Expect(mustGet("a")).To(Equal("http://a.com"))
+		_, ok := cache.Get("b")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("evict least recently used", func() {
+		cache.Set("a", "http://a.com")
+		cache.Set("b", "http://b.com")
+		cache.Get("a")
+		cache.Set("c", "http://c.com")
+		Expect(cache.Len()).To(Equal(2))
+		_, ok := cache.Get("b")
+		Expect(ok).To(BeFalse())
+		Expect(mustGet("a")).To(Equal("http://a.com"))
+		Expect(mustGet("c")).To(Equal("http://c.com"))
+	})
+
+	It("expire after ttl", func() {
+		cache.Set("a", "http://a.com")
+		now = now.Add(time.Minute)
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeFalse())
+		Expect(cache.Len()).To(Equal(0))
+	})
+
+	It("refresh ttl and value on set", func() {
+		cache.Set("a", "http://a.com")
+		now = now.Add(30 * time.Second)
+		cache.Set("a", "http://a2.com")
+		now = now.Add(45 * time.Second)
+		Expect(mustGet("a")).To(Equal("http://a2.com"))
+	})
+
+	It("delete keys", func() {
+		cache.Set("a", "http://a.com")
+		cache.Set("b", "http://b.com")
+		cache.Delete("a", "b", "c")
+		Expect(cache.Len()).To(Equal(0))
+	})
+})
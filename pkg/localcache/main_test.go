@@ -0,0 +1,13 @@
+package localcache
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLocalCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LocalCache Suite")
+}
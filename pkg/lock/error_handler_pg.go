@@ -0,0 +1,18 @@
+package lock
+
+import (
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	"go.uber.org/zap"
+)
+
+func pgErrorHandle(logger *loglib.Logger, err error) *business.Error {
+	if contextErr := business.NewContextError(err); contextErr != nil {
+		logger.Warn("postgres canceled", zap.Error(err))
+		return contextErr
+	}
+	logger.Error("postgres internal error", zap.Error(err))
+	return business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", err)
+}
@@ -0,0 +1,18 @@
+package lock
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/golib/loglib"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pgErrorHandle", func() {
+	It("internal error", func() {
+		internalError := errors.New("internal error")
+		Expect(pgErrorHandle(loglib.NewNopLogger(), internalError)).To(Equal(business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", internalError)))
+	})
+})
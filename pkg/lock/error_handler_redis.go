@@ -15,6 +15,10 @@ var (
 )
 
 func redisErrorHandle(logger *loglib.Logger, err error) *business.Error {
+	if contextErr := business.NewContextError(err); contextErr != nil {
+		logger.Warn("redis canceled", zap.Error(err))
+		return contextErr
+	}
 	switch {
 	case err == redis.Nil:
 		return business.NewError(business.NotFound, http.StatusNotFound, "record not found", RedisErrKeyNotExist)
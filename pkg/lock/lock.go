@@ -1,14 +1,17 @@
 package lock
 
 import (
+	"context"
 	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 )
 
 type Locker interface {
-	AcquireLock(name string, lockDuration, waitTime time.Duration) (bool, *business.Error)
-	ReleaseLock(name string) *business.Error
+	// AcquireLock 拿到lock時回傳這一次持有的token ctx被取消時不再等lock 跟等不到lock一樣回傳false
+	AcquireLock(ctx context.Context, name string, lockDuration, waitTime time.Duration) (token string, ok bool, err *business.Error)
+	// ReleaseLock 只會刪掉token那一次拿到的lock 已經過期被別人(包括同一個process)拿走的不會動
+	ReleaseLock(ctx context.Context, name, token string) *business.Error
 }
 
 // 可以根據系統的performance來做更改
@@ -0,0 +1,85 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+)
+
+func NewMemoryLockTable() *MemoryLockTable {
+	return &MemoryLockTable{locks: make(map[string]memoryLock)}
+}
+
+// MemoryLockTable 取代redis放lock的地方 只在同一個process裡有效
+type MemoryLockTable struct {
+	mu    sync.Mutex
+	locks map[string]memoryLock
+}
+
+type memoryLock struct {
+	token string
+	// zero代表不會過期
+	expiredAt time.Time
+}
+
+// tryLock 跟SET NX一樣 過期的lock當作不存在
+func (t *MemoryLockTable) tryLock(name, token string, lockDuration time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if held, ok := t.locks[name]; ok && (held.expiredAt.IsZero() || held.expiredAt.After(now)) {
+		return false
+	}
+	lock := memoryLock{token: token}
+	if lockDuration > 0 {
+		lock.expiredAt = now.Add(lockDuration)
+	}
+	t.locks[name] = lock
+	return true
+}
+
+// unlock token是自己的才刪
+func (t *MemoryLockTable) unlock(name, token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if held, ok := t.locks[name]; ok && held.token == token {
+		delete(t.locks, name)
+	}
+}
+
+// NewMemoryLocker 同一個table的locker互斥 release時帶token 只會刪掉那一次拿到的lock
+func NewMemoryLocker(table *MemoryLockTable) *MemoryLocker {
+	return &MemoryLocker{table: table}
+}
+
+type MemoryLocker struct {
+	table *MemoryLockTable
+}
+
+func (m *MemoryLocker) AcquireLock(ctx context.Context, name string, lockDuration, waitTime time.Duration) (string, bool, *business.Error) {
+	token := tokenFunc()
+	for _, t := range waitTimeSeries(waitTime) {
+		if ctx.Err() != nil {
+			return "", false, nil
+		}
+		if m.table.tryLock(name, token, lockDuration) {
+			return token, true, nil
+		}
+
+		timer := time.NewTimer(t)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", false, nil
+		case <-timer.C:
+		}
+	}
+	return "", false, nil
+}
+
+func (m *MemoryLocker) ReleaseLock(ctx context.Context, name, token string) *business.Error {
+	m.table.unlock(name, token)
+	return nil
+}
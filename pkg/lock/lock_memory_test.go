@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryLocker", func() {
+	var table *MemoryLockTable
+
+	BeforeEach(func() {
+		table = NewMemoryLockTable()
+	})
+
+	var _ = Describe("Locker", func() {
+		lockerBehaviors(func() Locker {
+			return NewMemoryLocker(table)
+		})
+		expiredHolderBehaviors(func() Locker {
+			return NewMemoryLocker(table)
+		})
+	})
+
+	var _ = Describe("AcquireLock", func() {
+		It("get lock expired without release", func() {
+			ctx := context.Background()
+			name := "resourceName"
+			_, ok, err := NewMemoryLocker(table).AcquireLock(ctx, name, 50*time.Millisecond, 0)
+			Expect(err).To(BeNil())
+			Expect(ok).To(Equal(true))
+			_, ok, err = NewMemoryLocker(table).AcquireLock(ctx, name, time.Minute, time.Second)
+			Expect(err).To(BeNil())
+			Expect(ok).To(Equal(true))
+		})
+	})
+
+	var _ = Describe("ReleaseLock", func() {
+		It("not delete lock taken by others after expired", func() {
+			ctx := context.Background()
+			name := "resourceName"
+			locker := NewMemoryLocker(table)
+			others := NewMemoryLocker(table)
+			token, ok, err := locker.AcquireLock(ctx, name, 50*time.Millisecond, 0)
+			Expect(err).To(BeNil())
+			Expect(ok).To(Equal(true))
+			_, ok, err = others.AcquireLock(ctx, name, time.Minute, time.Second)
+			Expect(err).To(BeNil())
+			Expect(ok).To(Equal(true))
+			Expect(locker.ReleaseLock(ctx, name, token)).To(BeNil())
+			_, ok, err = NewMemoryLocker(table).AcquireLock(ctx, name, time.Minute, 0)
+			Expect(err).To(BeNil())
+			Expect(ok).To(Equal(false))
+		})
+	})
+})
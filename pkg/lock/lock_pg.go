@@ -0,0 +1,107 @@
+package lock
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/KennyChenFight/golib/pglib"
+)
+
+// NewPGLocker 用postgres的session advisory lock 不會因為redis被清空就失效
+// 拿到lock的連線會一直被佔用到ReleaseLock 所以POSTGRES_POOL_SIZE要比同時持有的lock數量大
+func NewPGLocker(logger *loglib.Logger, client *pglib.GOPGClient) *PGLocker {
+	return &PGLocker{logger: logger, client: client, held: make(map[string]heldConn)}
+}
+
+// heldConn 持有lock的連線 以及拿lock時的name
+type heldConn struct {
+	name string
+	conn *pg.Conn
+}
+
+type PGLocker struct {
+	logger *loglib.Logger
+	client *pglib.GOPGClient
+
+	mu sync.Mutex
+	// key是token 每次拿到的lock各自佔用一條連線
+	held map[string]heldConn
+}
+
+// advisory lock的key是bigint 用name的hash 撞到的機率可以忽略
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AcquireLock session lock沒有ttl 所以不看lockDuration 持有的process掛掉時連線斷掉就會自動release
+func (p *PGLocker) AcquireLock(ctx context.Context, name string, lockDuration, waitTime time.Duration) (string, bool, *business.Error) {
+	// lock跟unlock一定要在同一個連線 不然unlock不掉
+	conn := p.client.Conn()
+	key := advisoryLockKey(name)
+	for _, t := range waitTimeSeries(waitTime) {
+		if ctx.Err() != nil {
+			break
+		}
+		var ok bool
+		_, err := conn.QueryOneContext(ctx, pg.Scan(&ok), "SELECT pg_try_advisory_lock(?)", key)
+		if err != nil {
+			conn.Close()
+			if ctx.Err() != nil {
+				return "", false, nil
+			}
+			return "", false, pgErrorHandle(p.logger, err)
+		}
+
+		if ok {
+			token := tokenFunc()
+			p.mu.Lock()
+			p.held[token] = heldConn{name: name, conn: conn}
+			p.mu.Unlock()
+			return token, true, nil
+		}
+
+		timer := time.NewTimer(t)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+	conn.Close()
+	return "", false, nil
+}
+
+func (p *PGLocker) ReleaseLock(ctx context.Context, name, token string) *business.Error {
+	p.mu.Lock()
+	held, ok := p.held[token]
+	// 跟RedisLocker一樣 name對不上的話不算release 連線繼續佔著 之後還可以用正確的name放掉
+	if ok && held.name == name {
+		delete(p.held, token)
+	}
+	p.mu.Unlock()
+	if !ok || held.name != name {
+		p.logger.Warn("release lock not held", zap.String("name", name))
+		return nil
+	}
+	conn := held.conn
+	defer conn.Close()
+
+	var unlocked bool
+	_, err := conn.QueryOneContext(ctx, pg.Scan(&unlocked), "SELECT pg_advisory_unlock(?)", advisoryLockKey(name))
+	if err != nil {
+		return pgErrorHandle(p.logger, err)
+	}
+	if !unlocked {
+		p.logger.Warn("lock lost before release", zap.String("name", name))
+	}
+	return nil
+}
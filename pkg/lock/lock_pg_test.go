@@ -0,0 +1,48 @@
+package lock
+
+import (
+	"context"
+
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/go-pg/pg/v10"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PGLocker", func() {
+	var logger *loglib.Logger
+
+	BeforeEach(func() {
+		requirePG()
+		logger = loglib.NewNopLogger()
+	})
+
+	var _ = Describe("Locker", func() {
+		lockerBehaviors(func() Locker {
+			return NewPGLocker(logger, testPGClient)
+		})
+	})
+
+	var _ = Describe("ReleaseLock", func() {
+		It("unlock on the holding connection", func() {
+			ctx := context.Background()
+			pgLocker := NewPGLocker(logger, testPGClient)
+			name := "resourceName"
+			token, ok, err := pgLocker.AcquireLock(ctx, name, 0, 0)
+			Expect(err).To(BeNil())
+			Expect(ok).To(Equal(true))
+			Expect(pgLocker.held).To(HaveKey(token))
+
+			var locks int
+			_, pgErr := testPGClient.QueryOne(pg.Scan(&locks), "SELECT count(*) FROM pg_locks WHERE locktype = 'advisory' AND objsubid = 1 AND (classid::bigint << 32 | objid::bigint) = ?", advisoryLockKey(name))
+			Expect(pgErr).To(BeNil())
+			Expect(locks).To(Equal(1))
+
+			Expect(pgLocker.ReleaseLock(ctx, name, token)).To(BeNil())
+			Expect(pgLocker.held).NotTo(HaveKey(token))
+			_, pgErr = testPGClient.QueryOne(pg.Scan(&locks), "SELECT count(*) FROM pg_locks WHERE locktype = 'advisory' AND objsubid = 1 AND (classid::bigint << 32 | objid::bigint) = ?", advisoryLockKey(name))
+			Expect(pgErr).To(BeNil())
+			Expect(locks).To(Equal(0))
+		})
+	})
+})
@@ -2,47 +2,154 @@ package lock
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
 	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
 
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/KennyChenFight/golib/redislib"
 )
 
-func NewRedisLocker(logger *loglib.Logger, client *redislib.GORedisClient) *RedisLocker {
-	return &RedisLocker{logger: logger, client: client}
+var (
+	// value是自己的token才刪 避免刪到過期後被別人拿走的lock
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+	extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+)
+
+var tokenFunc = newToken
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewRedisLocker watchdog開啟時 拿到lock之後會在背景每lockDuration/3延長一次 直到ReleaseLock
+// 關閉時工作超過lockDuration lock就會過期 其他人可以拿到
+func NewRedisLocker(logger *loglib.Logger, client *redislib.GORedisClient, watchdog bool) *RedisLocker {
+	return &RedisLocker{logger: logger, client: client, watchdog: watchdog, held: make(map[string]*heldLock)}
 }
 
 type RedisLocker struct {
-	logger *loglib.Logger
-	client *redislib.GORedisClient
+	logger   *loglib.Logger
+	client   *redislib.GORedisClient
+	watchdog bool
+
+	mu sync.Mutex
+	// key是token 同一個name過期後在同一個process裡被重新拿到時 每次持有還是分開的
+	held map[string]*heldLock
 }
 
-func (r *RedisLocker) AcquireLock(name string, lockDuration, waitTime time.Duration) (bool, *business.Error) {
+type heldLock struct {
+	name string
+	// 關掉watchdog
+	stop chan struct{}
+}
+
+func (r *RedisLocker) AcquireLock(ctx context.Context, name string, lockDuration, waitTime time.Duration) (string, bool, *business.Error) {
+	token := tokenFunc()
 	for _, t := range waitTimeSeries(waitTime) {
-		ok, err := r.client.SetNX(context.Background(), name, "", lockDuration).Result()
+		if ctx.Err() != nil {
+			return "", false, nil
+		}
+		ok, err := r.client.SetNX(ctx, name, token, lockDuration).Result()
 		if err != nil {
-			return false, redisErrorHandle(r.logger, err)
+			if ctx.Err() != nil {
+				return "", false, nil
+			}
+			return "", false, redisErrorHandle(r.logger, err)
 		}
 
 		if ok {
-			return true, nil
-		} else {
-			if t >= 0 {
-				time.Sleep(t)
-			} else {
-				break
-			}
+			r.hold(name, token, lockDuration)
+			return token, true, nil
+		}
+
+		timer := time.NewTimer(t)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", false, nil
+		case <-timer.C:
 		}
 	}
-	return false, nil
+	return "", false, nil
 }
 
-func (r *RedisLocker) ReleaseLock(name string) *business.Error {
-	_, err := r.client.Del(context.Background(), name).Result()
+func (r *RedisLocker) ReleaseLock(ctx context.Context, name, token string) *business.Error {
+	r.mu.Lock()
+	held, ok := r.held[token]
+	// name對不上的話不算release 原本的lock之後還可以用正確的name放掉
+	if ok && held.name == name {
+		delete(r.held, token)
+	}
+	r.mu.Unlock()
+	if !ok || held.name != name {
+		r.logger.Warn("release lock not held", zap.String("name", name))
+		return nil
+	}
+	if held.stop != nil {
+		close(held.stop)
+	}
+
+	deleted, err := releaseScript.Run(ctx, r.client, []string{name}, token).Int()
 	if err != nil {
 		return redisErrorHandle(r.logger, err)
 	}
+	if deleted == 0 {
+		r.logger.Warn("lock expired before release", zap.String("name", name))
+	}
 	return nil
 }
+
+// hold 每次拿到的lock用自己的token記 過期的holder release時不會刪到之後拿到的
+func (r *RedisLocker) hold(name, token string, lockDuration time.Duration) {
+	held := &heldLock{name: name}
+	if r.watchdog && lockDuration > 0 {
+		held.stop = make(chan struct{})
+		go r.renew(name, token, held, lockDuration)
+	}
+
+	r.mu.Lock()
+	r.held[token] = held
+	r.mu.Unlock()
+}
+
+func (r *RedisLocker) renew(name, token string, held *heldLock, lockDuration time.Duration) {
+	ticker := time.NewTicker(lockDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-held.stop:
+			return
+		case <-ticker.C:
+			extended, err := extendScript.Run(context.Background(), r.client, []string{name}, token, lockDuration.Milliseconds()).Int()
+			if err != nil {
+				// 下一次再試 連續失敗到lockDuration就會過期
+				r.logger.Error("fail to extend lock", zap.String("name", name), zap.Error(err))
+				continue
+			}
+			if extended == 0 {
+				r.logger.Warn("lock lost before release", zap.String("name", name))
+				return
+			}
+		}
+	}
+}
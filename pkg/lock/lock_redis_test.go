@@ -9,6 +9,7 @@ import (
 	"github.com/KennyChenFight/golib/redislib"
 	"github.com/go-redis/redis/v8"
 	"github.com/go-redis/redismock/v8"
+	"github.com/prashantv/gostub"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/golib/loglib"
@@ -21,12 +22,23 @@ var _ = Describe("RedisLocker", func() {
 	var logger *loglib.Logger
 
 	BeforeEach(func() {
+		requireRedis()
 		logger = loglib.NewNopLogger()
-		redisLocker = NewRedisLocker(logger, testRedisClient)
+		redisLocker = NewRedisLocker(logger, testRedisClient, false)
+	})
+
+	var _ = Describe("Locker", func() {
+		lockerBehaviors(func() Locker {
+			return NewRedisLocker(logger, testRedisClient, false)
+		})
+		expiredHolderBehaviors(func() Locker {
+			return NewRedisLocker(logger, testRedisClient, false)
+		})
 	})
 
 	var _ = Describe("AcquireLock", func() {
 		var (
+			token         string
 			expectGetLock bool
 			getErr        *business.Error
 		)
@@ -37,7 +49,7 @@ var _ = Describe("RedisLocker", func() {
 		waitTime := time.Second
 
 		JustBeforeEach(func() {
-			expectGetLock, getErr = redisLocker.AcquireLock(name, lockDuration, waitTime)
+			token, expectGetLock, getErr = redisLocker.AcquireLock(ctx, name, lockDuration, waitTime)
 		})
 
 		Context("success get lock", func() {
@@ -55,14 +67,17 @@ var _ = Describe("RedisLocker", func() {
 			var businessSetErr *business.Error
 			var wrapper *redis.Client
 			var clientMock redismock.ClientMock
+			var stub *gostub.Stubs
 			BeforeEach(func() {
 				wrapper, clientMock = redismock.NewClientMock()
 				redisLocker.client = &redislib.GORedisClient{Client: wrapper}
 				setErr := errors.New("internal")
 				businessSetErr = business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", setErr)
-				clientMock.ExpectSetNX(name, "", lockDuration).SetErr(setErr)
+				stub = gostub.Stub(&tokenFunc, func() string { return "token" })
+				clientMock.ExpectSetNX(name, "token", lockDuration).SetErr(setErr)
 			})
 			AfterEach(func() {
+				stub.Reset()
 				wrapper.Close()
 				redisLocker.client = testRedisClient
 			})
@@ -88,23 +103,53 @@ var _ = Describe("RedisLocker", func() {
 			})
 		})
 
+		Context("with watchdog", func() {
+			BeforeEach(func() {
+				redisLocker.watchdog = true
+			})
+
+			AfterEach(func() {
+				Expect(redisLocker.ReleaseLock(ctx, name, token)).To(BeNil())
+			})
+
+			It("extend lock until release", func() {
+				Expect(getErr).To(BeNil())
+				Expect(expectGetLock).To(Equal(true))
+				Consistently(func() int64 {
+					return testRedisClient.Exists(ctx, name).Val()
+				}, 2*lockDuration, 100*time.Millisecond).Should(Equal(int64(1)))
+			})
+		})
 	})
 
 	var _ = Describe("ReleaseLock", func() {
 		var (
+			token      string
 			releaseErr *business.Error
 		)
 
 		ctx := context.Background()
 		name := "resourceName"
 
+		acquire := func() {
+			var ok bool
+			var err *business.Error
+			token, ok, err = redisLocker.AcquireLock(ctx, name, time.Minute, 0)
+			Expect(err).To(BeNil())
+			Expect(ok).To(Equal(true))
+		}
+
+		BeforeEach(func() {
+			token = ""
+		})
+
 		JustBeforeEach(func() {
-			releaseErr = redisLocker.ReleaseLock(name)
+			releaseErr = redisLocker.ReleaseLock(ctx, name, token)
 		})
 
 		Context("success", func() {
 			BeforeEach(func() {
-				Expect(testRedisClient.Set(ctx, name, "", -1).Err()).NotTo(HaveOccurred())
+				acquire()
 			})
 
 			It("result", func() {
@@ -113,6 +158,37 @@ var _ = Describe("RedisLocker", func() {
 			})
 		})
 
+		Context("lock expired and held by others", func() {
+			BeforeEach(func() {
+				acquire()
+				Expect(testRedisClient.Set(ctx, name, "others", -1).Err()).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(testRedisClient.Del(ctx, name).Err()).NotTo(HaveOccurred())
+			})
+
+			It("result", func() {
+				Expect(releaseErr).To(BeNil())
+				Expect(testRedisClient.Get(ctx, name).Val()).To(Equal("others"))
+			})
+		})
+
+		Context("not held", func() {
+			BeforeEach(func() {
+				Expect(testRedisClient.Set(ctx, name, "others", -1).Err()).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(testRedisClient.Del(ctx, name).Err()).NotTo(HaveOccurred())
+			})
+
+			It("result", func() {
+				Expect(releaseErr).To(BeNil())
+				Expect(testRedisClient.Get(ctx, name).Val()).To(Equal("others"))
+			})
+		})
+
 		Context("fail with redis internal problem", func() {
 			var businessSetErr *business.Error
 			var wrapper *redis.Client
@@ -122,7 +198,9 @@ var _ = Describe("RedisLocker", func() {
 				redisLocker.client = &redislib.GORedisClient{Client: wrapper}
 				setErr := errors.New("internal")
 				businessSetErr = business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", setErr)
-				clientMock.ExpectDel(name).SetErr(setErr)
+				token = "token"
+				redisLocker.held[token] = &heldLock{name: name}
+				clientMock.ExpectEvalSha(releaseScript.Hash(), []string{name}, "token").SetErr(setErr)
 			})
 			AfterEach(func() {
 				wrapper.Close()
@@ -0,0 +1,135 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// acquired 測試中拿到的lock AfterEach再用token放掉
+type acquired struct {
+	locker Locker
+	name   string
+	token  string
+}
+
+// lockerBehaviors 每個Locker實作都要通過 newLocker每次回傳新的instance 當作不同的server
+func lockerBehaviors(newLocker func() Locker) {
+	var locker, others Locker
+	var held []acquired
+	ctx := context.Background()
+	name := "sharedResourceName"
+	lockDuration := time.Minute
+
+	acquire := func(l Locker, ctx context.Context, name string, waitTime time.Duration) bool {
+		token, ok, err := l.AcquireLock(ctx, name, lockDuration, waitTime)
+		Expect(err).To(BeNil())
+		if ok {
+			Expect(token).NotTo(BeEmpty())
+			held = append(held, acquired{locker: l, name: name, token: token})
+		}
+		return ok
+	}
+
+	BeforeEach(func() {
+		locker = newLocker()
+		others = newLocker()
+		held = nil
+	})
+
+	AfterEach(func() {
+		for _, h := range held {
+			Expect(h.locker.ReleaseLock(ctx, h.name, h.token)).To(BeNil())
+		}
+	})
+
+	It("get free lock", func() {
+		Expect(acquire(locker, ctx, name, 0)).To(Equal(true))
+	})
+
+	It("fail with others hold the lock", func() {
+		Expect(acquire(others, ctx, name, 0)).To(Equal(true))
+		Expect(acquire(locker, ctx, name, 200*time.Millisecond)).To(Equal(false))
+	})
+
+	It("fail with the same locker hold the lock", func() {
+		Expect(acquire(locker, ctx, name, 0)).To(Equal(true))
+		Expect(acquire(locker, ctx, name, 200*time.Millisecond)).To(Equal(false))
+	})
+
+	It("get lock released while waiting", func() {
+		token, ok, err := others.AcquireLock(ctx, name, lockDuration, 0)
+		Expect(err).To(BeNil())
+		Expect(ok).To(Equal(true))
+		time.AfterFunc(200*time.Millisecond, func() {
+			defer GinkgoRecover()
+			Expect(others.ReleaseLock(ctx, name, token)).To(BeNil())
+		})
+		Expect(acquire(locker, ctx, name, 2*time.Second)).To(Equal(true))
+	})
+
+	It("get lock again after release", func() {
+		token, ok, err := locker.AcquireLock(ctx, name, lockDuration, 0)
+		Expect(err).To(BeNil())
+		Expect(ok).To(Equal(true))
+		Expect(locker.ReleaseLock(ctx, name, token)).To(BeNil())
+		Expect(acquire(others, ctx, name, 0)).To(Equal(true))
+	})
+
+	It("not blocked by other names", func() {
+		Expect(acquire(others, ctx, "otherResourceName", 0)).To(Equal(true))
+		Expect(acquire(locker, ctx, name, 0)).To(Equal(true))
+	})
+
+	It("release lock not held", func() {
+		Expect(acquire(others, ctx, name, 0)).To(Equal(true))
+		Expect(locker.ReleaseLock(ctx, name, "unknown")).To(BeNil())
+		Expect(acquire(locker, ctx, name, 0)).To(Equal(false))
+	})
+
+	It("ignore release with another name", func() {
+		token, ok, err := locker.AcquireLock(ctx, name, lockDuration, 0)
+		Expect(err).To(BeNil())
+		Expect(ok).To(Equal(true))
+		Expect(locker.ReleaseLock(ctx, "otherResourceName", token)).To(BeNil())
+		Expect(acquire(others, ctx, name, 0)).To(Equal(false))
+
+		// 用正確的name還是可以放掉
+		Expect(locker.ReleaseLock(ctx, name, token)).To(BeNil())
+		Expect(acquire(others, ctx, name, 0)).To(Equal(true))
+	})
+
+	It("stop waiting when context canceled", func() {
+		Expect(acquire(others, ctx, name, 0)).To(Equal(true))
+		ctx, cancel := context.WithCancel(ctx)
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		Expect(acquire(locker, ctx, name, 10*time.Second)).To(Equal(false))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+}
+
+// expiredHolderBehaviors 會過期的Locker 同一個instance過期後重新拿到lock 舊的holder release不能刪掉新的
+func expiredHolderBehaviors(newLocker func() Locker) {
+	It("expired holder release not delete lock acquired again by the same locker", func() {
+		ctx := context.Background()
+		name := "expiredResourceName"
+		locker := newLocker()
+		expiredToken, ok, err := locker.AcquireLock(ctx, name, 50*time.Millisecond, 0)
+		Expect(err).To(BeNil())
+		Expect(ok).To(Equal(true))
+
+		token, ok, err := locker.AcquireLock(ctx, name, time.Minute, time.Second)
+		Expect(err).To(BeNil())
+		Expect(ok).To(Equal(true))
+		Expect(token).NotTo(Equal(expiredToken))
+		defer locker.ReleaseLock(ctx, name, token)
+
+		Expect(locker.ReleaseLock(ctx, name, expiredToken)).To(BeNil())
+		_, ok, err = newLocker().AcquireLock(ctx, name, time.Minute, 0)
+		Expect(err).To(BeNil())
+		Expect(ok).To(Equal(false))
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/KennyChenFight/golib/pglib"
 	"github.com/KennyChenFight/golib/redislib"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,19 +16,40 @@ func TestLock(t *testing.T) {
 }
 
 var testRedisClient *redislib.GORedisClient
+var testPGClient *pglib.GOPGClient
 
 var _ = BeforeSuite(func() {
 	testRedisClient = setupTestRedis()
+	testPGClient = setupTestPG()
 })
 
 var _ = AfterSuite(func() {
-	testRedisClient.Close()
+	if testRedisClient != nil {
+		testRedisClient.Close()
+	}
+	if testPGClient != nil {
+		testPGClient.Close()
+	}
 })
 
+// requireRedis 沒有設定REDIS_URL時skip 不需要redis的測試照常跑
+func requireRedis() {
+	if testRedisClient == nil {
+		Skip("REDIS_URL is not set")
+	}
+}
+
+// requirePG 沒有設定POSTGRES_URL時skip
+func requirePG() {
+	if testPGClient == nil {
+		Skip("POSTGRES_URL is not set")
+	}
+}
+
 func setupTestRedis() *redislib.GORedisClient {
 	redisURL := os.Getenv("REDIS_URL")
 	if redisURL == "" {
-		panic("should setup redis url")
+		return nil
 	}
 
 	redisClient, err := redislib.NewGORedisClient(redislib.GORedisConfig{URL: redisURL}, nil)
@@ -36,3 +58,17 @@ func setupTestRedis() *redislib.GORedisClient {
 
 	return redisClient
 }
+
+// advisory lock不需要任何table 不用跑migration
+func setupTestPG() *pglib.GOPGClient {
+	pgURL := os.Getenv("POSTGRES_URL")
+	if pgURL == "" {
+		return nil
+	}
+
+	pgClient, err := pglib.NewDefaultGOPGClient(pglib.GOPGConfig{URL: pgURL, PoolSize: 10})
+	Expect(err).To(BeNil())
+	Expect(pgClient).NotTo(BeNil())
+
+	return pgClient
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortening_url"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	KeyPoolSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "key_pool_size",
+		Help:      "Unused keys in the keys table at the last check.",
+	})
+	KeyPoolRefills = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "key_pool_refills_total",
+		Help:      "Times the key pool dropped below the low watermark and was refilled.",
+	})
+	KeyPoolGeneratedKeys = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "key_pool_generated_keys_total",
+		Help:      "Keys inserted into the key pool by refills.",
+	})
+	KeyPoolSkippedKeys = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "key_pool_skipped_keys_total",
+		Help:      "Generated keys skipped by refills because the id is already in keys, urls or quarantine.",
+	})
+	KeyPoolRecycledKeys = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "key_pool_recycled_keys_total",
+		Help:      "Ids of expired or deleted urls put back into the key pool after the cooldown.",
+	})
+	KeyQuarantineSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "key_quarantine_size",
+		Help:      "Ids waiting in quarantine at the last recycle.",
+	})
+	OriginalURLFilterMissingIDs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "original_url_filter_missing_ids",
+		Help:      "Active url ids that were missing from the filter at the last rebuild.",
+	})
+	OriginalURLFilterExtraIDs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "original_url_filter_extra_ids",
+		Help:      "Estimated entries in the filter without an active url at the last rebuild.",
+	})
+	OriginalURLFilterItems = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "original_url_filter_items",
+		Help:      "Items in the cuckoo filter at the last check.",
+	})
+	OriginalURLFilterCapacity = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "original_url_filter_capacity",
+		Help:      "Fingerprint slots across all sub filters of the cuckoo filter at the last check.",
+	})
+	OriginalURLFilterFillRatio = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "original_url_filter_fill_ratio",
+		Help:      "Items divided by capacity of the cuckoo filter at the last check.",
+	})
+	OriginalURLFilterSubFilters = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "original_url_filter_sub_filters",
+		Help:      "Sub filters of the cuckoo filter, more than one means it has expanded.",
+	})
+	OriginalURLFilterErrorRate = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "original_url_filter_error_rate",
+		Help:      "Estimated false positive rate of the cuckoo filter at the last check.",
+	})
+	OriginalURLL1CacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "original_url_l1_cache_hits_total",
+		Help:      "Original url lookups served by the in-process cache.",
+	})
+	OriginalURLL1CacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "original_url_l1_cache_misses_total",
+		Help:      "Original url lookups that fell through the in-process cache to redis.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		KeyPoolSize,
+		KeyPoolRefills,
+		KeyPoolGeneratedKeys,
+		KeyPoolSkippedKeys,
+		KeyPoolRecycledKeys,
+		KeyQuarantineSize,
+		OriginalURLFilterMissingIDs,
+		OriginalURLFilterExtraIDs,
+		OriginalURLFilterItems,
+		OriginalURLFilterCapacity,
+		OriginalURLFilterFillRatio,
+		OriginalURLFilterSubFilters,
+		OriginalURLFilterErrorRate,
+		OriginalURLL1CacheHits,
+		OriginalURLL1CacheMisses,
+	)
+}
+
+// Handler 回傳prometheus scrape用的handler
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
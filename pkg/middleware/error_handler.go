@@ -24,6 +24,10 @@ func (b *BaseMiddleware) GlobalErrorHandle() gin.HandlerFunc {
 				return
 			}
 		}
+		// handler 已經自行寫回response 例如 html page
+		if c.Writer.Written() {
+			return
+		}
 		success := c.MustGet("success").(*business.Success)
 		switch success.HTTPStatusCode {
 		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
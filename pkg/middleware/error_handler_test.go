@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/internal/validationtranslatormock"
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
@@ -83,6 +84,28 @@ var _ = Describe("GlobalErrorHandle", func() {
 			})
 		})
 
+		Context("send success response with redirect code on head method", func() {
+			var actualSuccess *business.Success
+			var actualLocation string
+			BeforeEach(func() {
+				ginMockContext.Request = httptest.NewRequest("HEAD", "http://server.com/random", nil)
+				actualLocation = "http://example.com"
+				actualSuccess = business.NewSuccess(http.StatusTemporaryRedirect, actualLocation)
+				ginMockContext.Set("success", actualSuccess)
+			})
+
+			It("result", func() {
+				// head 沒有body 真正寫回header是在gin handle完整個chain之後
+				ginMockContext.Writer.WriteHeaderNow()
+				result := mockWriter.Result()
+				Expect(result.StatusCode).To(Equal(http.StatusTemporaryRedirect))
+				Expect(result.Header.Get("Location")).To(Equal(actualLocation))
+				body, err := ioutil.ReadAll(result.Body)
+				Expect(err).To(BeNil())
+				Expect(body).To(BeEmpty())
+			})
+		})
+
 		Context("send error response with business error type", func() {
 			var actualBusinessErr *business.Error
 			var request *http.Request
@@ -107,6 +130,24 @@ var _ = Describe("GlobalErrorHandle", func() {
 			})
 		})
 
+		Context("send error response with retry after", func() {
+			var actualBusinessErr *business.Error
+
+			BeforeEach(func() {
+				ginMockContext.Request = &http.Request{Header: make(map[string][]string, 0)}
+				actualBusinessErr = business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil)
+				actualBusinessErr.RetryAfter = 1500 * time.Millisecond
+				ginMockContext.Error(actualBusinessErr)
+				mockTranslator.EXPECT().Translate(ginMockContext.GetHeader("Accept-Language"), actualBusinessErr.Reason).Return(nil, nil)
+			})
+
+			It("result", func() {
+				result := mockWriter.Result()
+				Expect(result.StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(result.Header.Get("Retry-After")).To(Equal("2"))
+			})
+		})
+
 		Context("send error response with unknown error type", func() {
 			var actualErr error
 			var request *http.Request
@@ -130,5 +171,20 @@ var _ = Describe("GlobalErrorHandle", func() {
 				Expect(expectResponseBody.Message).To(Equal(actualBusinessErr.Message))
 			})
 		})
+
+		Context("skip response when handler already wrote it", func() {
+			actualBody := "<html></html>"
+			BeforeEach(func() {
+				ginMockContext.Data(http.StatusOK, "text/html; charset=utf-8", []byte(actualBody))
+			})
+
+			It("result", func() {
+				result := mockWriter.Result()
+				Expect(result.StatusCode).To(Equal(http.StatusOK))
+				body, err := ioutil.ReadAll(result.Body)
+				Expect(err).To(BeNil())
+				Expect(string(body)).To(Equal(actualBody))
+			})
+		})
 	})
 })
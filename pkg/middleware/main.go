@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"math"
+	"strconv"
+
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/Shortening-URL/pkg/validation"
 	"github.com/KennyChenFight/golib/loglib"
@@ -21,6 +24,9 @@ type BaseMiddleware struct {
 }
 
 func (b *BaseMiddleware) sendErrorResponse(c *gin.Context, businessError *business.Error) {
+	if businessError.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(businessError.RetryAfter.Seconds()))))
+	}
 	translated, err := b.validationTranslator.Translate(c.GetHeader("Accept-Language"), businessError.Reason)
 	if translated == nil && err == nil {
 		c.JSON(businessError.HTTPStatusCode, businessError)
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"context"
 	"errors"
 	"net/http"
 	"strconv"
@@ -15,7 +14,7 @@ import (
 func (b *BaseMiddleware) SlideWindowRateLimiter() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		key := c.Request.URL.Path + "-" + c.Request.Method + "-" + c.ClientIP()
-		total, err := b.rateLimiter.Incr(context.Background(), key, time.Now().UnixNano())
+		total, err := b.rateLimiter.Incr(c.Request.Context(), key, time.Now().UnixNano())
 		if err != nil {
 			c.Error(business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", err))
 			c.Abort()
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 每呼叫這麼多次Incr就掃一次 清掉整個window都沒有request的bucket
+const sweepInterval = 1024
+
+func NewMemorySlideWindowRateLimiter(capacity int64, interval time.Duration) *MemorySlideWindowRateLimiter {
+	return &MemorySlideWindowRateLimiter{capacity: capacity, interval: interval.Nanoseconds(), buckets: make(map[string][]int64)}
+}
+
+// MemorySlideWindowRateLimiter 跟ratelimitlib.SlideWindowRateLimiter一樣的sliding window 只在同一個process裡計算
+type MemorySlideWindowRateLimiter struct {
+	capacity int64
+	interval int64
+
+	mu sync.Mutex
+	// 每個bucket在window裡的request時間 由舊到新
+	buckets map[string][]int64
+	calls   int
+}
+
+// Incr 回傳加上這次之後window裡的request數 超過capacity時回傳-1且不計入
+func (m *MemorySlideWindowRateLimiter) Incr(ctx context.Context, bucketName string, lastTimestamp int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if m.calls%sweepInterval == 0 {
+		m.sweep(lastTimestamp)
+	}
+
+	timestamps := prune(m.buckets[bucketName], lastTimestamp-m.interval)
+	if int64(len(timestamps)) >= m.capacity {
+		m.buckets[bucketName] = timestamps
+		return -1, nil
+	}
+	timestamps = append(timestamps, lastTimestamp)
+	m.buckets[bucketName] = timestamps
+	return int64(len(timestamps)), nil
+}
+
+func (m *MemorySlideWindowRateLimiter) sweep(now int64) {
+	for name, timestamps := range m.buckets {
+		if len(prune(timestamps, now-m.interval)) == 0 {
+			delete(m.buckets, name)
+		}
+	}
+}
+
+// prune 跟ZREMRANGEBYSCORE 0 min一樣 去掉小於等於min的
+func prune(timestamps []int64, min int64) []int64 {
+	i := 0
+	for i < len(timestamps) && timestamps[i] <= min {
+		i++
+	}
+	return timestamps[i:]
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemorySlideWindowRateLimiter", func() {
+	ctx := context.Background()
+	var limiter *MemorySlideWindowRateLimiter
+	var now int64
+
+	BeforeEach(func() {
+		limiter = NewMemorySlideWindowRateLimiter(2, time.Minute)
+		now = time.Now().UnixNano()
+	})
+
+	It("count until reach capacity", func() {
+		Expect(limiter.Incr(ctx, "a", now)).To(Equal(int64(1)))
+		Expect(limiter.Incr(ctx, "a", now+1)).To(Equal(int64(2)))
+		Expect(limiter.Incr(ctx, "a", now+2)).To(Equal(int64(-1)))
+		Expect(limiter.Incr(ctx, "b", now+3)).To(Equal(int64(1)))
+	})
+
+	It("allow again after window slides", func() {
+		Expect(limiter.Incr(ctx, "a", now)).To(Equal(int64(1)))
+		Expect(limiter.Incr(ctx, "a", now+int64(30*time.Second))).To(Equal(int64(2)))
+		Expect(limiter.Incr(ctx, "a", now+int64(time.Minute))).To(Equal(int64(2)))
+		Expect(limiter.Incr(ctx, "a", now+int64(time.Minute)+1)).To(Equal(int64(-1)))
+	})
+
+	It("drop idle buckets", func() {
+		Expect(limiter.Incr(ctx, "idle", now)).To(Equal(int64(1)))
+		for i := 1; i < sweepInterval; i++ {
+			limiter.Incr(ctx, "a", now+int64(time.Minute)+int64(i))
+		}
+		Expect(limiter.buckets).NotTo(HaveKey("idle"))
+	})
+})
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/golib/loglib"
+)
+
+// 同一個id同時miss的request數
+const benchConcurrentMisses = 50
+
+// 模擬database的latency
+const benchGetURLLatency = time.Millisecond
+
+// benchCacheDAO 只有originalURL的cache 一開始是空的 filter永遠回傳存在
+type benchCacheDAO struct {
+	dao.CacheDAO
+	mu           sync.Mutex
+	originalURLs map[string]string
+}
+
+func (b *benchCacheDAO) ExistOriginalURLIDInFilters(ctx context.Context, originalURLID string) (bool, *business.Error) {
+	return true, nil
+}
+
+func (b *benchCacheDAO) GetOriginalURL(ctx context.Context, name string) (string, *business.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	originalURL, ok := b.originalURLs[name]
+	if !ok {
+		return "", business.NewError(business.NotFound, http.StatusNotFound, "record not found", dao.RedisErrKeyNotExist)
+	}
+	return originalURL, nil
+}
+
+func (b *benchCacheDAO) SetOriginalURL(ctx context.Context, name string, originalURL string) *business.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.originalURLs[name] = originalURL
+	return nil
+}
+
+type benchUrlDAO struct {
+	dao.UrlDAO
+	gets int64
+}
+
+func (b *benchUrlDAO) Get(ctx context.Context, id string) (*dao.URL, *business.Error) {
+	atomic.AddInt64(&b.gets, 1)
+	time.Sleep(benchGetURLLatency)
+	return &dao.URL{ID: id, Original: "http://example.com"}, nil
+}
+
+// benchLocker 跟redis lock一樣同時只有一個人拿得到 其他人排隊等
+type benchLocker struct {
+	mu       sync.Mutex
+	acquires int64
+}
+
+func (b *benchLocker) AcquireLock(ctx context.Context, name string, lockDuration, waitTime time.Duration) (string, bool, *business.Error) {
+	atomic.AddInt64(&b.acquires, 1)
+	b.mu.Lock()
+	return name, true, nil
+}
+
+func (b *benchLocker) ReleaseLock(ctx context.Context, name, token string) *business.Error {
+	b.mu.Unlock()
+	return nil
+}
+
+// BenchmarkGetOriginalURLWithoutCoalescing 原本每個miss的request都去搶distributed lock
+func BenchmarkGetOriginalURLWithoutCoalescing(b *testing.B) {
+	benchmarkConcurrentMisses(b, func(u *URLRepository, id string) {
+		if _, err := u.CacheDAO.GetOriginalURL(context.Background(), id); err != nil {
+			u.loadOriginalURL(context.Background(), id)
+		}
+	})
+}
+
+// BenchmarkGetOriginalURLWithCoalescing 同一個instance裡只有一個request去搶lock
+func BenchmarkGetOriginalURLWithCoalescing(b *testing.B) {
+	benchmarkConcurrentMisses(b, func(u *URLRepository, id string) {
+		u.GetOriginalURL(context.Background(), id)
+	})
+}
+
+// benchmarkConcurrentMisses 每一輪都從空的cache開始 同時送出benchConcurrentMisses個同一個id的request
+func benchmarkConcurrentMisses(b *testing.B, get func(u *URLRepository, id string)) {
+	locker := &benchLocker{}
+	urlDAO := &benchUrlDAO{}
+	cacheDAO := &benchCacheDAO{}
+	u := NewURLRepository(loglib.NewNopLogger(), urlDAO, nil, cacheDAO, nil, locker, dao.KeyStrategyPool, Timeouts{})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cacheDAO.originalURLs = make(map[string]string)
+		var wg sync.WaitGroup
+		for j := 0; j < benchConcurrentMisses; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				get(u, "abcdef")
+			}()
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(locker.acquires)/float64(b.N), "locks/op")
+	b.ReportMetric(float64(urlDAO.gets)/float64(b.N), "dbgets/op")
+}
@@ -5,3 +5,15 @@ import "time"
 const prefixLockURLResource = "LOCK-URL-RESOURCE"
 const lockURLResourceDuration = time.Second * 5
 const waitingLockURLResourceDuration = time.Second * 5
+
+// key pool 用完時 在request上同步補key用的lock 只允許一個request補 其他人等待後直接重試
+const lockKeyPoolFallback = "LOCK-KEY-POOL-FALLBACK"
+const lockKeyPoolFallbackDuration = time.Second * 5
+const waitingLockKeyPoolFallbackDuration = time.Second * 2
+const fallbackKeyNumber = 100
+
+// 補key也失敗時 告訴client多久之後再試 cronjob應該會在這段時間內補上key
+const capacityExhaustedRetryAfter = time.Second * 30
+
+// request的ctx可能已經超時或被取消 release lock要用新的ctx 不然別人要等到lock過期
+const releaseLockTimeout = time.Second
@@ -1,11 +1,14 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
@@ -14,49 +17,105 @@ import (
 )
 
 type Repository interface {
-	CreateShorteningURL(originalURL string) (*dao.URL, *business.Error)
-	GetOriginalURL(id string) (string, *business.Error)
-	DeleteShorteningURL(id string) *business.Error
-	BatchCreateKeys(num int) (int, *business.Error)
+	CreateShorteningURL(ctx context.Context, originalURL string) (*dao.URL, *business.Error)
+	GetOriginalURL(ctx context.Context, id string) (string, *business.Error)
+	DeleteShorteningURL(ctx context.Context, id string) *business.Error
+	BatchCreateKeys(ctx context.Context, num int) (int, *business.Error)
+	GetURLHealth(ctx context.Context, id string) (*dao.HealthCheck, *business.Error)
+	ListBrokenURLs(ctx context.Context, limit int) ([]dao.HealthCheck, *business.Error)
+	ListURLs(ctx context.Context, afterID string, limit int, includeExpired bool) ([]dao.URL, *business.Error)
+	ImportURLs(ctx context.Context, urls []dao.URL) ([]dao.ImportResult, *business.Error)
 }
 
-func NewURLRepository(logger *loglib.Logger, urlDAO dao.UrlDAO, keyDAO dao.KeyDAO, cacheDAO dao.CacheDAO, locker lock.Locker) *URLRepository {
+// Timeouts 每個操作最多花多久 包含等lock 0則只受呼叫端的ctx限制
+type Timeouts struct {
+	// 查詢 例如redirect、health、list
+	Read time.Duration
+	// 寫入 例如建立、刪除、import、產生key
+	Write time.Duration
+}
+
+// NewURLRepository keyStrategy是urlDAO用哪個strategy拿id 只有pool會用到keys table
+func NewURLRepository(logger *loglib.Logger, urlDAO dao.UrlDAO, keyDAO dao.KeyDAO, cacheDAO dao.CacheDAO, healthCheckDAO dao.HealthCheckDAO, locker lock.Locker, keyStrategy string, timeouts Timeouts) *URLRepository {
 	return &URLRepository{
-		logger:   logger,
-		UrlDAO:   urlDAO,
-		KeyDAO:   keyDAO,
-		CacheDAO: cacheDAO,
-		locker:   locker,
+		logger:         logger,
+		keyStrategy:    keyStrategy,
+		timeouts:       timeouts,
+		UrlDAO:         urlDAO,
+		KeyDAO:         keyDAO,
+		CacheDAO:       cacheDAO,
+		HealthCheckDAO: healthCheckDAO,
+		locker:         locker,
 	}
 }
 
 type URLRepository struct {
-	logger   *loglib.Logger
-	UrlDAO   dao.UrlDAO
-	KeyDAO   dao.KeyDAO
-	CacheDAO dao.CacheDAO
-	locker   lock.Locker
+	logger         *loglib.Logger
+	keyStrategy    string
+	timeouts       Timeouts
+	UrlDAO         dao.UrlDAO
+	KeyDAO         dao.KeyDAO
+	CacheDAO       dao.CacheDAO
+	HealthCheckDAO dao.HealthCheckDAO
+	locker         lock.Locker
+	loadGroup      singleflight.Group
 }
 
-func (u *URLRepository) CreateShorteningURL(originalURL string) (*dao.URL, *business.Error) {
-	url, err := u.UrlDAO.Create(originalURL)
+func (u *URLRepository) CreateShorteningURL(ctx context.Context, originalURL string) (*dao.URL, *business.Error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Write)
+	defer cancel()
+	url, err := u.UrlDAO.Create(ctx, originalURL)
+	// sequence以及hash不會讀keys table 補key也沒用
+	if err != nil && err.BusinessCode == business.CapacityExhausted && u.keyStrategy == dao.KeyStrategyPool {
+		url, err = u.createWithKeyFallback(ctx, originalURL)
+	}
 	if err != nil {
+		if err.BusinessCode == business.CapacityExhausted {
+			err.RetryAfter = capacityExhaustedRetryAfter
+		}
 		return nil, err
 	}
-	err = u.CacheDAO.SetOriginalURL(url.ID, url.Original)
+	// 回收的id可能還留著不存在的記號 下面寫入cache失敗的話會一直404到記號過期
+	err = u.CacheDAO.DeleteOriginalURLNotFound(ctx, []string{url.ID})
+	if err != nil {
+		u.logger.Error("fail to delete originalURL not found in cache", zap.Error(err))
+	}
+	err = u.CacheDAO.SetOriginalURL(ctx, url.ID, url.Original)
 	if err != nil {
 		u.logger.Error("fail to set originalURL in cache", zap.Error(err))
 	}
-	err = u.CacheDAO.AddOriginalURLIDInFilters(url.ID)
+	err = u.CacheDAO.AddOriginalURLIDInFilters(ctx, url.ID)
 	if err != nil {
 		u.logger.Error("fail to set originalURL in filter", zap.Error(err))
 	}
 	return url, nil
 }
 
-func (u *URLRepository) GetOriginalURL(id string) (string, *business.Error) {
+// createWithKeyFallback keys table沒有key時 拿到lock的request同步產生一批key 再重試一次
+func (u *URLRepository) createWithKeyFallback(ctx context.Context, originalURL string) (*dao.URL, *business.Error) {
+	token, ok, err := u.locker.AcquireLock(ctx, lockKeyPoolFallback, lockKeyPoolFallbackDuration, waitingLockKeyPoolFallbackDuration)
+	if err != nil {
+		return nil, err
+	}
+	// 沒拿到lock代表有人正在補key 等完之後一樣重試
+	if ok {
+		defer u.releaseLock(lockKeyPoolFallback, token)
+		inserted, err := u.KeyDAO.BatchCreate(ctx, fallbackKeyNumber)
+		if err != nil {
+			u.logger.Error("fail to generate fallback keys", zap.Error(err))
+		} else {
+			u.logger.Warn("key pool exhausted, generated fallback keys", zap.Int("inserted", inserted))
+		}
+	}
+
+	return u.UrlDAO.Create(ctx, originalURL)
+}
+
+func (u *URLRepository) GetOriginalURL(ctx context.Context, id string) (string, *business.Error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Read)
+	defer cancel()
 	// 避免太多random不存在的key的訪問 可以利用這個先擋著
-	exist, err := u.CacheDAO.ExistOriginalURLIDInFilters(id)
+	exist, err := u.CacheDAO.ExistOriginalURLIDInFilters(ctx, id)
 	if err != nil {
 		u.logger.Error("fail to check originalURLID in filters", zap.Error(err))
 	} else {
@@ -66,59 +125,163 @@ func (u *URLRepository) GetOriginalURL(id string) (string, *business.Error) {
 	}
 
 	// 先從first cache 拿
-	originalURL, err := u.CacheDAO.GetOriginalURL(id)
+	originalURL, err := u.CacheDAO.GetOriginalURL(ctx, id)
 	if err != nil {
 		if err.Reason == dao.RedisErrKeyNotExist {
-			// 如果first cache miss 則 需要獲取lock 避免當cache失效時 太多request過來要更新cache 使用 lock 只能有一個進來訪問database並更新cache
-			// 這樣以來其他人就可以透過second cache hit來拿到資料 而不用真的訪問到database
-			ok, err := u.locker.AcquireLock(fmt.Sprintf("%s-%s", prefixLockURLResource, id), lockURLResourceDuration, waitingLockURLResourceDuration)
-			if err != nil {
-				return "", err
-			}
-			if !ok {
-				return "", business.NewError(business.AcquireLockURLResourceError, http.StatusServiceUnavailable, "server unavailable", errors.New("server unavailable"))
-			} else {
-				defer u.locker.ReleaseLock(fmt.Sprintf("%s-%s", prefixLockURLResource, id))
+			// 同一個instance裡同一個id只讓一個goroutine去搶lock跟查database 其他的直接共用結果
+			ch := u.loadGroup.DoChan(id, func() (interface{}, error) {
+				// 結果是大家共用的 不能因為第一個request斷線就取消 只受read timeout限制
+				loadCtx, cancel := withTimeout(context.Background(), u.timeouts.Read)
+				defer cancel()
+				originalURL, err := u.loadOriginalURL(loadCtx, id)
+				return loadOriginalURLResult{originalURL, err}, nil
+			})
+			select {
+			case <-ctx.Done():
+				return "", business.NewContextError(ctx.Err())
+			case v := <-ch:
+				result := v.Val.(loadOriginalURLResult)
+				return result.originalURL, result.err
 			}
-			// second cache check
-			originalURL, err = u.CacheDAO.GetOriginalURL(id)
+		}
+		return "", err
+	}
+	return originalURL, nil
+}
+
+type loadOriginalURLResult struct {
+	originalURL string
+	err         *business.Error
+}
+
+// loadOriginalURL first cache miss之後的流程
+func (u *URLRepository) loadOriginalURL(ctx context.Context, id string) (string, *business.Error) {
+	// 如果first cache miss 則 需要獲取lock 避免當cache失效時 太多request過來要更新cache 使用 lock 只能有一個進來訪問database並更新cache
+	// 這樣以來其他人就可以透過second cache hit來拿到資料 而不用真的訪問到database
+	lockName := fmt.Sprintf("%s-%s", prefixLockURLResource, id)
+	token, ok, err := u.locker.AcquireLock(ctx, lockName, lockURLResourceDuration, waitingLockURLResourceDuration)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if ctx.Err() != nil {
+			return "", business.NewContextError(ctx.Err())
+		}
+		return "", business.NewError(business.AcquireLockURLResourceError, http.StatusServiceUnavailable, "server unavailable", errors.New("server unavailable"))
+	} else {
+		defer u.releaseLock(lockName, token)
+	}
+	// second cache check
+	originalURL, err := u.CacheDAO.GetOriginalURL(ctx, id)
+	if err != nil {
+		if err.Reason == dao.RedisErrKeyNotExist {
+			url, err := u.UrlDAO.Get(ctx, id)
 			if err != nil {
-				if err.Reason == dao.RedisErrKeyNotExist {
-					url, err := u.UrlDAO.Get(id)
-					if err != nil {
-						return "", err
-					}
-					err = u.CacheDAO.SetOriginalURL(url.ID, url.Original)
-					if err != nil {
-						u.logger.Error("fail to set originalURL cache", zap.Error(err))
+				// 不存在或過期的id記在cache 一直掃不存在id的request就不會每次都打到database
+				if err.BusinessCode == business.NotFound {
+					if setErr := u.CacheDAO.SetOriginalURLNotFound(ctx, id); setErr != nil {
+						u.logger.Error("fail to set originalURL not found in cache", zap.Error(setErr))
 					}
-					return url.Original, nil
 				}
 				return "", err
 			}
-			return originalURL, nil
+			err = u.CacheDAO.SetOriginalURL(ctx, url.ID, url.Original)
+			if err != nil {
+				u.logger.Error("fail to set originalURL cache", zap.Error(err))
+			}
+			return url.Original, nil
 		}
 		return "", err
 	}
 	return originalURL, nil
 }
 
-func (u *URLRepository) DeleteShorteningURL(id string) *business.Error {
-	err := u.CacheDAO.DeleteOriginalURL(id)
+func (u *URLRepository) DeleteShorteningURL(ctx context.Context, id string) *business.Error {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Write)
+	defer cancel()
+	err := u.CacheDAO.DeleteOriginalURL(ctx, id)
 	if err != nil {
 		u.logger.Error("fail to delete originalURL in cache", zap.Error(err))
 	}
-	err = u.UrlDAO.Delete(id)
+	err = u.UrlDAO.Delete(ctx, id)
 	if err != nil {
 		return err
 	}
-	ok, err := u.CacheDAO.DeleteOriginalURLIDInFilters(id)
+	ok, err := u.CacheDAO.DeleteOriginalURLIDInFilters(ctx, id)
 	if err != nil || !ok {
 		u.logger.Error("fail to delete originalURL in filter", zap.Bool("ok", ok), zap.Error(err))
 	}
 	return nil
 }
 
-func (u *URLRepository) BatchCreateKeys(num int) (int, *business.Error) {
-	return u.KeyDAO.BatchCreate(num)
+func (u *URLRepository) BatchCreateKeys(ctx context.Context, num int) (int, *business.Error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Write)
+	defer cancel()
+	return u.KeyDAO.BatchCreate(ctx, num)
+}
+
+func (u *URLRepository) GetURLHealth(ctx context.Context, id string) (*dao.HealthCheck, *business.Error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Read)
+	defer cancel()
+	return u.HealthCheckDAO.Get(ctx, id)
+}
+
+func (u *URLRepository) ListBrokenURLs(ctx context.Context, limit int) ([]dao.HealthCheck, *business.Error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Read)
+	defer cancel()
+	return u.HealthCheckDAO.ListBroken(ctx, limit)
+}
+
+func (u *URLRepository) ListURLs(ctx context.Context, afterID string, limit int, includeExpired bool) ([]dao.URL, *business.Error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Read)
+	defer cancel()
+	return u.UrlDAO.List(ctx, afterID, limit, includeExpired)
+}
+
+func (u *URLRepository) ImportURLs(ctx context.Context, urls []dao.URL) ([]dao.ImportResult, *business.Error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.Write)
+	defer cancel()
+	results, err := u.UrlDAO.Import(ctx, urls)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	err = u.CacheDAO.DeleteOriginalURLNotFound(ctx, ids)
+	if err != nil {
+		u.logger.Error("fail to delete originalURL not found in cache", zap.Error(err))
+	}
+	now := time.Now()
+	for _, result := range results {
+		// 已經過期的只寫進database 不需要放進cache跟filter
+		if !result.URL.ExpiredAt.After(now) {
+			continue
+		}
+		err = u.CacheDAO.SetOriginalURL(ctx, result.ID, result.URL.Original)
+		if err != nil {
+			u.logger.Error("fail to set originalURL in cache", zap.String("id", result.ID), zap.Error(err))
+		}
+		err = u.CacheDAO.AddOriginalURLIDInFilters(ctx, result.ID)
+		if err != nil {
+			u.logger.Error("fail to set originalURL in filter", zap.String("id", result.ID), zap.Error(err))
+		}
+	}
+	return results, nil
+}
+
+func (u *URLRepository) releaseLock(name, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseLockTimeout)
+	defer cancel()
+	if err := u.locker.ReleaseLock(ctx, name, token); err != nil {
+		u.logger.Error("fail to release lock", zap.String("name", name), zap.Error(err))
+	}
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
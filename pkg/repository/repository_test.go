@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/KennyChenFight/Shortening-URL/internal/daomock"
 	"github.com/KennyChenFight/Shortening-URL/internal/lockmock"
@@ -16,10 +20,12 @@ import (
 )
 
 var _ = Describe("URLRepository", func() {
+	ctx := context.Background()
 	var mockCtrl *gomock.Controller
 	var mockUrlDAO *daomock.MockUrlDAO
 	var mockCacheDAO *daomock.MockCacheDAO
 	var mockKeyDAO *daomock.MockKeyDAO
+	var mockHealthCheckDAO *daomock.MockHealthCheckDAO
 	var mockLocker *lockmock.MockLocker
 	var logger *loglib.Logger
 	var urlRepository *URLRepository
@@ -30,8 +36,9 @@ var _ = Describe("URLRepository", func() {
 		mockUrlDAO = daomock.NewMockUrlDAO(mockCtrl)
 		mockKeyDAO = daomock.NewMockKeyDAO(mockCtrl)
 		mockCacheDAO = daomock.NewMockCacheDAO(mockCtrl)
+		mockHealthCheckDAO = daomock.NewMockHealthCheckDAO(mockCtrl)
 		mockLocker = lockmock.NewMockLocker(mockCtrl)
-		urlRepository = NewURLRepository(logger, mockUrlDAO, mockKeyDAO, mockCacheDAO, mockLocker)
+		urlRepository = NewURLRepository(logger, mockUrlDAO, mockKeyDAO, mockCacheDAO, mockHealthCheckDAO, mockLocker, dao.KeyStrategyPool, Timeouts{})
 	})
 
 	AfterEach(func() {
@@ -47,16 +54,17 @@ var _ = Describe("URLRepository", func() {
 		actualOriginalURL := "http://example.com"
 
 		JustBeforeEach(func() {
-			expectURL, createErr = urlRepository.CreateShorteningURL(actualOriginalURL)
+			expectURL, createErr = urlRepository.CreateShorteningURL(ctx, actualOriginalURL)
 		})
 
 		Context("success", func() {
 			var actualURL *dao.URL
 			BeforeEach(func() {
 				actualURL = &dao.URL{ID: "random", Original: actualOriginalURL}
-				mockUrlDAO.EXPECT().Create(actualOriginalURL).Return(actualURL, nil)
-				mockCacheDAO.EXPECT().SetOriginalURL(actualURL.ID, actualURL.Original).Return(nil)
-				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(actualURL.ID).Return(nil)
+				mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(actualURL, nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLNotFound(gomock.Any(), []string{actualURL.ID}).Return(nil)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualURL.ID, actualURL.Original).Return(nil)
+				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(gomock.Any(), actualURL.ID).Return(nil)
 			})
 
 			It("result", func() {
@@ -69,7 +77,7 @@ var _ = Describe("URLRepository", func() {
 			var createURLErr *business.Error
 			BeforeEach(func() {
 				createURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
-				mockUrlDAO.EXPECT().Create(actualOriginalURL).Return(nil, createURLErr)
+				mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(nil, createURLErr)
 			})
 
 			It("result", func() {
@@ -78,15 +86,112 @@ var _ = Describe("URLRepository", func() {
 			})
 		})
 
+		Context("key pool exhausted and fallback generates keys", func() {
+			var actualURL *dao.URL
+			BeforeEach(func() {
+				actualURL = &dao.URL{ID: "random", Original: actualOriginalURL}
+				exhaustedErr := business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil)
+				gomock.InOrder(
+					mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(nil, exhaustedErr),
+					mockLocker.EXPECT().AcquireLock(gomock.Any(), lockKeyPoolFallback, lockKeyPoolFallbackDuration, waitingLockKeyPoolFallbackDuration).Return("token", true, nil),
+					mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), fallbackKeyNumber).Return(fallbackKeyNumber, nil),
+					mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(actualURL, nil),
+					mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockKeyPoolFallback, "token").Return(nil),
+				)
+				mockCacheDAO.EXPECT().DeleteOriginalURLNotFound(gomock.Any(), []string{actualURL.ID}).Return(nil)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualURL.ID, actualURL.Original).Return(nil)
+				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(gomock.Any(), actualURL.ID).Return(nil)
+			})
+
+			It("result", func() {
+				Expect(createErr).To(BeNil())
+				Expect(expectURL).To(Equal(actualURL))
+			})
+		})
+
+		Context("key pool exhausted and another request is generating keys", func() {
+			var actualURL *dao.URL
+			BeforeEach(func() {
+				actualURL = &dao.URL{ID: "random", Original: actualOriginalURL}
+				exhaustedErr := business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil)
+				gomock.InOrder(
+					mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(nil, exhaustedErr),
+					mockLocker.EXPECT().AcquireLock(gomock.Any(), lockKeyPoolFallback, lockKeyPoolFallbackDuration, waitingLockKeyPoolFallbackDuration).Return("", false, nil),
+					mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(actualURL, nil),
+				)
+				mockCacheDAO.EXPECT().DeleteOriginalURLNotFound(gomock.Any(), []string{actualURL.ID}).Return(nil)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualURL.ID, actualURL.Original).Return(nil)
+				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(gomock.Any(), actualURL.ID).Return(nil)
+			})
+
+			It("result", func() {
+				Expect(createErr).To(BeNil())
+				Expect(expectURL).To(Equal(actualURL))
+			})
+		})
+
+		Context("key pool still exhausted after fallback", func() {
+			BeforeEach(func() {
+				generateErr := business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", nil)
+				gomock.InOrder(
+					mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(nil, business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil)),
+					mockLocker.EXPECT().AcquireLock(gomock.Any(), lockKeyPoolFallback, lockKeyPoolFallbackDuration, waitingLockKeyPoolFallbackDuration).Return("token", true, nil),
+					mockKeyDAO.EXPECT().BatchCreate(gomock.Any(), fallbackKeyNumber).Return(0, generateErr),
+					mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(nil, business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil)),
+					mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockKeyPoolFallback, "token").Return(nil),
+				)
+			})
+
+			It("result", func() {
+				Expect(expectURL).To(BeNil())
+				Expect(createErr.BusinessCode).To(Equal(business.CapacityExhausted))
+				Expect(createErr.HTTPStatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(createErr.RetryAfter).To(Equal(capacityExhaustedRetryAfter))
+			})
+		})
+
+		Context("key pool exhausted and fail to acquire fallback lock", func() {
+			var lockErr *business.Error
+			BeforeEach(func() {
+				lockErr = business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", nil)
+				mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(nil, business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil))
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockKeyPoolFallback, lockKeyPoolFallbackDuration, waitingLockKeyPoolFallbackDuration).Return("", false, lockErr)
+			})
+
+			It("result", func() {
+				Expect(createErr).To(Equal(lockErr))
+				Expect(expectURL).To(BeNil())
+			})
+		})
+
+		Context("ids exhausted with sequence strategy", func() {
+			BeforeEach(func() {
+				urlRepository = NewURLRepository(logger, mockUrlDAO, mockKeyDAO, mockCacheDAO, mockHealthCheckDAO, mockLocker, dao.KeyStrategySequence, Timeouts{})
+				// 不會去拿fallback的lock 也不會產生key
+				mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(nil, business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil))
+			})
+
+			It("result", func() {
+				Expect(expectURL).To(BeNil())
+				Expect(createErr.BusinessCode).To(Equal(business.CapacityExhausted))
+				Expect(createErr.HTTPStatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(createErr.RetryAfter).To(Equal(capacityExhaustedRetryAfter))
+			})
+		})
+
 		Context("setOriginalURL cache fail", func() {
 			var actualURL *dao.URL
 			var setOriginalURLErr *business.Error
 			BeforeEach(func() {
 				actualURL = &dao.URL{ID: "random", Original: actualOriginalURL}
 				setOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
-				mockUrlDAO.EXPECT().Create(actualOriginalURL).Return(actualURL, nil)
-				mockCacheDAO.EXPECT().SetOriginalURL(actualURL.ID, actualURL.Original).Return(setOriginalURLErr)
-				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(actualURL.ID).Return(nil)
+				mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(actualURL, nil)
+				// 先刪掉不存在的記號 寫入失敗時下一次讀取才會回database拿
+				gomock.InOrder(
+					mockCacheDAO.EXPECT().DeleteOriginalURLNotFound(gomock.Any(), []string{actualURL.ID}).Return(nil),
+					mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualURL.ID, actualURL.Original).Return(setOriginalURLErr),
+				)
+				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(gomock.Any(), actualURL.ID).Return(nil)
 			})
 
 			It("result", func() {
@@ -101,9 +206,10 @@ var _ = Describe("URLRepository", func() {
 			BeforeEach(func() {
 				actualURL = &dao.URL{ID: "random", Original: actualOriginalURL}
 				addOriginalURLIDInFiltersErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
-				mockUrlDAO.EXPECT().Create(actualOriginalURL).Return(actualURL, nil)
-				mockCacheDAO.EXPECT().SetOriginalURL(actualURL.ID, actualURL.Original).Return(nil)
-				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(actualURL.ID).Return(addOriginalURLIDInFiltersErr)
+				mockUrlDAO.EXPECT().Create(gomock.Any(), actualOriginalURL).Return(actualURL, nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLNotFound(gomock.Any(), []string{actualURL.ID}).Return(nil)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualURL.ID, actualURL.Original).Return(nil)
+				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(gomock.Any(), actualURL.ID).Return(addOriginalURLIDInFiltersErr)
 			})
 
 			It("result", func() {
@@ -123,13 +229,13 @@ var _ = Describe("URLRepository", func() {
 		actualOriginalURL := "http://example.com"
 
 		JustBeforeEach(func() {
-			expectOriginalURL, getErr = urlRepository.GetOriginalURL(actualID)
+			expectOriginalURL, getErr = urlRepository.GetOriginalURL(ctx, actualID)
 		})
 
 		Context("success with cache hit", func() {
 			BeforeEach(func() {
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return(actualOriginalURL, nil)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return(actualOriginalURL, nil)
 			})
 
 			It("result", func() {
@@ -145,12 +251,12 @@ var _ = Describe("URLRepository", func() {
 			BeforeEach(func() {
 				existOriginalURLIDInFiltersErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
 				getOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(false, existOriginalURLIDInFiltersErr)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", getOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(false, existOriginalURLIDInFiltersErr)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr)
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return(actualOriginalURL, nil)
-				mockLocker.EXPECT().ReleaseLock(lockName).Return(nil)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return(actualOriginalURL, nil)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
 			})
 
 			It("result", func() {
@@ -164,12 +270,12 @@ var _ = Describe("URLRepository", func() {
 			var lockName string
 			BeforeEach(func() {
 				getOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", getOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr)
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return(actualOriginalURL, nil)
-				mockLocker.EXPECT().ReleaseLock(lockName).Return(nil)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return(actualOriginalURL, nil)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
 			})
 
 			It("result", func() {
@@ -184,15 +290,15 @@ var _ = Describe("URLRepository", func() {
 			var actualURL *dao.URL
 			BeforeEach(func() {
 				getOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", getOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr)
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", getOriginalURLErr)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr)
 				actualURL = &dao.URL{ID: actualID, Original: actualOriginalURL}
-				mockUrlDAO.EXPECT().Get(actualID).Return(actualURL, nil)
-				mockCacheDAO.EXPECT().SetOriginalURL(actualURL.ID, actualURL.Original).Return(nil)
-				mockLocker.EXPECT().ReleaseLock(lockName).Return(nil)
+				mockUrlDAO.EXPECT().Get(gomock.Any(), actualID).Return(actualURL, nil)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualURL.ID, actualURL.Original).Return(nil)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
 			})
 
 			It("result", func() {
@@ -207,7 +313,7 @@ var _ = Describe("URLRepository", func() {
 			BeforeEach(func() {
 				exist = false
 				notExistErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", errors.New("can not found in filters"))
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(exist, nil)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(exist, nil)
 			})
 
 			It("result", func() {
@@ -220,8 +326,8 @@ var _ = Describe("URLRepository", func() {
 			var getOriginalURLErr *business.Error
 			BeforeEach(func() {
 				getOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New("unknown"))
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", getOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr)
 			})
 
 			It("result", func() {
@@ -236,11 +342,11 @@ var _ = Describe("URLRepository", func() {
 			var lockName string
 			BeforeEach(func() {
 				getOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", getOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr)
 				acquireLockErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New("unknown"))
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(false, acquireLockErr)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("", false, acquireLockErr)
 			})
 
 			It("result", func() {
@@ -254,10 +360,10 @@ var _ = Describe("URLRepository", func() {
 			var lockName string
 			BeforeEach(func() {
 				getOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", getOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr)
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(false, nil)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("", false, nil)
 			})
 
 			It("result", func() {
@@ -272,13 +378,13 @@ var _ = Describe("URLRepository", func() {
 			var lockName string
 			BeforeEach(func() {
 				firstGetOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", firstGetOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", firstGetOriginalURLErr)
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(true, nil)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
 				secondGetOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New("unknown"))
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", secondGetOriginalURLErr)
-				mockLocker.EXPECT().ReleaseLock(lockName).Return(nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", secondGetOriginalURLErr)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
 			})
 
 			It("result", func() {
@@ -294,15 +400,72 @@ var _ = Describe("URLRepository", func() {
 			var lockName string
 			BeforeEach(func() {
 				firstGetOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", firstGetOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", firstGetOriginalURLErr)
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(true, nil)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
 				secondGetOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", secondGetOriginalURLErr)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", secondGetOriginalURLErr)
 				getURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New("unknown"))
-				mockUrlDAO.EXPECT().Get(actualID).Return(nil, getURLErr)
-				mockLocker.EXPECT().ReleaseLock(lockName).Return(nil)
+				mockUrlDAO.EXPECT().Get(gomock.Any(), actualID).Return(nil, getURLErr)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
+			})
+
+			It("result", func() {
+				Expect(getErr).To(Equal(getURLErr))
+				Expect(expectOriginalURL).To(Equal(""))
+			})
+		})
+
+		Context("fail with cached not found without touching lock and database", func() {
+			var cachedNotFoundErr *business.Error
+			BeforeEach(func() {
+				cachedNotFoundErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", dao.RedisErrCachedNotFound)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", cachedNotFoundErr)
+			})
+
+			It("result", func() {
+				Expect(getErr).To(Equal(cachedNotFoundErr))
+				Expect(expectOriginalURL).To(Equal(""))
+			})
+		})
+
+		Context("fail with first cache miss and second cache hit cached not found", func() {
+			var firstGetOriginalURLErr *business.Error
+			var cachedNotFoundErr *business.Error
+			var lockName string
+			BeforeEach(func() {
+				firstGetOriginalURLErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", dao.RedisErrKeyNotExist)
+				cachedNotFoundErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", dao.RedisErrCachedNotFound)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", firstGetOriginalURLErr)
+				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", cachedNotFoundErr)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
+			})
+
+			It("result", func() {
+				Expect(getErr).To(Equal(cachedNotFoundErr))
+				Expect(expectOriginalURL).To(Equal(""))
+			})
+		})
+
+		Context("fail with not found in database and cache the not found", func() {
+			var getOriginalURLErr *business.Error
+			var getURLErr *business.Error
+			var lockName string
+			BeforeEach(func() {
+				getOriginalURLErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", dao.RedisErrKeyNotExist)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getOriginalURLErr).Times(2)
+				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
+				getURLErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", errors.New("pg: no rows in result set"))
+				mockUrlDAO.EXPECT().Get(gomock.Any(), actualID).Return(nil, getURLErr)
+				mockCacheDAO.EXPECT().SetOriginalURLNotFound(gomock.Any(), actualID).Return(nil)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
 			})
 
 			It("result", func() {
@@ -319,17 +482,17 @@ var _ = Describe("URLRepository", func() {
 			var lockName string
 			BeforeEach(func() {
 				firstGetOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(actualID).Return(true, nil)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", firstGetOriginalURLErr)
+				mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", firstGetOriginalURLErr)
 				lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
-				mockLocker.EXPECT().AcquireLock(lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return(true, nil)
+				mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).Return("token", true, nil)
 				secondGetOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", dao.RedisErrKeyNotExist)
-				mockCacheDAO.EXPECT().GetOriginalURL(actualID).Return("", secondGetOriginalURLErr)
+				mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", secondGetOriginalURLErr)
 				actualURL = &dao.URL{ID: actualID, Original: actualOriginalURL}
-				mockUrlDAO.EXPECT().Get(actualID).Return(actualURL, nil)
+				mockUrlDAO.EXPECT().Get(gomock.Any(), actualID).Return(actualURL, nil)
 				setOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New("unknown"))
-				mockCacheDAO.EXPECT().SetOriginalURL(actualURL.ID, actualURL.Original).Return(setOriginalURLErr)
-				mockLocker.EXPECT().ReleaseLock(lockName).Return(nil)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualURL.ID, actualURL.Original).Return(setOriginalURLErr)
+				mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil)
 			})
 
 			It("result", func() {
@@ -339,6 +502,97 @@ var _ = Describe("URLRepository", func() {
 		})
 	})
 
+	var _ = Describe("GetOriginalURL concurrently", func() {
+		actualID := "abcdef"
+		actualOriginalURL := "http://example.com"
+		concurrency := 10
+
+		It("only one goroutine acquires lock and hits the database", func() {
+			missErr := business.NewError(business.NotFound, http.StatusNotFound, "record not found", dao.RedisErrKeyNotExist)
+			var firstMisses int32
+			mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil).Times(concurrency)
+			mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).DoAndReturn(func(context.Context, string) (string, *business.Error) {
+				atomic.AddInt32(&firstMisses, 1)
+				return "", missErr
+			}).Times(concurrency + 1)
+			lockName := fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
+			release := make(chan struct{})
+			mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).DoAndReturn(func(context.Context, string, time.Duration, time.Duration) (string, bool, *business.Error) {
+				<-release
+				return "token", true, nil
+			}).Times(1)
+			mockUrlDAO.EXPECT().Get(gomock.Any(), actualID).Return(&dao.URL{ID: actualID, Original: actualOriginalURL}, nil).Times(1)
+			mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), actualID, actualOriginalURL).Return(nil).Times(1)
+			mockLocker.EXPECT().ReleaseLock(gomock.Any(), lockName, "token").Return(nil).Times(1)
+
+			var wg sync.WaitGroup
+			results := make(chan string, concurrency)
+			for i := 0; i < concurrency; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					originalURL, err := urlRepository.GetOriginalURL(ctx, actualID)
+					Expect(err).To(BeNil())
+					results <- originalURL
+				}()
+			}
+			// 等所有goroutine都miss之後才放行lock 讓它們都在等同一個結果
+			Eventually(func() int32 { return atomic.LoadInt32(&firstMisses) }).Should(Equal(int32(concurrency)))
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(results)
+			for originalURL := range results {
+				Expect(originalURL).To(Equal(actualOriginalURL))
+			}
+		})
+	})
+
+	var _ = Describe("GetOriginalURL with context", func() {
+		actualID := "abcdef"
+		var lockName string
+		var missErr *business.Error
+
+		BeforeEach(func() {
+			lockName = fmt.Sprintf("%s-%s", prefixLockURLResource, actualID)
+			missErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", dao.RedisErrKeyNotExist)
+			mockCacheDAO.EXPECT().ExistOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
+			mockCacheDAO.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", missErr)
+		})
+
+		It("return canceled without waiting for the shared load", func() {
+			release := make(chan struct{})
+			done := make(chan struct{})
+			mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).DoAndReturn(func(context.Context, string, time.Duration, time.Duration) (string, bool, *business.Error) {
+				<-release
+				defer close(done)
+				return "", false, nil
+			})
+
+			cancelCtx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(20*time.Millisecond, cancel)
+			originalURL, err := urlRepository.GetOriginalURL(cancelCtx, actualID)
+			Expect(err).To(Equal(business.NewError(business.Canceled, business.StatusClientClosedRequest, "request canceled", context.Canceled)))
+			Expect(originalURL).To(Equal(""))
+
+			close(release)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("return deadline exceeded when lock is not acquired before read timeout", func() {
+			urlRepository = NewURLRepository(logger, mockUrlDAO, mockKeyDAO, mockCacheDAO, mockHealthCheckDAO, mockLocker, dao.KeyStrategyPool, Timeouts{Read: 20 * time.Millisecond})
+			mockLocker.EXPECT().AcquireLock(gomock.Any(), lockName, lockURLResourceDuration, waitingLockURLResourceDuration).DoAndReturn(func(ctx context.Context, _ string, _ time.Duration, _ time.Duration) (string, bool, *business.Error) {
+				<-ctx.Done()
+				return "", false, nil
+			})
+
+			originalURL, err := urlRepository.GetOriginalURL(ctx, actualID)
+			Expect(err).To(Equal(business.NewError(business.DeadlineExceeded, http.StatusGatewayTimeout, "deadline exceeded", context.DeadlineExceeded)))
+			Expect(originalURL).To(Equal(""))
+		})
+	})
+
 	var _ = Describe("DeleteShorteningURL", func() {
 		var (
 			deleteErr *business.Error
@@ -347,14 +601,14 @@ var _ = Describe("URLRepository", func() {
 		actualID := "random"
 
 		JustBeforeEach(func() {
-			deleteErr = urlRepository.DeleteShorteningURL(actualID)
+			deleteErr = urlRepository.DeleteShorteningURL(ctx, actualID)
 		})
 
 		Context("success", func() {
 			BeforeEach(func() {
-				mockCacheDAO.EXPECT().DeleteOriginalURL(actualID).Return(nil)
-				mockUrlDAO.EXPECT().Delete(actualID).Return(nil)
-				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURL(gomock.Any(), actualID).Return(nil)
+				mockUrlDAO.EXPECT().Delete(gomock.Any(), actualID).Return(nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
 			})
 
 			It("result", func() {
@@ -366,9 +620,9 @@ var _ = Describe("URLRepository", func() {
 			var deleteOriginalURLErr *business.Error
 			BeforeEach(func() {
 				deleteOriginalURLErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
-				mockCacheDAO.EXPECT().DeleteOriginalURL(actualID).Return(deleteOriginalURLErr)
-				mockUrlDAO.EXPECT().Delete(actualID).Return(nil)
-				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(actualID).Return(true, nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURL(gomock.Any(), actualID).Return(deleteOriginalURLErr)
+				mockUrlDAO.EXPECT().Delete(gomock.Any(), actualID).Return(nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(gomock.Any(), actualID).Return(true, nil)
 			})
 
 			It("result", func() {
@@ -380,9 +634,9 @@ var _ = Describe("URLRepository", func() {
 			var deleteOriginalURLInFilterErr *business.Error
 			BeforeEach(func() {
 				deleteOriginalURLInFilterErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
-				mockCacheDAO.EXPECT().DeleteOriginalURL(actualID).Return(nil)
-				mockUrlDAO.EXPECT().Delete(actualID).Return(nil)
-				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(actualID).Return(false, deleteOriginalURLInFilterErr)
+				mockCacheDAO.EXPECT().DeleteOriginalURL(gomock.Any(), actualID).Return(nil)
+				mockUrlDAO.EXPECT().Delete(gomock.Any(), actualID).Return(nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(gomock.Any(), actualID).Return(false, deleteOriginalURLInFilterErr)
 			})
 
 			It("result", func() {
@@ -392,9 +646,9 @@ var _ = Describe("URLRepository", func() {
 
 		Context("success with fail to delete originalURLID in filters because not exist", func() {
 			BeforeEach(func() {
-				mockCacheDAO.EXPECT().DeleteOriginalURL(actualID).Return(nil)
-				mockUrlDAO.EXPECT().Delete(actualID).Return(nil)
-				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(actualID).Return(false, nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURL(gomock.Any(), actualID).Return(nil)
+				mockUrlDAO.EXPECT().Delete(gomock.Any(), actualID).Return(nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLIDInFilters(gomock.Any(), actualID).Return(false, nil)
 			})
 
 			It("result", func() {
@@ -406,8 +660,8 @@ var _ = Describe("URLRepository", func() {
 			var deleteInDBErr *business.Error
 			BeforeEach(func() {
 				deleteInDBErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
-				mockCacheDAO.EXPECT().DeleteOriginalURL(actualID).Return(nil)
-				mockUrlDAO.EXPECT().Delete(actualID).Return(deleteInDBErr)
+				mockCacheDAO.EXPECT().DeleteOriginalURL(gomock.Any(), actualID).Return(nil)
+				mockUrlDAO.EXPECT().Delete(gomock.Any(), actualID).Return(deleteInDBErr)
 			})
 
 			It("result", func() {
@@ -416,4 +670,67 @@ var _ = Describe("URLRepository", func() {
 		})
 	})
 
+	var _ = Describe("ImportURLs", func() {
+		var (
+			expectResults []dao.ImportResult
+			importErr     *business.Error
+		)
+
+		now := time.Now()
+		actualURLs := []dao.URL{
+			{ID: "random", Original: "http://example.com/a", ExpiredAt: now.Add(time.Hour)},
+			{ID: "expire", Original: "http://example.com/b", ExpiredAt: now.Add(-time.Hour)},
+		}
+		actualResults := []dao.ImportResult{
+			{OriginalID: "random", ID: "random", URL: actualURLs[0]},
+			{OriginalID: "expire", ID: "expire", URL: actualURLs[1]},
+		}
+
+		JustBeforeEach(func() {
+			expectResults, importErr = urlRepository.ImportURLs(ctx, actualURLs)
+		})
+
+		Context("success and only active urls go to cache and filters", func() {
+			BeforeEach(func() {
+				mockUrlDAO.EXPECT().Import(gomock.Any(), actualURLs).Return(actualResults, nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLNotFound(gomock.Any(), []string{"random", "expire"}).Return(nil)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), "random", "http://example.com/a").Return(nil)
+				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(gomock.Any(), "random").Return(nil)
+			})
+
+			It("result", func() {
+				Expect(importErr).To(BeNil())
+				Expect(expectResults).To(Equal(actualResults))
+			})
+		})
+
+		Context("success with fail to set cache and filters", func() {
+			BeforeEach(func() {
+				cacheErr := business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
+				mockUrlDAO.EXPECT().Import(gomock.Any(), actualURLs).Return(actualResults, nil)
+				mockCacheDAO.EXPECT().DeleteOriginalURLNotFound(gomock.Any(), []string{"random", "expire"}).Return(cacheErr)
+				mockCacheDAO.EXPECT().SetOriginalURL(gomock.Any(), "random", "http://example.com/a").Return(cacheErr)
+				mockCacheDAO.EXPECT().AddOriginalURLIDInFilters(gomock.Any(), "random").Return(cacheErr)
+			})
+
+			It("result", func() {
+				Expect(importErr).To(BeNil())
+				Expect(expectResults).To(Equal(actualResults))
+			})
+		})
+
+		Context("fail with import in database", func() {
+			var importInDBErr *business.Error
+			BeforeEach(func() {
+				importInDBErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
+				mockUrlDAO.EXPECT().Import(gomock.Any(), actualURLs).Return(nil, importInDBErr)
+			})
+
+			It("result", func() {
+				Expect(importErr).To(Equal(importInDBErr))
+				Expect(expectResults).To(BeNil())
+			})
+		})
+	})
+
 })
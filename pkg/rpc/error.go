@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const errorDomain = "shortening-url"
+
+func businessCodeToGRPCCode(businessError *business.Error) codes.Code {
+	switch businessError.BusinessCode {
+	case business.NotFound, business.PathNotFound:
+		return codes.NotFound
+	case business.Validation:
+		return codes.InvalidArgument
+	case business.TooManyRequest:
+		return codes.ResourceExhausted
+	case business.ServerUnavailable, business.AcquireLockURLResourceError, business.CapacityExhausted:
+		return codes.Unavailable
+	case business.MethodNowAllowed:
+		return codes.Unimplemented
+	case business.DeadlineExceeded:
+		return codes.DeadlineExceeded
+	case business.Canceled:
+		return codes.Canceled
+	case business.Unknown:
+		return codes.Unknown
+	}
+
+	// 其他沒有特別定義的code 就依照http status code來決定
+	switch businessError.HTTPStatusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// toStatusError 會把business code放在ErrorInfo 讓client可以拿到跟http api一樣的code
+func toStatusError(businessError *business.Error) error {
+	st := status.New(businessCodeToGRPCCode(businessError), businessError.Message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   strconv.Itoa(businessError.BusinessCode),
+		Domain:   errorDomain,
+		Metadata: businessError.ValidationErrors,
+	})
+	// 對應http的Retry-After
+	if err == nil && businessError.RetryAfter > 0 {
+		detailed, err = detailed.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(businessError.RetryAfter)})
+	}
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/repository"
+	"github.com/KennyChenFight/Shortening-URL/pkg/rpc/shorteningpb"
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+)
+
+// 跟http的binding用同一組規則 兩邊才會接受一樣的url
+const originalURLRule = "required,min=1,max=2048"
+
+var urlValidator = validator.New()
+
+type Config struct {
+	FQDN         string
+	MaxBatchSize int
+}
+
+func NewGRPCServer(config *Config, logger *loglib.Logger, urlRepository repository.Repository) *grpc.Server {
+	server := grpc.NewServer()
+	shorteningpb.RegisterShorteningURLServiceServer(server, NewShorteningURLServer(config, logger, urlRepository))
+	return server
+}
+
+func NewShorteningURLServer(config *Config, logger *loglib.Logger, urlRepository repository.Repository) *ShorteningURLServer {
+	return &ShorteningURLServer{config: config, logger: logger, urlRepository: urlRepository}
+}
+
+type ShorteningURLServer struct {
+	shorteningpb.UnimplementedShorteningURLServiceServer
+	config        *Config
+	logger        *loglib.Logger
+	urlRepository repository.Repository
+}
+
+func (s *ShorteningURLServer) CreateShorteningURL(ctx context.Context, request *shorteningpb.CreateShorteningURLRequest) (*shorteningpb.CreateShorteningURLResponse, error) {
+	if err := urlValidator.Var(request.Url, originalURLRule); err != nil {
+		return nil, toStatusError(business.NewError(business.Validation, http.StatusBadRequest, "invalid url field", err))
+	}
+
+	url, err := s.urlRepository.CreateShorteningURL(ctx, request.Url)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &shorteningpb.CreateShorteningURLResponse{Id: url.ID, ShortUrl: fmt.Sprintf("%s/%s", s.config.FQDN, url.ID)}, nil
+}
+
+func (s *ShorteningURLServer) GetOriginalURL(ctx context.Context, request *shorteningpb.GetOriginalURLRequest) (*shorteningpb.GetOriginalURLResponse, error) {
+	if err := validateID(request.Id); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	originalURL, err := s.urlRepository.GetOriginalURL(ctx, request.Id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &shorteningpb.GetOriginalURLResponse{Id: request.Id, OriginalUrl: originalURL}, nil
+}
+
+func (s *ShorteningURLServer) DeleteShorteningURL(ctx context.Context, request *shorteningpb.DeleteShorteningURLRequest) (*shorteningpb.DeleteShorteningURLResponse, error) {
+	if err := validateID(request.Id); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if err := s.urlRepository.DeleteShorteningURL(ctx, request.Id); err != nil {
+		return nil, toStatusError(err)
+	}
+	return &shorteningpb.DeleteShorteningURLResponse{}, nil
+}
+
+func (s *ShorteningURLServer) BatchGetOriginalURLs(ctx context.Context, request *shorteningpb.BatchGetOriginalURLsRequest) (*shorteningpb.BatchGetOriginalURLsResponse, error) {
+	if len(request.Ids) == 0 || len(request.Ids) > s.config.MaxBatchSize {
+		return nil, toStatusError(business.NewError(business.Validation, http.StatusBadRequest, fmt.Sprintf("ids length should be between 1 and %d", s.config.MaxBatchSize), nil))
+	}
+
+	// 單一個id失敗不影響整個batch 錯誤會放在各自的result裡
+	results := make([]*shorteningpb.ResolvedURL, 0, len(request.Ids))
+	for _, id := range request.Ids {
+		result := &shorteningpb.ResolvedURL{Id: id}
+		originalURL, err := s.resolve(ctx, id)
+		if err != nil {
+			result.ErrorCode = int32(err.BusinessCode)
+			result.ErrorMessage = err.Message
+		} else {
+			result.OriginalUrl = originalURL
+		}
+		results = append(results, result)
+	}
+	return &shorteningpb.BatchGetOriginalURLsResponse{Results: results}, nil
+}
+
+func (s *ShorteningURLServer) resolve(ctx context.Context, id string) (string, *business.Error) {
+	if err := validateID(id); err != nil {
+		return "", err
+	}
+	return s.urlRepository.GetOriginalURL(ctx, id)
+}
+
+func validateID(id string) *business.Error {
+	if !shortid.Valid(id) {
+		return business.NewError(business.Validation, http.StatusBadRequest, "invalid id field", nil)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/repositorymock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/rpc/shorteningpb"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func expectStatus(err error, code codes.Code, businessCode int) {
+	st, ok := status.FromError(err)
+	Expect(ok).To(BeTrue())
+	Expect(st.Code()).To(Equal(code))
+	Expect(st.Details()).To(HaveLen(1))
+	errorInfo, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	Expect(ok).To(BeTrue())
+	Expect(errorInfo.Reason).To(Equal(strconv.Itoa(businessCode)))
+	Expect(errorInfo.Domain).To(Equal(errorDomain))
+}
+
+var _ = Describe("ShorteningURLServer", func() {
+	var server *ShorteningURLServer
+	var mockCtrl *gomock.Controller
+	var repositoryMock *repositorymock.MockRepository
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		repositoryMock = repositorymock.NewMockRepository(mockCtrl)
+		server = NewShorteningURLServer(&Config{FQDN: "http://example.com", MaxBatchSize: 2}, loglib.NewNopLogger(), repositoryMock)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var _ = Describe("CreateShorteningURL", func() {
+		It("success", func() {
+			repositoryMock.EXPECT().CreateShorteningURL(gomock.Any(), "http://test.com").Return(&dao.URL{ID: "abcdef", Original: "http://test.com"}, nil)
+			response, err := server.CreateShorteningURL(ctx, &shorteningpb.CreateShorteningURLRequest{Url: "http://test.com"})
+			Expect(err).To(BeNil())
+			Expect(response.Id).To(Equal("abcdef"))
+			Expect(response.ShortUrl).To(Equal("http://example.com/abcdef"))
+		})
+
+		It("fail with validation", func() {
+			_, err := server.CreateShorteningURL(ctx, &shorteningpb.CreateShorteningURLRequest{})
+			expectStatus(err, codes.InvalidArgument, business.Validation)
+		})
+
+		It("fail with too long url", func() {
+			_, err := server.CreateShorteningURL(ctx, &shorteningpb.CreateShorteningURLRequest{Url: "http://test.com/" + strings.Repeat("a", 2048)})
+			expectStatus(err, codes.InvalidArgument, business.Validation)
+		})
+
+		It("fail with capacity exhausted", func() {
+			exhaustedErr := business.NewError(business.CapacityExhausted, http.StatusServiceUnavailable, "capacity exhausted", nil)
+			exhaustedErr.RetryAfter = 30 * time.Second
+			repositoryMock.EXPECT().CreateShorteningURL(gomock.Any(), "http://test.com").Return(nil, exhaustedErr)
+			_, err := server.CreateShorteningURL(ctx, &shorteningpb.CreateShorteningURLRequest{Url: "http://test.com"})
+			st, _ := status.FromError(err)
+			Expect(st.Code()).To(Equal(codes.Unavailable))
+			Expect(st.Details()).To(HaveLen(2))
+			retryInfo, ok := st.Details()[1].(*errdetails.RetryInfo)
+			Expect(ok).To(BeTrue())
+			Expect(retryInfo.RetryDelay.AsDuration()).To(Equal(30 * time.Second))
+		})
+
+		It("fail with repository", func() {
+			repositoryMock.EXPECT().CreateShorteningURL(gomock.Any(), "http://test.com").Return(nil, business.NewError(business.PostgresInternalError, http.StatusInternalServerError, "internal error", errors.New("internal")))
+			_, err := server.CreateShorteningURL(ctx, &shorteningpb.CreateShorteningURLRequest{Url: "http://test.com"})
+			expectStatus(err, codes.Internal, business.PostgresInternalError)
+		})
+	})
+
+	var _ = Describe("GetOriginalURL", func() {
+		It("success", func() {
+			repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), "abcdef").Return("http://test.com", nil)
+			response, err := server.GetOriginalURL(ctx, &shorteningpb.GetOriginalURLRequest{Id: "abcdef"})
+			Expect(err).To(BeNil())
+			Expect(response.OriginalUrl).To(Equal("http://test.com"))
+		})
+
+		It("fail with validation", func() {
+			_, err := server.GetOriginalURL(ctx, &shorteningpb.GetOriginalURLRequest{Id: "abc"})
+			expectStatus(err, codes.InvalidArgument, business.Validation)
+		})
+
+		It("fail with not found", func() {
+			repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), "abcdef").Return("", business.NewError(business.NotFound, http.StatusNotFound, "record not found", nil))
+			_, err := server.GetOriginalURL(ctx, &shorteningpb.GetOriginalURLRequest{Id: "abcdef"})
+			expectStatus(err, codes.NotFound, business.NotFound)
+		})
+
+		It("fail with lock unavailable", func() {
+			repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), "abcdef").Return("", business.NewError(business.AcquireLockURLResourceError, http.StatusServiceUnavailable, "server unavailable", nil))
+			_, err := server.GetOriginalURL(ctx, &shorteningpb.GetOriginalURLRequest{Id: "abcdef"})
+			expectStatus(err, codes.Unavailable, business.AcquireLockURLResourceError)
+		})
+	})
+
+	var _ = Describe("DeleteShorteningURL", func() {
+		It("success", func() {
+			repositoryMock.EXPECT().DeleteShorteningURL(gomock.Any(), "abcdef").Return(nil)
+			_, err := server.DeleteShorteningURL(ctx, &shorteningpb.DeleteShorteningURLRequest{Id: "abcdef"})
+			Expect(err).To(BeNil())
+		})
+
+		It("fail with repository", func() {
+			repositoryMock.EXPECT().DeleteShorteningURL(gomock.Any(), "abcdef").Return(business.NewError(business.RedisInternalError, http.StatusInternalServerError, "internal error", nil))
+			_, err := server.DeleteShorteningURL(ctx, &shorteningpb.DeleteShorteningURLRequest{Id: "abcdef"})
+			expectStatus(err, codes.Internal, business.RedisInternalError)
+		})
+	})
+
+	var _ = Describe("BatchGetOriginalURLs", func() {
+		It("success with partial failure", func() {
+			repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), "abcdef").Return("http://test.com", nil)
+			response, err := server.BatchGetOriginalURLs(ctx, &shorteningpb.BatchGetOriginalURLsRequest{Ids: []string{"abcdef", "abc"}})
+			Expect(err).To(BeNil())
+			Expect(response.Results).To(HaveLen(2))
+			Expect(response.Results[0].OriginalUrl).To(Equal("http://test.com"))
+			Expect(response.Results[0].ErrorCode).To(Equal(int32(0)))
+			Expect(response.Results[1].OriginalUrl).To(Equal(""))
+			Expect(response.Results[1].ErrorCode).To(Equal(int32(business.Validation)))
+		})
+
+		It("fail with exceed max batch size", func() {
+			_, err := server.BatchGetOriginalURLs(ctx, &shorteningpb.BatchGetOriginalURLsRequest{Ids: []string{"abcdef", "bcdefg", "cdefgh"}})
+			expectStatus(err, codes.InvalidArgument, business.Validation)
+		})
+	})
+})
@@ -0,0 +1,209 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package shorteningpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ShorteningURLServiceClient is the client API for ShorteningURLService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ShorteningURLServiceClient interface {
+	CreateShorteningURL(ctx context.Context, in *CreateShorteningURLRequest, opts ...grpc.CallOption) (*CreateShorteningURLResponse, error)
+	GetOriginalURL(ctx context.Context, in *GetOriginalURLRequest, opts ...grpc.CallOption) (*GetOriginalURLResponse, error)
+	DeleteShorteningURL(ctx context.Context, in *DeleteShorteningURLRequest, opts ...grpc.CallOption) (*DeleteShorteningURLResponse, error)
+	BatchGetOriginalURLs(ctx context.Context, in *BatchGetOriginalURLsRequest, opts ...grpc.CallOption) (*BatchGetOriginalURLsResponse, error)
+}
+
+type shorteningURLServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShorteningURLServiceClient(cc grpc.ClientConnInterface) ShorteningURLServiceClient {
+	return &shorteningURLServiceClient{cc}
+}
+
+func (c *shorteningURLServiceClient) CreateShorteningURL(ctx context.Context, in *CreateShorteningURLRequest, opts ...grpc.CallOption) (*CreateShorteningURLResponse, error) {
+	out := new(CreateShorteningURLResponse)
+	err := c.cc.Invoke(ctx, "/shortening.v1.ShorteningURLService/CreateShorteningURL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shorteningURLServiceClient) GetOriginalURL(ctx context.Context, in *GetOriginalURLRequest, opts ...grpc.CallOption) (*GetOriginalURLResponse, error) {
+	out := new(GetOriginalURLResponse)
+	err := c.cc.Invoke(ctx, "/shortening.v1.ShorteningURLService/GetOriginalURL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shorteningURLServiceClient) DeleteShorteningURL(ctx context.Context, in *DeleteShorteningURLRequest, opts ...grpc.CallOption) (*DeleteShorteningURLResponse, error) {
+	out := new(DeleteShorteningURLResponse)
+	err := c.cc.Invoke(ctx, "/shortening.v1.ShorteningURLService/DeleteShorteningURL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shorteningURLServiceClient) BatchGetOriginalURLs(ctx context.Context, in *BatchGetOriginalURLsRequest, opts ...grpc.CallOption) (*BatchGetOriginalURLsResponse, error) {
+	out := new(BatchGetOriginalURLsResponse)
+	err := c.cc.Invoke(ctx, "/shortening.v1.ShorteningURLService/BatchGetOriginalURLs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ShorteningURLServiceServer is the server API for ShorteningURLService service.
+// All implementations must embed UnimplementedShorteningURLServiceServer
+// for forward compatibility
+type ShorteningURLServiceServer interface {
+	CreateShorteningURL(context.Context, *CreateShorteningURLRequest) (*CreateShorteningURLResponse, error)
+	GetOriginalURL(context.Context, *GetOriginalURLRequest) (*GetOriginalURLResponse, error)
+	DeleteShorteningURL(context.Context, *DeleteShorteningURLRequest) (*DeleteShorteningURLResponse, error)
+	BatchGetOriginalURLs(context.Context, *BatchGetOriginalURLsRequest) (*BatchGetOriginalURLsResponse, error)
+	mustEmbedUnimplementedShorteningURLServiceServer()
+}
+
+// UnimplementedShorteningURLServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedShorteningURLServiceServer struct {
+}
+
+func (UnimplementedShorteningURLServiceServer) CreateShorteningURL(context.Context, *CreateShorteningURLRequest) (*CreateShorteningURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateShorteningURL not implemented")
+}
+func (UnimplementedShorteningURLServiceServer) GetOriginalURL(context.Context, *GetOriginalURLRequest) (*GetOriginalURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOriginalURL not implemented")
+}
+func (UnimplementedShorteningURLServiceServer) DeleteShorteningURL(context.Context, *DeleteShorteningURLRequest) (*DeleteShorteningURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteShorteningURL not implemented")
+}
+func (UnimplementedShorteningURLServiceServer) BatchGetOriginalURLs(context.Context, *BatchGetOriginalURLsRequest) (*BatchGetOriginalURLsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetOriginalURLs not implemented")
+}
+func (UnimplementedShorteningURLServiceServer) mustEmbedUnimplementedShorteningURLServiceServer() {}
+
+// UnsafeShorteningURLServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShorteningURLServiceServer will
+// result in compilation errors.
+type UnsafeShorteningURLServiceServer interface {
+	mustEmbedUnimplementedShorteningURLServiceServer()
+}
+
+func RegisterShorteningURLServiceServer(s grpc.ServiceRegistrar, srv ShorteningURLServiceServer) {
+	s.RegisterService(&ShorteningURLService_ServiceDesc, srv)
+}
+
+func _ShorteningURLService_CreateShorteningURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateShorteningURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShorteningURLServiceServer).CreateShorteningURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortening.v1.ShorteningURLService/CreateShorteningURL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShorteningURLServiceServer).CreateShorteningURL(ctx, req.(*CreateShorteningURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShorteningURLService_GetOriginalURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOriginalURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShorteningURLServiceServer).GetOriginalURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortening.v1.ShorteningURLService/GetOriginalURL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShorteningURLServiceServer).GetOriginalURL(ctx, req.(*GetOriginalURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShorteningURLService_DeleteShorteningURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteShorteningURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShorteningURLServiceServer).DeleteShorteningURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortening.v1.ShorteningURLService/DeleteShorteningURL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShorteningURLServiceServer).DeleteShorteningURL(ctx, req.(*DeleteShorteningURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShorteningURLService_BatchGetOriginalURLs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetOriginalURLsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShorteningURLServiceServer).BatchGetOriginalURLs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortening.v1.ShorteningURLService/BatchGetOriginalURLs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShorteningURLServiceServer).BatchGetOriginalURLs(ctx, req.(*BatchGetOriginalURLsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ShorteningURLService_ServiceDesc is the grpc.ServiceDesc for ShorteningURLService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ShorteningURLService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shortening.v1.ShorteningURLService",
+	HandlerType: (*ShorteningURLServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateShorteningURL",
+			Handler:    _ShorteningURLService_CreateShorteningURL_Handler,
+		},
+		{
+			MethodName: "GetOriginalURL",
+			Handler:    _ShorteningURLService_GetOriginalURL_Handler,
+		},
+		{
+			MethodName: "DeleteShorteningURL",
+			Handler:    _ShorteningURLService_DeleteShorteningURL_Handler,
+		},
+		{
+			MethodName: "BatchGetOriginalURLs",
+			Handler:    _ShorteningURLService_BatchGetOriginalURLs_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "shortening.proto",
+}
@@ -24,11 +24,37 @@ func registerRoutingRule(engine *gin.Engine, mwe *middleware.BaseMiddleware, svc
 	{
 		v1APIGroup.POST("/urls", svc.CreateShorteningURL)
 		v1APIGroup.DELETE("/urls/:id", svc.DeleteShorteningURL)
+		v1APIGroup.GET("/urls/:id/health", svc.GetURLHealth)
+		v1APIGroup.GET("/health-checks/broken", svc.ListBrokenURLs)
 		// for local test, need to remove in production
 		v1APIGroup.POST("/_internal/keys", svc.BatchCreateKeys)
+		v1APIGroup.GET("/openapi.json", svc.GetOpenAPISpec)
+		v1APIGroup.GET("/docs", svc.GetAPIDocs)
+		v1APIGroup.GET("/docs/assets/:file", svc.GetAPIDocsAsset)
 	}
 
 	// for redirect
 	engine.GET("/:id", mwe.SlideWindowRateLimiter(), svc.GetOriginalURL)
+	engine.HEAD("/:id", mwe.SlideWindowRateLimiter(), svc.GetOriginalURL)
+	return engine
+}
+
+// NewAdminHTTPServer bulk export/import這種一次讀寫全部url的api不放在對外的server 只開在另外的port
+func NewAdminHTTPServer(engine *gin.Engine, port string, mwe *middleware.BaseMiddleware, svc *service.BaseService) *http.Server {
+	return &http.Server{
+		Addr:    port,
+		Handler: registerAdminRoutingRule(engine, mwe, svc),
+	}
+}
+
+func registerAdminRoutingRule(engine *gin.Engine, mwe *middleware.BaseMiddleware, svc *service.BaseService) *gin.Engine {
+	engine.Use(mwe.GlobalErrorHandle())
+	engine.NoMethod(svc.HandleMethodNotAllowed)
+	engine.NoRoute(svc.HandlePathNotFound)
+	v1APIGroup := engine.Group("/api/v1")
+	{
+		v1APIGroup.GET("/urls/export", svc.ExportURLs)
+		v1APIGroup.POST("/urls/import", svc.ImportURLs)
+	}
 	return engine
 }
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/middleware"
+	"github.com/KennyChenFight/Shortening-URL/pkg/service"
+	"github.com/KennyChenFight/Shortening-URL/pkg/validation"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var ginPathParam = regexp.MustCompile(`:([^/]+)`)
+
+// docsPageAsset index.html裡引用的script以及stylesheet
+var docsPageAsset = regexp.MustCompile(`(?:src|href)="([^"]+)"`)
+
+var _ = Describe("registerRoutingRule", func() {
+	var engine, adminEngine *gin.Engine
+	var spec struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas struct {
+				BusinessCode struct {
+					Enum []int `json:"enum"`
+				} `json:"BusinessCode"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	BeforeEach(func() {
+		gin.SetMode("release")
+		logger := loglib.NewNopLogger()
+		translator, err := validation.NewValidationTranslator(validator.New(), "en")
+		Expect(err).To(BeNil())
+		mwe := middleware.NewMiddleware(logger, translator, nil)
+		svc := service.NewService(&service.Config{}, logger, nil)
+		engine = registerRoutingRule(gin.New(), mwe, svc)
+		adminEngine = registerAdminRoutingRule(gin.New(), mwe, svc)
+
+		// 用server實際回傳的文件 不是直接讀embed的檔案
+		recorder := get("/api/v1/openapi.json")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &spec)).To(Succeed())
+		Expect(spec.OpenAPI).To(HavePrefix("3."))
+		Expect(spec.Paths).NotTo(BeEmpty())
+	})
+
+	// 有自己servers的path是開在admin port的api
+	documented := func(admin bool) map[string]bool {
+		operations := make(map[string]bool)
+		for path, item := range spec.Paths {
+			if _, ok := item["servers"]; ok != admin {
+				continue
+			}
+			for method := range item {
+				if method != "servers" {
+					operations[strings.ToUpper(method)+" "+path] = true
+				}
+			}
+		}
+		return operations
+	}
+
+	registered := func(engine *gin.Engine) map[string]bool {
+		operations := make(map[string]bool)
+		for _, route := range engine.Routes() {
+			operations[route.Method+" "+ginPathParam.ReplaceAllString(route.Path, "{$1}")] = true
+		}
+		return operations
+	}
+
+	It("business code enum matches the constants", func() {
+		// 直接parse原始碼 新增code忘了補文件的話這裡會失敗
+		file, err := parser.ParseFile(token.NewFileSet(), "../business/business_code.go", nil, 0)
+		Expect(err).To(BeNil())
+		var codes []int
+		ast.Inspect(file, func(node ast.Node) bool {
+			if literal, ok := node.(*ast.BasicLit); ok && literal.Kind == token.INT {
+				code, err := strconv.Atoi(literal.Value)
+				Expect(err).To(BeNil())
+				codes = append(codes, code)
+			}
+			return true
+		})
+		sort.Ints(codes)
+		Expect(codes).NotTo(BeEmpty())
+		Expect(spec.Components.Schemas.BusinessCode.Enum).To(Equal(codes))
+	})
+
+	It("every route is documented in openapi spec", func() {
+		for operation := range registered(engine) {
+			Expect(documented(false)).To(HaveKey(operation), "%s is missing in openapi spec", operation)
+		}
+		for operation := range registered(adminEngine) {
+			Expect(documented(true)).To(HaveKey(operation), "%s is missing in openapi spec as admin api", operation)
+		}
+	})
+
+	It("every documented operation is registered", func() {
+		for operation := range documented(false) {
+			Expect(registered(engine)).To(HaveKey(operation), "%s is not registered", operation)
+		}
+		for operation := range documented(true) {
+			Expect(registered(adminEngine)).To(HaveKey(operation), "%s is not registered on admin server", operation)
+		}
+	})
+
+	It("bulk api is not on the public server", func() {
+		Expect(get("/api/v1/urls/export").Code).To(Equal(http.StatusNotFound))
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/urls/import", nil))
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("docs page loads every asset from this server", func() {
+		recorder := get("/api/v1/docs")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		assets := docsPageAsset.FindAllStringSubmatch(recorder.Body.String(), -1)
+		Expect(assets).NotTo(BeEmpty())
+		for _, asset := range assets {
+			Expect(asset[1]).To(HavePrefix("/api/v1/docs/assets/"), "%s is not served by this server", asset[1])
+			recorder := get(asset[1])
+			Expect(recorder.Code).To(Equal(http.StatusOK), "%s is not served", asset[1])
+			Expect(recorder.Body.Len()).To(BeNumerically(">", 0))
+		}
+		Expect(get("/api/v1/docs/assets/openapi.json").Code).To(Equal(http.StatusNotFound))
+	})
+})
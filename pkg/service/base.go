@@ -5,12 +5,24 @@ import (
 	"net/http"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/repository"
+	"github.com/KennyChenFight/Shortening-URL/pkg/validation"
 
 	"github.com/KennyChenFight/Shortening-URL/pkg/business"
 	"github.com/KennyChenFight/golib/loglib"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
+// gin的binding validator是global的 在這裡註冊自訂的tag 讓handler可以直接用
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		if err := validation.RegisterCustomValidations(v); err != nil {
+			panic(err)
+		}
+	}
+}
+
 type Config struct {
 	FQDN string
 }
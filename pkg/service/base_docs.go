@@ -0,0 +1,31 @@
+package service
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/docs"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *BaseService) GetOpenAPISpec(c *gin.Context) {
+	s.responseWithSuccess(c, business.NewSuccess(http.StatusOK, json.RawMessage(docs.OpenAPISpec)))
+}
+
+func (s *BaseService) GetAPIDocs(c *gin.Context) {
+	// html page 不走 json response 直接寫回
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docs.UIPage)
+}
+
+func (s *BaseService) GetAPIDocsAsset(c *gin.Context) {
+	name := c.Param("file")
+	data, ok := docs.UIAsset(name)
+	if !ok {
+		s.HandlePathNotFound(c)
+		return
+	}
+	c.Data(http.StatusOK, mime.TypeByExtension(path.Ext(name)), data)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultListBrokenURLsLimit = 100
+
+func (s *BaseService) GetURLHealth(c *gin.Context) {
+	var request struct {
+		ID string `json:"id" uri:"id" binding:"shortid"`
+	}
+	if err := c.ShouldBindUri(&request); err != nil {
+		s.responseWithError(c, business.NewError(business.Validation, http.StatusBadRequest, "invalid id field", err))
+		return
+	}
+
+	check, err := s.urlRepository.GetURLHealth(c.Request.Context(), request.ID)
+	if err != nil {
+		s.responseWithError(c, err)
+		return
+	}
+	s.responseWithSuccess(c, business.NewSuccess(http.StatusOK, check))
+}
+
+func (s *BaseService) ListBrokenURLs(c *gin.Context) {
+	var request struct {
+		Limit int `json:"limit" form:"limit" binding:"omitempty,min=1,max=1000"`
+	}
+	if err := c.ShouldBindQuery(&request); err != nil {
+		s.responseWithError(c, business.NewError(business.Validation, http.StatusBadRequest, "invalid limit field", err))
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = defaultListBrokenURLsLimit
+	}
+
+	checks, err := s.urlRepository.ListBrokenURLs(c.Request.Context(), request.Limit)
+	if err != nil {
+		s.responseWithError(c, err)
+		return
+	}
+	s.responseWithSuccess(c, business.NewSuccess(http.StatusOK, gin.H{"healthChecks": checks}))
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/repositorymock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BaseService HealthCheck", func() {
+	var baseService *BaseService
+	var mockCtrl *gomock.Controller
+	var repositoryMock *repositorymock.MockRepository
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		repositoryMock = repositorymock.NewMockRepository(mockCtrl)
+		baseService = NewService(&Config{FQDN: "http://example.com"}, loglib.NewNopLogger(), repositoryMock)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var _ = Describe("GetURLHealth", func() {
+		var ginMockContext *gin.Context
+		var actualID string
+
+		BeforeEach(func() {
+			ginMockContext, _ = gin.CreateTestContext(httptest.NewRecorder())
+		})
+
+		JustBeforeEach(func() {
+			ginMockContext.Params = gin.Params{{Key: "id", Value: actualID}}
+			ginMockContext.Request = httptest.NewRequest("GET", "http://server.com/api/v1/health-checks/"+actualID, nil)
+			baseService.GetURLHealth(ginMockContext)
+		})
+
+		Context("success", func() {
+			var check *dao.HealthCheck
+			BeforeEach(func() {
+				actualID = "random"
+				check = &dao.HealthCheck{URLID: actualID, StatusCode: http.StatusOK}
+				repositoryMock.EXPECT().GetURLHealth(gomock.Any(), actualID).Return(check, nil)
+			})
+
+			It("result", func() {
+				expectSuccess, _ := ginMockContext.Get("success")
+				Expect(expectSuccess).To(Equal(business.NewSuccess(http.StatusOK, check)))
+			})
+		})
+
+		Context("binding validation fail", func() {
+			BeforeEach(func() {
+				actualID = "test"
+			})
+
+			It("result", func() {
+				businessError, ok := ginMockContext.Errors.Last().Err.(*business.Error)
+				Expect(ok).To(BeTrue())
+				Expect(businessError.BusinessCode).To(Equal(business.Validation))
+			})
+		})
+
+		Context("get url health fail", func() {
+			var getErr *business.Error
+			BeforeEach(func() {
+				actualID = "random"
+				getErr = business.NewError(business.NotFound, http.StatusNotFound, "record not found", errors.New(""))
+				repositoryMock.EXPECT().GetURLHealth(gomock.Any(), actualID).Return(nil, getErr)
+			})
+
+			It("result", func() {
+				Expect(ginMockContext.Errors.Last().Err).To(Equal(getErr))
+			})
+		})
+	})
+
+	var _ = Describe("ListBrokenURLs", func() {
+		var ginMockContext *gin.Context
+		var rawQuery string
+
+		BeforeEach(func() {
+			ginMockContext, _ = gin.CreateTestContext(httptest.NewRecorder())
+		})
+
+		JustBeforeEach(func() {
+			ginMockContext.Request = httptest.NewRequest("GET", "http://server.com/api/v1/health-checks/broken?"+rawQuery, nil)
+			baseService.ListBrokenURLs(ginMockContext)
+		})
+
+		Context("success with default limit", func() {
+			checks := []dao.HealthCheck{{URLID: "random", Broken: true}}
+			BeforeEach(func() {
+				rawQuery = ""
+				repositoryMock.EXPECT().ListBrokenURLs(gomock.Any(), defaultListBrokenURLsLimit).Return(checks, nil)
+			})
+
+			It("result", func() {
+				expectSuccess, _ := ginMockContext.Get("success")
+				Expect(expectSuccess).To(Equal(business.NewSuccess(http.StatusOK, gin.H{"healthChecks": checks})))
+			})
+		})
+
+		Context("success with given limit", func() {
+			BeforeEach(func() {
+				rawQuery = "limit=10"
+				repositoryMock.EXPECT().ListBrokenURLs(gomock.Any(), 10).Return(nil, nil)
+			})
+
+			It("result", func() {
+				Expect(ginMockContext.Errors).To(BeEmpty())
+			})
+		})
+
+		Context("binding validation fail with exceed limit", func() {
+			BeforeEach(func() {
+				rawQuery = "limit=1001"
+			})
+
+			It("result", func() {
+				businessError, ok := ginMockContext.Errors.Last().Err.(*business.Error)
+				Expect(ok).To(BeTrue())
+				Expect(businessError.BusinessCode).To(Equal(business.Validation))
+			})
+		})
+	})
+})
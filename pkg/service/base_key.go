@@ -16,7 +16,7 @@ func (s *BaseService) BatchCreateKeys(c *gin.Context) {
 		return
 	}
 
-	length, err := s.urlRepository.BatchCreateKeys(request.Number)
+	length, err := s.urlRepository.BatchCreateKeys(c.Request.Context(), request.Number)
 	if err != nil {
 		s.responseWithError(c, err)
 		return
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/transfer"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const exportURLsBatchSize = 1000
+const importURLsBatchSize = 500
+
+func (s *BaseService) ExportURLs(c *gin.Context) {
+	var request struct {
+		Format         string `json:"format" form:"format" binding:"omitempty,oneof=csv jsonl"`
+		IncludeExpired bool   `json:"includeExpired" form:"includeExpired"`
+	}
+	if err := c.ShouldBindQuery(&request); err != nil {
+		s.responseWithError(c, business.NewError(business.Validation, http.StatusBadRequest, "invalid format or includeExpired field", err))
+		return
+	}
+	format := transfer.CSV
+	if request.Format != "" {
+		format = transfer.Format(request.Format)
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=urls.%s", format))
+	exported, err := transfer.Export(c.Request.Context(), c.Writer, format, s.urlRepository, exportURLsBatchSize, request.IncludeExpired)
+	if err != nil {
+		// 已經開始寫body就沒辦法改status code了 只能記log
+		if c.Writer.Written() {
+			s.logger.Error("fail to export urls after response started", zap.Int("exported", exported), zap.Error(err))
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		s.responseWithError(c, err)
+		return
+	}
+	// 沒有任何資料時body是空的 也要把header寫回去
+	c.Writer.WriteHeaderNow()
+}
+
+func (s *BaseService) ImportURLs(c *gin.Context) {
+	var request struct {
+		Format string `json:"format" form:"format" binding:"omitempty,oneof=csv jsonl"`
+	}
+	if err := c.ShouldBindQuery(&request); err != nil {
+		s.responseWithError(c, business.NewError(business.Validation, http.StatusBadRequest, "invalid format field", err))
+		return
+	}
+	format := transfer.CSV
+	if request.Format != "" {
+		format = transfer.Format(request.Format)
+	}
+
+	summary, err := transfer.Import(c.Request.Context(), c.Request.Body, format, s.urlRepository, importURLsBatchSize)
+	if err != nil {
+		// 失敗之前的batch已經寫入 記下來方便對帳
+		s.logger.Error("fail to import urls", zap.Int("imported", summary.Imported), zap.Error(err))
+		s.responseWithError(c, err)
+		return
+	}
+	s.responseWithSuccess(c, business.NewSuccess(http.StatusOK, summary))
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/repositorymock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/transfer"
+	"github.com/KennyChenFight/golib/loglib"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BaseService Transfer", func() {
+	var baseService *BaseService
+	var mockCtrl *gomock.Controller
+	var repositoryMock *repositorymock.MockRepository
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		repositoryMock = repositorymock.NewMockRepository(mockCtrl)
+		baseService = NewService(&Config{FQDN: "http://example.com"}, loglib.NewNopLogger(), repositoryMock)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var _ = Describe("ExportURLs", func() {
+		var ginMockContext *gin.Context
+		var mockWriter *httptest.ResponseRecorder
+		var actualQuery string
+
+		BeforeEach(func() {
+			mockWriter = httptest.NewRecorder()
+			ginMockContext, _ = gin.CreateTestContext(mockWriter)
+		})
+
+		JustBeforeEach(func() {
+			ginMockContext.Request = httptest.NewRequest(http.MethodGet, "http://server.com/api/v1/urls/export?"+actualQuery, nil)
+			baseService.ExportURLs(ginMockContext)
+		})
+
+		Context("success with csv", func() {
+			BeforeEach(func() {
+				actualQuery = "format=csv&includeExpired=true"
+				repositoryMock.EXPECT().ListURLs(gomock.Any(), "", exportURLsBatchSize, true).Return([]dao.URL{{ID: "random", Original: "http://example.com"}}, nil)
+			})
+
+			It("result", func() {
+				result := mockWriter.Result()
+				Expect(result.StatusCode).To(Equal(http.StatusOK))
+				Expect(result.Header.Get("Content-Type")).To(Equal(transfer.CSV.ContentType()))
+				Expect(result.Header.Get("Content-Disposition")).To(Equal("attachment; filename=urls.csv"))
+				Expect(mockWriter.Body.String()).To(HavePrefix("id,original,created_at,expired_at\nrandom,http://example.com,"))
+			})
+		})
+
+		Context("success with empty jsonl", func() {
+			BeforeEach(func() {
+				actualQuery = "format=jsonl"
+				repositoryMock.EXPECT().ListURLs(gomock.Any(), "", exportURLsBatchSize, false).Return(nil, nil)
+			})
+
+			It("result", func() {
+				Expect(ginMockContext.Writer.Written()).To(BeTrue())
+				Expect(mockWriter.Body.String()).To(BeEmpty())
+			})
+		})
+
+		Context("binding validation fail", func() {
+			BeforeEach(func() {
+				actualQuery = "format=xml"
+			})
+
+			It("result", func() {
+				businessError, ok := ginMockContext.Errors.Last().Err.(*business.Error)
+				Expect(ok).To(BeTrue())
+				Expect(businessError.BusinessCode).To(Equal(business.Validation))
+			})
+		})
+
+		Context("list urls fail before response started", func() {
+			var listErr *business.Error
+			BeforeEach(func() {
+				actualQuery = ""
+				listErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
+				repositoryMock.EXPECT().ListURLs(gomock.Any(), "", exportURLsBatchSize, false).Return(nil, listErr)
+			})
+
+			It("result", func() {
+				Expect(ginMockContext.Errors.Last().Err).To(Equal(listErr))
+				Expect(ginMockContext.Writer.Header().Get("Content-Disposition")).To(BeEmpty())
+			})
+		})
+	})
+
+	var _ = Describe("ImportURLs", func() {
+		var ginMockContext *gin.Context
+		var actualQuery, actualBody string
+
+		BeforeEach(func() {
+			ginMockContext, _ = gin.CreateTestContext(httptest.NewRecorder())
+		})
+
+		JustBeforeEach(func() {
+			ginMockContext.Request = httptest.NewRequest(http.MethodPost, "http://server.com/api/v1/urls/import?"+actualQuery, strings.NewReader(actualBody))
+			baseService.ImportURLs(ginMockContext)
+		})
+
+		Context("success with jsonl", func() {
+			BeforeEach(func() {
+				actualQuery = "format=jsonl"
+				actualBody = "{\"id\":\"random\",\"original\":\"http://example.com\"}\n"
+				repositoryMock.EXPECT().ImportURLs(gomock.Any(), []dao.URL{{ID: "random", Original: "http://example.com"}}).
+					Return([]dao.ImportResult{{OriginalID: "random", ID: "random"}}, nil)
+			})
+
+			It("result", func() {
+				expectSuccess, _ := ginMockContext.Get("success")
+				Expect(expectSuccess).To(Equal(business.NewSuccess(http.StatusOK, &transfer.ImportSummary{Imported: 1, Preserved: 1, Reassigned: []dao.ImportResult{}})))
+			})
+		})
+
+		Context("binding validation fail", func() {
+			BeforeEach(func() {
+				actualQuery = "format=xml"
+				actualBody = ""
+			})
+
+			It("result", func() {
+				businessError, ok := ginMockContext.Errors.Last().Err.(*business.Error)
+				Expect(ok).To(BeTrue())
+				Expect(businessError.BusinessCode).To(Equal(business.Validation))
+			})
+		})
+
+		Context("import urls fail", func() {
+			var importErr *business.Error
+			BeforeEach(func() {
+				actualQuery = ""
+				actualBody = "random,http://example.com,,\n"
+				importErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
+				repositoryMock.EXPECT().ImportURLs(gomock.Any(), gomock.Any()).Return(nil, importErr)
+			})
+
+			It("result", func() {
+				Expect(ginMockContext.Errors.Last().Err).To(Equal(importErr))
+			})
+		})
+	})
+})
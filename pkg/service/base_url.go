@@ -17,7 +17,7 @@ func (s *BaseService) CreateShorteningURL(c *gin.Context) {
 		return
 	}
 
-	url, err := s.urlRepository.CreateShorteningURL(request.URL)
+	url, err := s.urlRepository.CreateShorteningURL(c.Request.Context(), request.URL)
 	if err != nil {
 		s.responseWithError(c, err)
 		return
@@ -28,7 +28,7 @@ func (s *BaseService) CreateShorteningURL(c *gin.Context) {
 
 func (s *BaseService) GetOriginalURL(c *gin.Context) {
 	var request struct {
-		ID string `json:"id" uri:"id" binding:"len=6"`
+		ID string `json:"id" uri:"id" binding:"shortid"`
 	}
 	if err := c.ShouldBindUri(&request); err != nil {
 		s.responseWithError(c, business.NewError(business.Validation, http.StatusBadRequest, "invalid id field", err))
@@ -36,24 +36,31 @@ func (s *BaseService) GetOriginalURL(c *gin.Context) {
 	}
 
 	id := c.Param("id")
-	originalURL, err := s.urlRepository.GetOriginalURL(id)
+	originalURL, err := s.urlRepository.GetOriginalURL(c.Request.Context(), id)
 	if err != nil {
 		s.responseWithError(c, err)
 		return
 	}
+
+	// 給程式化的client 不需要再去parse Location header
+	c.Header("Vary", "Accept")
+	if c.Request.Method == http.MethodGet && c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		s.responseWithSuccess(c, business.NewSuccess(http.StatusOK, gin.H{"id": id, "originalUrl": originalURL}))
+		return
+	}
 	s.responseWithSuccess(c, business.NewSuccess(http.StatusTemporaryRedirect, originalURL))
 }
 
 func (s *BaseService) DeleteShorteningURL(c *gin.Context) {
 	var request struct {
-		ID string `json:"id" uri:"id" binding:"len=6"`
+		ID string `json:"id" uri:"id" binding:"shortid"`
 	}
 	if err := c.ShouldBindUri(&request); err != nil {
 		s.responseWithError(c, business.NewError(business.Validation, http.StatusBadRequest, "invalid id field", err))
 		return
 	}
 
-	err := s.urlRepository.DeleteShorteningURL(request.ID)
+	err := s.urlRepository.DeleteShorteningURL(c.Request.Context(), request.ID)
 	if err != nil {
 		s.responseWithError(c, err)
 		return
@@ -66,7 +66,7 @@ var _ = Describe("BaseService", func() {
 					CreatedAt: time.Now(),
 					ExpiredAt: time.Now(),
 				}
-				repositoryMock.EXPECT().CreateShorteningURL(actualURL).Return(shorteningURL, nil)
+				repositoryMock.EXPECT().CreateShorteningURL(gomock.Any(), actualURL).Return(shorteningURL, nil)
 			})
 
 			It("result", func() {
@@ -115,7 +115,7 @@ var _ = Describe("BaseService", func() {
 				ginMockContext.Request = mockRequest
 
 				createErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New(""))
-				repositoryMock.EXPECT().CreateShorteningURL(actualURL).Return(nil, createErr)
+				repositoryMock.EXPECT().CreateShorteningURL(gomock.Any(), actualURL).Return(nil, createErr)
 			})
 
 			It("result", func() {
@@ -145,7 +145,82 @@ var _ = Describe("BaseService", func() {
 					},
 				}
 				originalURL = "http://example.com"
-				repositoryMock.EXPECT().GetOriginalURL(actualID).Return(originalURL, nil)
+				ginMockContext.Request = httptest.NewRequest("GET", "http://server.com/"+actualID, nil)
+				repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return(originalURL, nil)
+			})
+
+			It("result", func() {
+				actualSuccess := business.NewSuccess(http.StatusTemporaryRedirect, originalURL)
+				expectSuccess, _ := ginMockContext.Get("success")
+				Expect(expectSuccess).To(Equal(actualSuccess))
+			})
+		})
+
+		Context("success with longer id", func() {
+			var actualID string
+			var originalURL string
+			BeforeEach(func() {
+				// 調整id長度之後 新舊長度的id要可以共存
+				actualID = "random7"
+				ginMockContext.Params = gin.Params{
+					{
+						Key:   "id",
+						Value: actualID,
+					},
+				}
+				originalURL = "http://example.com"
+				ginMockContext.Request = httptest.NewRequest("GET", "http://server.com/"+actualID, nil)
+				repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return(originalURL, nil)
+			})
+
+			It("result", func() {
+				actualSuccess := business.NewSuccess(http.StatusTemporaryRedirect, originalURL)
+				expectSuccess, _ := ginMockContext.Get("success")
+				Expect(expectSuccess).To(Equal(actualSuccess))
+			})
+		})
+
+		Context("success with accept json", func() {
+			var actualID string
+			var originalURL string
+			BeforeEach(func() {
+				actualID = "random"
+				ginMockContext.Params = gin.Params{
+					{
+						Key:   "id",
+						Value: actualID,
+					},
+				}
+				originalURL = "http://example.com"
+				ginMockContext.Request = httptest.NewRequest("GET", "http://server.com/"+actualID, nil)
+				ginMockContext.Request.Header.Set("Accept", "application/json")
+				ginMockContext.Accepted = nil
+				repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return(originalURL, nil)
+			})
+
+			It("result", func() {
+				actualSuccess := business.NewSuccess(http.StatusOK, gin.H{"id": actualID, "originalUrl": originalURL})
+				expectSuccess, _ := ginMockContext.Get("success")
+				Expect(expectSuccess).To(Equal(actualSuccess))
+			})
+		})
+
+		Context("success with head method and accept json", func() {
+			var actualID string
+			var originalURL string
+			BeforeEach(func() {
+				actualID = "random"
+				ginMockContext.Params = gin.Params{
+					{
+						Key:   "id",
+						Value: actualID,
+					},
+				}
+				originalURL = "http://example.com"
+				ginMockContext.Request = httptest.NewRequest("HEAD", "http://server.com/"+actualID, nil)
+				ginMockContext.Request.Header.Set("Accept", "application/json")
+				ginMockContext.Accepted = nil
+				repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return(originalURL, nil)
 			})
 
 			It("result", func() {
@@ -188,7 +263,7 @@ var _ = Describe("BaseService", func() {
 					},
 				}
 				getErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New(""))
-				repositoryMock.EXPECT().GetOriginalURL(actualID).Return("", getErr)
+				repositoryMock.EXPECT().GetOriginalURL(gomock.Any(), actualID).Return("", getErr)
 			})
 
 			It("result", func() {
@@ -204,6 +279,7 @@ var _ = Describe("BaseService", func() {
 	var _ = Describe("DeleteShorteningURL", func() {
 		ginMockContext, _ := gin.CreateTestContext(httptest.NewRecorder())
 		JustBeforeEach(func() {
+			ginMockContext.Request = httptest.NewRequest("DELETE", "http://server.com/api/v1/urls/"+ginMockContext.Param("id"), nil)
 			baseService.DeleteShorteningURL(ginMockContext)
 		})
 
@@ -217,7 +293,7 @@ var _ = Describe("BaseService", func() {
 						Value: actualID,
 					},
 				}
-				repositoryMock.EXPECT().DeleteShorteningURL(actualID).Return(nil)
+				repositoryMock.EXPECT().DeleteShorteningURL(gomock.Any(), actualID).Return(nil)
 			})
 
 			It("result", func() {
@@ -260,7 +336,7 @@ var _ = Describe("BaseService", func() {
 					},
 				}
 				deleteErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", errors.New(""))
-				repositoryMock.EXPECT().DeleteShorteningURL(actualID).Return(deleteErr)
+				repositoryMock.EXPECT().DeleteShorteningURL(gomock.Any(), actualID).Return(deleteErr)
 			})
 
 			It("result", func() {
@@ -0,0 +1,13 @@
+package shortid
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestShortID(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ShortID Suite")
+}
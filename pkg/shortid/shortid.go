@@ -0,0 +1,83 @@
+package shortid
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/KennyChenFight/randstr"
+)
+
+const (
+	// 太短的id組合數太少 很快就會用完
+	MinLength = 5
+	// 要跟database的column寬度一致
+	MaxLength     = 16
+	DefaultLength = 6
+)
+
+const (
+	CharsetAlphabet    = "alphabet"
+	CharsetBase62      = "base62"
+	CharsetLowercase   = "lowercase"
+	CharsetUnambiguous = "unambiguous"
+)
+
+const DefaultCharset = CharsetAlphabet
+
+var charsets = map[string]randstr.CharSetType{
+	// 一開始使用的charset 為了相容舊的id保留為預設值
+	CharsetAlphabet: randstr.CharSetEnglishAlphabet,
+	CharsetBase62:   randstr.CharSetBase62,
+	// 不分大小寫的場合 例如要唸出來或是手打
+	CharsetLowercase: "abcdefghijklmnopqrstuvwxyz0123456789",
+	// 拿掉容易看錯的 0/O/o 以及 1/l/I
+	CharsetUnambiguous: "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789",
+}
+
+func Charsets() []string {
+	names := make([]string, 0, len(charsets))
+	for name := range charsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewGenerator(charset string) (randstr.RandomStrGenerator, error) {
+	alphabet, err := Alphabet(charset)
+	if err != nil {
+		return nil, err
+	}
+	return randstr.NewFastGenerator(randstr.CharSetType(alphabet)), nil
+}
+
+// Alphabet charset實際使用的字元
+func Alphabet(charset string) (string, error) {
+	set, ok := charsets[charset]
+	if !ok {
+		return "", fmt.Errorf("unsupported charset %q, should be one of %v", charset, Charsets())
+	}
+	return string(set), nil
+}
+
+func ValidateLength(length int) error {
+	if length < MinLength || length > MaxLength {
+		return fmt.Errorf("id length %d should be between %d and %d", length, MinLength, MaxLength)
+	}
+	return nil
+}
+
+// Valid 不管現在設定的長度以及charset 只要是之前可能產生過的id都算合法
+// 這樣調整設定之後 舊的id還是可以用
+func Valid(id string) bool {
+	if len(id) < MinLength || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package shortid
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ShortID", func() {
+	Context("Valid", func() {
+		It("accept mixed lengths", func() {
+			Expect(Valid("abcDE")).To(BeTrue())
+			Expect(Valid("KAWCny")).To(BeTrue())
+			Expect(Valid("KAWCny7")).To(BeTrue())
+			Expect(Valid(strings.Repeat("a", MaxLength))).To(BeTrue())
+		})
+
+		It("reject bad length or char", func() {
+			Expect(Valid("abcd")).To(BeFalse())
+			Expect(Valid(strings.Repeat("a", MaxLength+1))).To(BeFalse())
+			Expect(Valid("abc-ef")).To(BeFalse())
+			Expect(Valid("abcdé")).To(BeFalse())
+		})
+	})
+
+	Context("NewGenerator", func() {
+		for _, charset := range Charsets() {
+			charset := charset
+			It("generate ids only with "+charset, func() {
+				generator, err := NewGenerator(charset)
+				Expect(err).To(BeNil())
+				for i := 0; i < 100; i++ {
+					id := generator.GenerateRandomStr(7)
+					Expect(id).To(HaveLen(7))
+					Expect(Valid(id)).To(BeTrue())
+					for _, c := range id {
+						Expect(string(charsets[charset])).To(ContainSubstring(string(c)))
+					}
+				}
+			})
+		}
+
+		It("unambiguous charset has no look-alike chars", func() {
+			Expect(strings.ContainsAny(string(charsets[CharsetUnambiguous]), "0Oo1lI")).To(BeFalse())
+		})
+
+		It("fail with unknown charset", func() {
+			_, err := NewGenerator("emoji")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Context("ValidateLength", func() {
+		It("result", func() {
+			Expect(ValidateLength(DefaultLength)).To(BeNil())
+			Expect(ValidateLength(MinLength - 1)).NotTo(BeNil())
+			Expect(ValidateLength(MaxLength + 1)).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,203 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+)
+
+type Format string
+
+const (
+	CSV   Format = "csv"
+	JSONL Format = "jsonl"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case CSV:
+		return CSV, nil
+	case JSONL:
+		return JSONL, nil
+	}
+	return "", fmt.Errorf("unsupported format %q", s)
+}
+
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+var csvHeader = []string{"id", "original", "created_at", "expired_at"}
+
+type Encoder interface {
+	Encode(url dao.URL) error
+	Flush() error
+}
+
+// Decoder 讀完回傳io.EOF 單筆資料格式錯誤回傳*RecordError 可以繼續往下讀
+type Decoder interface {
+	Decode() (*dao.URL, error)
+}
+
+// RecordError Record是第幾筆資料 從1開始 不含csv header
+type RecordError struct {
+	Record int
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Record, e.Err)
+}
+
+func NewEncoder(w io.Writer, format Format) Encoder {
+	if format == CSV {
+		return &csvEncoder{writer: csv.NewWriter(w)}
+	}
+	return &jsonlEncoder{writer: bufio.NewWriter(w)}
+}
+
+func NewDecoder(r io.Reader, format Format) Decoder {
+	if format == CSV {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = len(csvHeader)
+		reader.ReuseRecord = true
+		return &csvDecoder{reader: reader}
+	}
+	return &jsonlDecoder{reader: bufio.NewReader(r)}
+}
+
+type csvEncoder struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+func (e *csvEncoder) Encode(url dao.URL) error {
+	if !e.wroteHeader {
+		if err := e.writer.Write(csvHeader); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+	return e.writer.Write([]string{url.ID, url.Original, url.CreatedAt.Format(time.RFC3339Nano), url.ExpiredAt.Format(time.RFC3339Nano)})
+}
+
+func (e *csvEncoder) Flush() error {
+	// 沒有任何資料也要輸出header
+	if !e.wroteHeader {
+		if err := e.writer.Write(csvHeader); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type csvDecoder struct {
+	reader     *csv.Reader
+	readHeader bool
+	records    int
+}
+
+func (d *csvDecoder) Decode() (*dao.URL, error) {
+	if !d.readHeader {
+		d.readHeader = true
+		record, err := d.reader.Read()
+		if err != nil {
+			return nil, d.wrap(err)
+		}
+		// 沒有header的檔案 第一行就是資料
+		if record[0] != csvHeader[0] {
+			return d.parse(record)
+		}
+	}
+	record, err := d.reader.Read()
+	if err != nil {
+		return nil, d.wrap(err)
+	}
+	return d.parse(record)
+}
+
+func (d *csvDecoder) parse(record []string) (*dao.URL, error) {
+	d.records++
+	url := &dao.URL{ID: record[0], Original: record[1]}
+	var err error
+	if record[2] != "" {
+		if url.CreatedAt, err = time.Parse(time.RFC3339Nano, record[2]); err != nil {
+			return nil, d.recordError(err)
+		}
+	}
+	if record[3] != "" {
+		if url.ExpiredAt, err = time.Parse(time.RFC3339Nano, record[3]); err != nil {
+			return nil, d.recordError(err)
+		}
+	}
+	return url, nil
+}
+
+func (d *csvDecoder) wrap(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		d.records++
+		return &RecordError{Record: d.records, Err: parseErr}
+	}
+	return err
+}
+
+func (d *csvDecoder) recordError(err error) error {
+	return &RecordError{Record: d.records, Err: err}
+}
+
+type jsonlEncoder struct {
+	writer *bufio.Writer
+}
+
+func (e *jsonlEncoder) Encode(url dao.URL) error {
+	b, err := json.Marshal(url)
+	if err != nil {
+		return err
+	}
+	if _, err = e.writer.Write(b); err != nil {
+		return err
+	}
+	return e.writer.WriteByte('\n')
+}
+
+func (e *jsonlEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+type jsonlDecoder struct {
+	reader  *bufio.Reader
+	records int
+}
+
+func (d *jsonlDecoder) Decode() (*dao.URL, error) {
+	for {
+		b, err := d.reader.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(b) == 0) {
+			return nil, err
+		}
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			continue
+		}
+		d.records++
+		var url dao.URL
+		if err := json.Unmarshal(b, &url); err != nil {
+			return nil, &RecordError{Record: d.records, Err: err}
+		}
+		return &url, nil
+	}
+}
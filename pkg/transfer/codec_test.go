@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Codec", func() {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	actualURLs := []dao.URL{
+		{ID: "aaaaaa", Original: "http://example.com/a?x=1,2", CreatedAt: now, ExpiredAt: now.Add(time.Hour)},
+		{ID: "bbbbbb", Original: "http://example.com/\"b\"", CreatedAt: now, ExpiredAt: now.Add(-time.Hour)},
+	}
+
+	decodeAll := func(decoder Decoder) ([]dao.URL, []error) {
+		var urls []dao.URL
+		var errs []error
+		for {
+			url, err := decoder.Decode()
+			if err == io.EOF {
+				return urls, errs
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			urls = append(urls, *url)
+		}
+	}
+
+	for _, format := range []Format{CSV, JSONL} {
+		format := format
+		Context(string(format)+" round trip", func() {
+			It("result", func() {
+				var buf bytes.Buffer
+				encoder := NewEncoder(&buf, format)
+				for _, url := range actualURLs {
+					Expect(encoder.Encode(url)).To(BeNil())
+				}
+				Expect(encoder.Flush()).To(BeNil())
+
+				urls, errs := decodeAll(NewDecoder(&buf, format))
+				Expect(errs).To(BeEmpty())
+				Expect(urls).To(Equal(actualURLs))
+			})
+		})
+	}
+
+	Context("csv without header and with blank times", func() {
+		It("result", func() {
+			urls, errs := decodeAll(NewDecoder(strings.NewReader("aaaaaa,http://example.com,,\n"), CSV))
+			Expect(errs).To(BeEmpty())
+			Expect(urls).To(Equal([]dao.URL{{ID: "aaaaaa", Original: "http://example.com"}}))
+		})
+	})
+
+	Context("csv with bad record", func() {
+		It("skip bad record and keep reading", func() {
+			input := "id,original,created_at,expired_at\naaaaaa,http://example.com,yesterday,\nbbbbbb,http://example.com\ncccccc,http://example.com,,\n"
+			urls, errs := decodeAll(NewDecoder(strings.NewReader(input), CSV))
+			Expect(urls).To(Equal([]dao.URL{{ID: "cccccc", Original: "http://example.com"}}))
+			Expect(errs).To(HaveLen(2))
+			Expect(errs[0].(*RecordError).Record).To(Equal(1))
+			Expect(errs[1].(*RecordError).Record).To(Equal(2))
+		})
+	})
+
+	Context("jsonl with bad record and blank line", func() {
+		It("skip bad record and keep reading", func() {
+			input := "{\"id\":\"aaaaaa\",\"original\":\"http://example.com\"}\n\nnot json\n{\"id\":\"bbbbbb\",\"original\":\"http://example.com\"}"
+			urls, errs := decodeAll(NewDecoder(strings.NewReader(input), JSONL))
+			Expect(urls).To(Equal([]dao.URL{{ID: "aaaaaa", Original: "http://example.com"}, {ID: "bbbbbb", Original: "http://example.com"}}))
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].(*RecordError).Record).To(Equal(2))
+		})
+	})
+
+	Context("empty csv export", func() {
+		It("still has header", func() {
+			var buf bytes.Buffer
+			Expect(NewEncoder(&buf, CSV).Flush()).To(BeNil())
+			Expect(buf.String()).To(Equal("id,original,created_at,expired_at\n"))
+		})
+	})
+
+	Context("ParseFormat", func() {
+		It("result", func() {
+			Expect(ParseFormat("CSV")).To(Equal(CSV))
+			Expect(ParseFormat("jsonl")).To(Equal(JSONL))
+			_, err := ParseFormat("xml")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/KennyChenFight/Shortening-URL/pkg/repository"
+)
+
+const maxOriginalURLLength = 2048
+
+// 回傳的invalid明細最多幾筆 避免整個檔案都是錯的時候response太大
+const maxInvalidRecords = 100
+
+type ImportSummary struct {
+	Imported   int                `json:"imported"`
+	Preserved  int                `json:"preserved"`
+	Reassigned []dao.ImportResult `json:"reassigned"`
+	Invalid    int                `json:"invalid"`
+	Errors     []string           `json:"errors,omitempty"`
+}
+
+// Export 以id排序分批從repository拿出url寫到w 回傳寫了幾筆
+func Export(ctx context.Context, w io.Writer, format Format, repo repository.Repository, batchSize int, includeExpired bool) (int, *business.Error) {
+	encoder := NewEncoder(w, format)
+	exported := 0
+	afterID := ""
+	for {
+		urls, err := repo.ListURLs(ctx, afterID, batchSize, includeExpired)
+		if err != nil {
+			return exported, err
+		}
+		for _, url := range urls {
+			if err := encoder.Encode(url); err != nil {
+				return exported, business.NewError(business.Internal, http.StatusInternalServerError, "fail to write export", err)
+			}
+			exported++
+		}
+		if len(urls) < batchSize {
+			break
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+	if err := encoder.Flush(); err != nil {
+		return exported, business.NewError(business.Internal, http.StatusInternalServerError, "fail to write export", err)
+	}
+	return exported, nil
+}
+
+// Import 從r讀出url 每batchSize筆交給repository寫入 格式錯誤的資料會略過並記在summary
+// 中途失敗時 之前的batch已經寫入 summary會是到失敗為止的結果
+func Import(ctx context.Context, r io.Reader, format Format, repo repository.Repository, batchSize int) (*ImportSummary, *business.Error) {
+	summary := &ImportSummary{Reassigned: []dao.ImportResult{}}
+	decoder := NewDecoder(r, format)
+	batch := make([]dao.URL, 0, batchSize)
+
+	flush := func() *business.Error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := repo.ImportURLs(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			summary.Imported++
+			if result.Preserved() {
+				summary.Preserved++
+			} else {
+				summary.Reassigned = append(summary.Reassigned, result)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		url, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var recordErr *RecordError
+			if !errors.As(err, &recordErr) {
+				return summary, business.NewError(business.Validation, http.StatusBadRequest, "fail to read import", err)
+			}
+			summary.addInvalid(recordErr)
+			continue
+		}
+		if url.Original == "" || len(url.Original) > maxOriginalURLLength {
+			summary.addInvalid(&RecordError{Record: summary.Imported + summary.Invalid + len(batch) + 1, Err: fmt.Errorf("original length must be between 1 and %d", maxOriginalURLLength)})
+			continue
+		}
+
+		batch = append(batch, *url)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+func (s *ImportSummary) addInvalid(err *RecordError) {
+	s.Invalid++
+	if len(s.Errors) < maxInvalidRecords {
+		s.Errors = append(s.Errors, err.Error())
+	}
+}
@@ -0,0 +1,118 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/KennyChenFight/Shortening-URL/internal/repositorymock"
+	"github.com/KennyChenFight/Shortening-URL/pkg/business"
+	"github.com/KennyChenFight/Shortening-URL/pkg/dao"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer", func() {
+	ctx := context.Background()
+	var mockCtrl *gomock.Controller
+	var mockRepository *repositorymock.MockRepository
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockRepository = repositorymock.NewMockRepository(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var _ = Describe("Export", func() {
+		var (
+			buf       bytes.Buffer
+			exported  int
+			exportErr *business.Error
+		)
+
+		JustBeforeEach(func() {
+			buf.Reset()
+			exported, exportErr = Export(ctx, &buf, JSONL, mockRepository, 2, true)
+		})
+
+		Context("success with pagination", func() {
+			BeforeEach(func() {
+				gomock.InOrder(
+					mockRepository.EXPECT().ListURLs(gomock.Any(), "", 2, true).Return([]dao.URL{{ID: "aaaaaa"}, {ID: "bbbbbb"}}, nil),
+					mockRepository.EXPECT().ListURLs(gomock.Any(), "bbbbbb", 2, true).Return([]dao.URL{{ID: "cccccc"}}, nil),
+				)
+			})
+
+			It("result", func() {
+				Expect(exportErr).To(BeNil())
+				Expect(exported).To(Equal(3))
+				Expect(strings.Count(buf.String(), "\n")).To(Equal(3))
+			})
+		})
+
+		Context("fail with list", func() {
+			var listErr *business.Error
+			BeforeEach(func() {
+				listErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
+				mockRepository.EXPECT().ListURLs(gomock.Any(), "", 2, true).Return(nil, listErr)
+			})
+
+			It("result", func() {
+				Expect(exportErr).To(Equal(listErr))
+				Expect(exported).To(Equal(0))
+			})
+		})
+	})
+
+	var _ = Describe("Import", func() {
+		var (
+			input     string
+			summary   *ImportSummary
+			importErr *business.Error
+		)
+
+		JustBeforeEach(func() {
+			summary, importErr = Import(ctx, strings.NewReader(input), CSV, mockRepository, 2)
+		})
+
+		Context("success with batches and invalid records", func() {
+			BeforeEach(func() {
+				input = "aaaaaa,http://example.com/a,,\nbbbbbb,,,\ncccccc,http://example.com/c,,\ndddddd,http://example.com/d,,\n"
+				gomock.InOrder(
+					mockRepository.EXPECT().ImportURLs(gomock.Any(), []dao.URL{{ID: "aaaaaa", Original: "http://example.com/a"}, {ID: "cccccc", Original: "http://example.com/c"}}).
+						Return([]dao.ImportResult{{OriginalID: "aaaaaa", ID: "aaaaaa"}, {OriginalID: "cccccc", ID: "zzzzzz"}}, nil),
+					mockRepository.EXPECT().ImportURLs(gomock.Any(), []dao.URL{{ID: "dddddd", Original: "http://example.com/d"}}).
+						Return([]dao.ImportResult{{OriginalID: "dddddd", ID: "dddddd"}}, nil),
+				)
+			})
+
+			It("result", func() {
+				Expect(importErr).To(BeNil())
+				Expect(summary.Imported).To(Equal(3))
+				Expect(summary.Preserved).To(Equal(2))
+				Expect(summary.Reassigned).To(Equal([]dao.ImportResult{{OriginalID: "cccccc", ID: "zzzzzz"}}))
+				Expect(summary.Invalid).To(Equal(1))
+				Expect(summary.Errors).To(Equal([]string{"record 2: original length must be between 1 and 2048"}))
+			})
+		})
+
+		Context("fail with import", func() {
+			var importURLsErr *business.Error
+			BeforeEach(func() {
+				input = "aaaaaa,http://example.com/a,,\n"
+				importURLsErr = business.NewError(business.Unknown, http.StatusInternalServerError, "", nil)
+				mockRepository.EXPECT().ImportURLs(gomock.Any(), gomock.Any()).Return(nil, importURLsErr)
+			})
+
+			It("result", func() {
+				Expect(importErr).To(Equal(importURLsErr))
+				Expect(summary.Imported).To(Equal(0))
+			})
+		})
+	})
+})
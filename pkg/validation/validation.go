@@ -3,8 +3,11 @@ package validation
 import (
 	"errors"
 	"reflect"
+	"strconv"
 	"strings"
 
+	"github.com/KennyChenFight/Shortening-URL/pkg/shortid"
+
 	"github.com/go-playground/locales"
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/zh_Hant"
@@ -23,6 +26,8 @@ type registerLocaleTranslation func(v *validator.Validate, trans ut.Translator)
 type localeTranslation struct {
 	locale              locales.Translator
 	translationRegister registerLocaleTranslation
+	// 自訂tag的訊息 {0}是欄位名稱
+	shortIDMessage string
 }
 
 type Locale string
@@ -33,8 +38,26 @@ const (
 )
 
 var supportedLocales = map[Locale]localeTranslation{
-	En:     {locale: en.New(), translationRegister: enTranslations.RegisterDefaultTranslations},
-	ZhHant: {locale: zh_Hant.New(), translationRegister: zhTWTranslations.RegisterDefaultTranslations},
+	En:     {locale: en.New(), translationRegister: enTranslations.RegisterDefaultTranslations, shortIDMessage: "{0} must be {1} to {2} letters or digits"},
+	ZhHant: {locale: zh_Hant.New(), translationRegister: zhTWTranslations.RegisterDefaultTranslations, shortIDMessage: "{0}必須是{1}到{2}個英文字母或數字"},
+}
+
+const ShortIDTag = "shortid"
+
+// RegisterCustomValidations 註冊這個專案自訂的validation tag
+func RegisterCustomValidations(v *validator.Validate) error {
+	return v.RegisterValidation(ShortIDTag, func(fl validator.FieldLevel) bool {
+		return shortid.Valid(fl.Field().String())
+	})
+}
+
+func registerCustomTranslations(v *validator.Validate, trans ut.Translator, localeTranslation localeTranslation) error {
+	return v.RegisterTranslation(ShortIDTag, trans, func(ut ut.Translator) error {
+		return ut.Add(ShortIDTag, localeTranslation.shortIDMessage, true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T(ShortIDTag, fe.Field(), strconv.Itoa(shortid.MinLength), strconv.Itoa(shortid.MaxLength))
+		return t
+	})
 }
 
 func NewValidationTranslator(ginBindingValidator *validator.Validate, defaultLanguage string) (*ValidationTranslator, error) {
@@ -55,6 +78,10 @@ func NewValidationTranslator(ginBindingValidator *validator.Validate, defaultLan
 			if err != nil {
 				return nil, err
 			}
+			err = registerCustomTranslations(ginBindingValidator, trans, localeTranslation)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 